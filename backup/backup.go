@@ -15,6 +15,7 @@
 package backup
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -25,12 +26,16 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/curator"
 	"github.com/gorilla/mux"
 	"github.com/opensearch-project/opensearch-go/opensearchapi"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 var (
@@ -38,27 +43,40 @@ var (
 	resourcePrefixAttributeName = "resource_prefix"
 )
 
+// backupIdLayout is the format of backup identifiers generated by Curator
+const backupIdLayout = "20060102T150405"
+
+// newBackupId generates identifier of backup which is collected without Curator. It starts with the time in
+// backupIdLayout like Curator ones, random suffix keeps backups collected within the same second apart.
+func newBackupId() string {
+	return time.Now().UTC().Format(backupIdLayout) + "_" + common.GenerateUUID()
+}
+
+const (
+	// defaultJobPollInterval is the interval to check status of started jobs which are awaited during drain
+	defaultJobPollInterval = 5 * time.Second
+	// jobAwaitingTimeout is the maximum time to await started job
+	jobAwaitingTimeout = 6 * time.Hour
+)
+
 type Repository struct {
 	Status int `json:"status"`
 }
 
 type ActionTrack struct {
-	Action        string            `json:"action"`
-	Details       TrackDetails      `json:"details"`
-	Status        string            `json:"status"`
-	TrackID       string            `json:"trackId"`
-	ChangedNameDb map[string]string `json:"changedNameDb"`
-	TrackPath     *string           `json:"trackPath"` // would be nil in case if names regeneration not requested
+	Action               string                        `json:"action"`
+	Details              TrackDetails                  `json:"details"`
+	Status               string                        `json:"status"`
+	TrackID              string                        `json:"trackId"`
+	ChangedNameDb        map[string]string             `json:"changedNameDb"`
+	TrackPath            *string                       `json:"trackPath"` // would be nil in case if names regeneration not requested
+	ConnectionProperties []common.ConnectionProperties `json:"connectionProperties,omitempty"`
+	ExpiresAt            *time.Time                    `json:"expiresAt,omitempty"` // specified for restorations into sibling prefixes
+	Progress             *BackupProgress               `json:"progress,omitempty"`  // specified for backups with known snapshot status
+	Error                string                        `json:"error,omitempty"`     // reason of failed backup
 }
 
-type JobStatus struct {
-	State   string `json:"status"`
-	Message string `json:"details,omitempty"`
-	Vault   string `json:"vault"`
-	Type    string `json:"type"`
-	Error   string `json:"err,omitempty"`
-	TaskId  string `json:"trackPath"`
-}
+type JobStatus = curator.JobStatus
 
 type Database struct {
 	Namespace    string `json:"namespace"`
@@ -67,6 +85,13 @@ type Database struct {
 	Prefix       string `json:"prefix,omitempty"`
 }
 
+// BackupRequest is the extended form of backup request, Repositories maps database names to snapshot repositories
+// which should be used instead of the default one.
+type BackupRequest struct {
+	Databases    []string          `json:"databases"`
+	Repositories map[string]string `json:"repositories,omitempty"`
+}
+
 type RestorationRequest struct {
 	Databases       []Database `json:"databases"`
 	RegenerateNames bool       `json:"regenerateNames,omitempty"`
@@ -81,9 +106,35 @@ type Snapshots struct {
 }
 
 type SnapshotStatus struct {
-	State    string
-	Snapshot string
-	Indices  map[string]interface{}
+	State       string
+	Snapshot    string
+	ShardsStats SnapshotShardsStats            `json:"shards_stats"`
+	Stats       SnapshotStats                  `json:"stats"`
+	Indices     map[string]IndexSnapshotStatus `json:"indices"`
+}
+
+type IndexSnapshotStatus struct {
+	ShardsStats SnapshotShardsStats `json:"shards_stats"`
+	Stats       SnapshotStats       `json:"stats"`
+}
+
+type SnapshotShardsStats struct {
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
+	Total  int `json:"total"`
+}
+
+// SnapshotStats contains sizes of snapshot files, Incremental is the part which should be copied to the repository
+// by this snapshot and Processed is the part which is already copied.
+type SnapshotStats struct {
+	Incremental SnapshotFileStats `json:"incremental"`
+	Processed   SnapshotFileStats `json:"processed"`
+	Total       SnapshotFileStats `json:"total"`
+}
+
+type SnapshotFileStats struct {
+	FileCount   int   `json:"file_count"`
+	SizeInBytes int64 `json:"size_in_bytes"`
 }
 
 type RecoverySourceInfo struct {
@@ -104,43 +155,54 @@ type IndexRecoveryInfo struct {
 
 type RecoveryInfo map[string]IndexRecoveryInfo
 
-type Curator struct {
-	url      string
-	username string
-	password string
-	client   *http.Client
-}
-
-var ErrBackupNotFound = errors.New("backup not found")
-var ErrCuratorUnavailable = errors.New("curator return internal server error")
+var ErrBackupNotFound = curator.ErrNotFound
+var ErrCuratorUnavailable = curator.ErrUnavailable
 
 type BackupProvider struct {
 	client     common.Client
 	indexNames *common.IndexAdapter
 	repoRoot   string
-	Curator    *Curator
+	Curator    *curator.Client
+	Registry   *JobRegistry
+
+	verifyAfterBackup bool
+	verificationRepo  string
+	// jobPollInterval is the interval to check status of started jobs, see awaitJob
+	jobPollInterval time.Duration
+
+	// baseProvider is used to provision users and metadata for databases restored under new names
+	baseProvider *basic.BaseProvider
 }
 
-func NewBackupProvider(opensearchClient common.Client, curatorClient *http.Client, repoRoot string) *BackupProvider {
+func NewBackupProvider(opensearchClient common.Client, curatorClient *http.Client, repoRoot string,
+	baseProvider *basic.BaseProvider) *BackupProvider {
 	logger.Info(fmt.Sprintf("Creating new backup provider, repository root is '%s'", repoRoot))
 	if !strings.HasSuffix(repoRoot, "/") {
 		repoRoot = repoRoot + "/"
 	}
-	curator := &Curator{
-		url:      common.GetEnv("CURATOR_ADDRESS", ""),
-		username: common.GetEnv("CURATOR_USERNAME", ""),
-		password: common.GetEnv("CURATOR_PASSWORD", ""),
-		client:   curatorClient,
-	}
 	backupService := &BackupProvider{
 		client:     opensearchClient,
 		indexNames: common.NewIndexAdapter(),
 		repoRoot:   repoRoot,
-		Curator:    curator,
+		Curator: curator.NewClient(common.GetEnv("CURATOR_ADDRESS", ""), common.GetEnv("CURATOR_USERNAME", ""),
+			common.GetEnv("CURATOR_PASSWORD", ""), curatorClient),
+		Registry:        NewJobRegistry(opensearchClient),
+		baseProvider:    baseProvider,
+		jobPollInterval: defaultJobPollInterval,
 	}
 	return backupService
 }
 
+// WithApiVersion returns provider for the given API version, so users of restored databases are provisioned
+// with role types of this version. It shares registry and clients with bp.
+func (bp *BackupProvider) WithApiVersion(apiVersion string) *BackupProvider {
+	provider := *bp
+	if bp.baseProvider != nil {
+		provider.baseProvider = bp.baseProvider.WithApiVersion(apiVersion)
+	}
+	return &provider
+}
+
 func (bp BackupProvider) CollectBackupHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := common.PrepareContext(r)
@@ -150,12 +212,16 @@ func (bp BackupProvider) CollectBackupHandler() func(w http.ResponseWriter, r *h
 			// Actually we do nothing in this case because OpenSearch stores snapshots as long as possible
 			logger.InfoContext(ctx, fmt.Sprintf("'allowEviction' property is set to '%s'", keys[0]))
 		}
-		decoder := json.NewDecoder(r.Body)
-		var databases []string
-		err := decoder.Decode(&databases)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to read request body", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, common.DecodeError(err))
+			return
+		}
+		request, err := parseBackupRequest(body)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to decode request from JSON", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -166,25 +232,31 @@ func (bp BackupProvider) CollectBackupHandler() func(w http.ResponseWriter, r *h
 			}
 		}(r.Body)
 
-		backupID, err := bp.CollectBackup(databases, ctx)
+		job, err := bp.collectBackup(ctx, request)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to create snapshot", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
+		backupID := job.ID
+		bp.registerJob(ctx, job)
+		bp.awaitJob(ctx, job)
+		if bp.verifyAfterBackup {
+			verificationCtx := context.WithValue(context.Background(), common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
+			go bp.verifyWhenCompleted(verificationCtx, backupID)
+		}
 
 		response, err := bp.TrackBackup(backupID, ctx)
 		if err != nil {
 			logger.ErrorContext(ctx, "failed to create snapshot, curator return an error", slog.String("error", err.Error()))
-			msg := fmt.Sprintf("failed to create snapshot, curator return an error: %s", err.Error())
-			common.ProcessResponseBody(ctx, w, []byte(msg), http.StatusInternalServerError)
+			common.WriteError(ctx, w, fmt.Errorf("failed to create snapshot, curator return an error: %w", err))
 			return
 		}
 
 		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -202,14 +274,7 @@ func (bp BackupProvider) DeleteBackupHandler() func(w http.ResponseWriter, r *ht
 		responseBody, status, err := bp.DeleteBackup(backupID, ctx)
 		if err != nil {
 			logger.ErrorContext(ctx, "failed to delete backup", slog.String("error", err.Error()))
-			statusCode := http.StatusInternalServerError
-			if errors.Is(err, ErrBackupNotFound) {
-				statusCode = http.StatusNotFound
-			}
-
-			w.WriteHeader(statusCode)
-			_, _ = w.Write([]byte(err.Error()))
-
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -224,27 +289,23 @@ func (bp BackupProvider) DeleteBackupHandler() func(w http.ResponseWriter, r *ht
 	}
 }
 
-func (bp BackupProvider) TrackBackupHandler() func(w http.ResponseWriter, r *http.Request) {
+func (bp BackupProvider) TrackBackupHandler(repo string) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := common.PrepareContext(r)
 		logger.InfoContext(ctx, fmt.Sprintf("Request to track backup in '%s' is received", r.URL.Path))
 		vars := mux.Vars(r)
 		trackID := vars["backupID"]
-		response, err := bp.TrackBackup(trackID, ctx)
+		response, err := bp.trackBackup(ctx, trackID, repo)
 		if err != nil {
-			if errors.Is(err, ErrBackupNotFound) {
-				w.WriteHeader(http.StatusNotFound)
-			} else {
-				logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.Any("error", err))
-				common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
-				return
-			}
+			logger.ErrorContext(ctx, "Failed to track backup", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
+			return
 		}
 
 		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -258,12 +319,14 @@ func (bp BackupProvider) RestoreBackupHandler(repo string, basePath string) func
 		vars := mux.Vars(r)
 		backupID := vars["backupID"]
 		logger.InfoContext(ctx, fmt.Sprintf("Request to restore '%s' backup is received", backupID))
-		decoder := json.NewDecoder(r.Body)
 		var databases []string
-		err := decoder.Decode(&databases)
+		err := common.DecodeJson(r.Body, &databases)
+		if err == nil {
+			err = validateDatabaseNames(databases)
+		}
 		if err != nil {
-			logger.ErrorContext(ctx, "Failed to decode request from JSON", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			logger.ErrorContext(ctx, "Invalid request to restore backup", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
 			return
 		}
 		defer r.Body.Close()
@@ -271,25 +334,22 @@ func (bp BackupProvider) RestoreBackupHandler(repo string, basePath string) func
 		regenerateNames := r.URL.Query().Get("regenerateNames") == "true"
 		changedNameDb, err := bp.RestoreBackup(backupID, databases, repo, regenerateNames, ctx)
 		if err != nil {
-			logMsg := "failed to restore backup, internal server error occur"
-			statusCode := http.StatusInternalServerError
-			if errors.Is(err, ErrBackupNotFound) {
-				logMsg = "failed to restore backup, the backup is not found"
-				statusCode = http.StatusNotFound
-			}
-			logger.ErrorContext(ctx, logMsg, slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), statusCode)
+			logger.ErrorContext(ctx, "failed to restore backup", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
 			return
 		}
+		job := newJob(RestoreJobType, backupID, databases, changedNameDb)
+		if regenerateNames {
+			job.Provisioning = legacyProvisioning(databases, changedNameDb, bp.baseProvider.GetProvisionedRoleTypes())
+		}
+		bp.registerJob(ctx, job)
+		bp.awaitJob(ctx, job)
 
 		response, err := bp.TrackRestore(backupID, ctx, changedNameDb)
 		if err != nil {
 			logger.ErrorContext(ctx, "restore backup is failed", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
-			_, err = w.Write([]byte(err.Error()))
-			if err != nil {
-				logger.ErrorContext(ctx, "failed to write bytes to the http body response")
-			}
+			common.WriteError(ctx, w, err)
+			return
 		}
 
 		if regenerateNames {
@@ -297,7 +357,7 @@ func (bp BackupProvider) RestoreBackupHandler(repo string, basePath string) func
 			indices, err = bp.getActualIndices(backupID, repo, changedNameDb, ctx)
 			if err != nil {
 				logger.ErrorContext(ctx, "Failed to receive indices from snapshot", slog.String("error", err.Error()))
-				common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+				common.WriteError(ctx, w, err)
 				return
 			}
 			trackPath := fmt.Sprintf("%s/backups/track/restoring/backups/%s/indices/%s",
@@ -311,7 +371,7 @@ func (bp BackupProvider) RestoreBackupHandler(repo string, basePath string) func
 		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		common.ProcessResponseBody(ctx, w, responseBody, 0)
@@ -335,41 +395,47 @@ func (bp BackupProvider) RestorationBackupHandler(repo string, basePath string)
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to decode request body", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, common.DecodeError(err))
 			return
 		}
 
 		var req RestorationRequest
-		err = json.Unmarshal(body, &req)
+		err = common.DecodeJson(bytes.NewReader(body), &req)
+		if err == nil {
+			err = validateDatabases(req.Databases)
+		}
 		if err != nil {
-			logger.ErrorContext(ctx, "Failed to unmarshal request from JSON", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			logger.ErrorContext(ctx, "Invalid request to restore backup", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
 			return
 		}
 
-		changedNameDb, err, trackId := bp.ProcessRestorationRequest(backupID, req, ctx)
+		job, err := bp.processRestoration(ctx, backupID, req)
 		if err != nil {
 			logger.ErrorContext(ctx, "failed to process restoration", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
+		if len(job.ChangedNameDb) != 0 {
+			job.Provisioning = &Provisioning{
+				Databases: req.Databases,
+				Prefixes:  job.ChangedNameDb,
+				RoleTypes: bp.baseProvider.GetProvisionedRoleTypes(),
+			}
+		}
+		bp.registerJob(ctx, job)
+		bp.awaitJob(ctx, job)
 
-		response, err := bp.TrackRestore(trackId, ctx, changedNameDb)
+		response, err := bp.TrackRestore(job.ID, ctx, job.ChangedNameDb)
 		if err != nil {
-			errStatusCode := http.StatusInternalServerError
-			logMsg := "an internal server error occurred while attempting to retrieve the recovery"
-			if errors.Is(err, ErrBackupNotFound) {
-				logMsg = "track restore not found"
-				errStatusCode = http.StatusNotFound
-			}
-			logger.ErrorContext(ctx, logMsg, slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), errStatusCode)
+			logger.ErrorContext(ctx, "failed to track restore", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
 			return
 		}
 		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		common.ProcessResponseBody(ctx, w, responseBody, 0)
@@ -384,20 +450,14 @@ func (bp BackupProvider) TrackRestoreFromTrackIdHandler(fromRepo string) func(w
 		backupID := vars["backupID"]
 		response, err := bp.TrackRestore(backupID, ctx, nil)
 		if err != nil {
-			errStatusCode := http.StatusInternalServerError
-			logMsg := "an internal server error occurred while attempting to retrieve the recovery"
-			if errors.Is(err, ErrBackupNotFound) {
-				logMsg = "track restore not found"
-				errStatusCode = http.StatusNotFound
-			}
-			logger.ErrorContext(ctx, logMsg, slog.String("error", err.Error()))
-			w.WriteHeader(errStatusCode)
+			logger.ErrorContext(ctx, "failed to track restore", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
 		}
-		var responseBody []byte
-		responseBody, err = json.Marshal(response)
+		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, responseBody, http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -417,7 +477,7 @@ func (bp BackupProvider) TrackRestoreFromIndicesHandler(fromRepo string) func(w
 		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -425,107 +485,166 @@ func (bp BackupProvider) TrackRestoreFromIndicesHandler(fromRepo string) func(w
 	}
 }
 
-func (bp BackupProvider) CollectBackup(dbs []string, ctx context.Context) (string, error) {
-	var body *strings.Reader
-	if len(dbs) != 0 {
-		quotedDbs := make([]string, len(dbs))
-		for i, db := range dbs {
-			quotedDbs[i] = fmt.Sprintf(`"%s"`, db)
-		}
-		body = strings.NewReader(fmt.Sprintf(`
-		{	
-			"allow_eviction":"False",	
-			"dbs": [%s]
-		}`, strings.Join(quotedDbs, ",")))
-	}
-	url := fmt.Sprintf("%s/%s", bp.Curator.url, "backup")
-	request, err := http.NewRequest(http.MethodPost, url, body)
-	if err != nil {
-		logger.ErrorContext(ctx, "Failed to prepare request to collect backup", slog.Any("error", err))
-		return "", err
+// collectBackup collects backup of databases by Curator into the default repository, databases with specified
+// repositories are collected by the adapter directly into snapshots with the same name.
+func (bp BackupProvider) collectBackup(ctx context.Context, request BackupRequest) (*Job, error) {
+	repositories := make(map[string][]string)
+	var curatorDbs []string
+	for _, db := range request.Databases {
+		if repo := request.Repositories[db]; repo != "" {
+			repositories[repo] = append(repositories[repo], db)
+		} else {
+			curatorDbs = append(curatorDbs, db)
+		}
 	}
-	request.Header.Set("Content-Type", "application/json")
-
-	request.Header.Set(common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
-	request.SetBasicAuth(bp.Curator.username, bp.Curator.password)
-	response, err := bp.Curator.client.Do(request)
-	if err != nil {
-		logger.ErrorContext(ctx, fmt.Sprintf("Failed to create snapshot with provided database prefixes: '%v'", body))
-		return "", err
+	// backup is not started at all if some repository does not exist
+	for repo := range repositories {
+		if err := bp.checkRepository(ctx, repo); err != nil {
+			return nil, err
+		}
 	}
-
-	defer func() {
-		err = response.Body.Close()
+	var backupID string
+	var err error
+	withCurator := len(curatorDbs) != 0 || len(repositories) == 0
+	if withCurator {
+		backupID, err = bp.CollectBackup(curatorDbs, ctx)
 		if err != nil {
-			logger.Error("failed to close http body", slog.String("error", err.Error()))
+			return nil, err
 		}
-	}()
+	} else {
+		backupID = newBackupId()
+	}
+	repos := make([]string, 0, len(repositories))
+	for repo := range repositories {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	var started []string
+	for _, repo := range repos {
+		if err = bp.createSnapshot(ctx, repo, backupID, repositories[repo], request.Databases); err != nil {
+			bp.removeStartedBackup(ctx, backupID, started, withCurator)
+			return nil, err
+		}
+		started = append(started, repo)
+	}
+	job := newJob(BackupJobType, backupID, request, nil)
+	if len(repositories) != 0 {
+		job.Repositories = repositories
+		job.WithoutCurator = len(curatorDbs) == 0
+	}
+	return job, nil
+}
 
-	responseBody, err := io.ReadAll(response.Body)
+// removeStartedBackup removes parts of the backup which is failed to be started completely
+func (bp BackupProvider) removeStartedBackup(ctx context.Context, backupID string, repos []string, withCurator bool) {
+	for _, repo := range repos {
+		if err := bp.deleteSnapshot(ctx, repo, backupID); err != nil {
+			logger.ErrorContext(ctx, "Failed to remove snapshot of partially started backup", slog.Any("error", err))
+		}
+	}
+	if withCurator {
+		if _, err := bp.Curator.Evict(ctx, backupID); err != nil {
+			logger.ErrorContext(ctx, fmt.Sprintf("Failed to remove '%s' backup which is partially started", backupID),
+				slog.Any("error", err))
+		}
+	}
+}
+
+func (bp BackupProvider) CollectBackup(dbs []string, ctx context.Context) (string, error) {
+	backupID, err := bp.Curator.Backup(ctx, curator.BackupRequest{AllowEviction: "False", Dbs: dbs})
 	if err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("Failed to create snapshot with provided database prefixes: '%v'", dbs),
+			slog.Any("error", err))
 		return "", err
 	}
-	logger.DebugContext(ctx, fmt.Sprintf("Snapshot is created: %s", responseBody))
-	return string(responseBody), nil
+	logger.DebugContext(ctx, fmt.Sprintf("Snapshot is created: %s", backupID))
+	return backupID, nil
 }
 
 func (bp BackupProvider) TrackBackup(backupID string, ctx context.Context) (ActionTrack, error) {
+	return bp.trackBackup(ctx, backupID, "")
+}
+
+// trackBackup returns status of the backup. If repo used by Curator is specified, progress of the backup is also
+// calculated and state of backed up indices is captured as soon as the backup is completed.
+func (bp BackupProvider) trackBackup(ctx context.Context, backupID string, repo string) (ActionTrack, error) {
 	logger.DebugContext(ctx, fmt.Sprintf("Request to track '%s' backup is requested",
 		backupID))
-	jobStatus, err := bp.getJobStatus(backupID, ctx)
+	job := bp.findJob(ctx, BackupJobType, backupID)
+	jobStatus, reason, err := bp.getBackupStatus(ctx, backupID, job)
+	bp.recordJobStatus(ctx, BackupJobType, backupID, jobStatus, err)
+	if err == nil && jobStatus == "SUCCESS" && repo != "" && job != nil && job.Indices == nil {
+		bp.recordIndicesState(ctx, job, repo)
+	}
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to find snapshot", slog.Any("error", err))
 		return backupTrack(backupID, "FAIL"), err
 	}
 	logger.DebugContext(ctx, fmt.Sprintf("'%s' backup status is %s", backupID, jobStatus))
-	return backupTrack(backupID, jobStatus), nil
+	track := backupTrack(backupID, jobStatus)
+	if jobStatus == "FAIL" {
+		track.Error = reason
+	}
+	if repo != "" {
+		track.Progress = bp.getBackupProgress(ctx, backupID, backupRepositories(job, repo))
+	}
+	return track, nil
 }
 
-func (bp BackupProvider) DeleteBackup(backupID string, ctx context.Context) ([]byte, int, error) {
-	url := fmt.Sprintf("%s/%s/%s", bp.Curator.url, "evict", backupID)
-	request, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		logger.ErrorContext(ctx, "Failed to prepare request to delete backup", slog.Any("error", err))
-		return nil, http.StatusInternalServerError, err
+// getBackupStatus receives status of backup from Curator and, if some databases are backed up into other
+// repositories, from their snapshots. The reason of failure is returned along with FAIL status.
+func (bp BackupProvider) getBackupStatus(ctx context.Context, backupID string, job *Job) (string, string, error) {
+	status := "SUCCESS"
+	var reason string
+	if job == nil || !job.WithoutCurator {
+		var err error
+		if status, reason, err = bp.getJobStatus(backupID, ctx); err != nil {
+			return status, "", err
+		}
 	}
-
-	request.Header.Set(common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
-	request.SetBasicAuth(bp.Curator.username, bp.Curator.password)
-	response, err := bp.Curator.client.Do(request)
-
-	if err != nil {
-		logger.ErrorContext(ctx, "failed to delete snapshot", slog.String("error", err.Error()))
-		return nil, http.StatusInternalServerError, err
+	if job == nil {
+		return status, reason, nil
 	}
-
-	defer func() {
-		err = response.Body.Close()
+	for repo := range job.Repositories {
+		snapshot, err := bp.getSnapshotStatus(backupID, repo, ctx)
 		if err != nil {
-			logger.ErrorContext(ctx, "failed to close http response body", slog.String("error", err.Error()))
+			return "FAIL", "", err
 		}
-	}()
-
-	if response.StatusCode == http.StatusInternalServerError {
-		return nil, http.StatusInternalServerError, ErrCuratorUnavailable
+		snapshotStatus := snapshotJobStatus(snapshot.State)
+		if snapshotStatus == "FAIL" && reason == "" {
+			reason = fmt.Sprintf("'%s' snapshot in '%s' repository is %s", backupID, repo, snapshot.State)
+		}
+		status = combineJobStatuses(status, snapshotStatus)
 	}
+	return status, reason, nil
+}
 
-	if response.StatusCode == http.StatusNotFound {
-		return nil, http.StatusNotFound, ErrBackupNotFound
+func (bp BackupProvider) DeleteBackup(backupID string, ctx context.Context) ([]byte, int, error) {
+	if job := bp.findJob(ctx, BackupJobType, backupID); job != nil {
+		for repo := range job.Repositories {
+			if err := bp.deleteSnapshot(ctx, repo, backupID); err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+		}
+		if job.WithoutCurator {
+			return nil, http.StatusOK, nil
+		}
 	}
-
-	all, err := io.ReadAll(response.Body)
+	response, err := bp.Curator.Evict(ctx, backupID)
 	if err != nil {
-		logger.ErrorContext(ctx, "failed to read bytes from http response body", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "failed to delete snapshot", slog.String("error", err.Error()))
+		if errors.Is(err, ErrBackupNotFound) {
+			return nil, http.StatusNotFound, err
+		}
 		return nil, http.StatusInternalServerError, err
 	}
-
-	return all, response.StatusCode, nil
+	return response.Body, response.StatusCode, nil
 }
 
 func (bp BackupProvider) RestoreBackup(backupId string, dbs []string, fromRepo string, regenerateNames bool, ctx context.Context) (map[string]string, error) {
 	if len(dbs) == 0 {
 		logger.ErrorContext(ctx, "Database prefixes to restore are not specified")
-		return nil, errors.New("database prefixes to restore are not specified")
+		return nil, common.NewError(common.ErrValidation, "database prefixes to restore are not specified")
 	}
 	var indices []string
 	var err error
@@ -552,8 +671,14 @@ func (bp BackupProvider) RestoreBackup(backupId string, dbs []string, fromRepo s
 			logger.InfoContext(ctx, "Cannot perform bulk restoration")
 			logger.WarnContext(ctx, "In names regeneration mode when restoring names are too long, restore request could take more time than expected and even time out, because restoration cannot be executed in parallel")
 			// TODO can speed up overall process if use subsequent mode only for overflowing names
+			// renamed indices of the same database share the prefix, so they are provisioned as one database
+			dbPrefixes := make(map[string]string)
 			for _, index := range indices {
-				newName := bp.indexNames.NameIndex()
+				database := databaseOf(index, dbs)
+				if _, ok := dbPrefixes[database]; !ok {
+					dbPrefixes[database] = bp.indexNames.NameIndex()
+				}
+				newName := bp.indexNames.NameIndexPrefixed(dbPrefixes[database])
 				err := bp.requestRestore(ctx, []string{index}, backupId, index, newName)
 				if err != nil {
 					return nil, err
@@ -612,21 +737,43 @@ func (bp BackupProvider) RestoreBackup(backupId string, dbs []string, fromRepo s
 }
 
 func (bp BackupProvider) ProcessRestorationRequest(backupId string, restorationRequest RestorationRequest, ctx context.Context) (map[string]string, error, string) {
+	job, err := bp.processRestoration(ctx, backupId, restorationRequest)
+	if err != nil {
+		return nil, err, ""
+	}
+	return job.ChangedNameDb, nil, job.ID
+}
+
+// processRestoration starts restoration and returns not registered restore job. Databases backed up into
+// repositories other than the default one are restored by the adapter directly, the rest are restored by Curator.
+func (bp BackupProvider) processRestoration(ctx context.Context, backupId string, restorationRequest RestorationRequest) (*Job, error) {
 	if len(restorationRequest.Databases) == 0 {
 		logger.ErrorContext(ctx, "Databases to restore are not specified")
-		return nil, errors.New("database to restore are not specified"), ""
+		return nil, common.NewError(common.ErrValidation, "database to restore are not specified")
+	}
+	databaseRepositories := make(map[string]string)
+	backupJob := bp.findJob(ctx, BackupJobType, backupId)
+	if backupJob != nil {
+		for repo, dbs := range backupJob.Repositories {
+			for _, db := range dbs {
+				databaseRepositories[db] = repo
+			}
+		}
 	}
 	var renames, dbs []string
 	var changedDbNames map[string]string
 	prefixes := make(map[string]struct{})
 	for _, dabatase := range restorationRequest.Databases {
-		dbs = append(dbs, fmt.Sprintf(`"%s"`, dabatase.Name))
+		if _, ok := databaseRepositories[dabatase.Name]; !ok {
+			dbs = append(dbs, dabatase.Name)
+		}
 		if restorationRequest.RegenerateNames {
 			if dabatase.Prefix != "" {
-				if ok, err := bp.checkPrefixUniqueness(dabatase.Prefix, ctx); ok {
-					if err != nil {
-						return nil, err, ""
-					}
+				ok, err := bp.checkPrefixUniqueness(dabatase.Prefix, ctx)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
 					renames = append(renames, fmt.Sprintf("%s:%s", dabatase.Name, dabatase.Prefix))
 				}
 			} else {
@@ -639,36 +786,176 @@ func (bp BackupProvider) ProcessRestorationRequest(backupId string, restorationR
 				}
 				if err != nil {
 					logger.ErrorContext(ctx, fmt.Sprintf("Failed to regenerate name for provided database: %v", dabatase), slog.Any("error", err))
-					return nil, err, ""
+					return nil, err
 				}
 				renames = append(renames, fmt.Sprintf("%s:%s", dabatase.Name, prefix))
 				prefixes[prefix] = struct{}{}
 			}
 		}
 	}
+	var curatorRenames map[string]string
 	if len(renames) != 0 {
 		changedDbNames = make(map[string]string)
+		curatorRenames = make(map[string]string)
 		for _, pair := range renames {
 			parts := strings.Split(pair, ":")
 			changedDbNames[parts[0]] = parts[1]
+			if _, ok := databaseRepositories[parts[0]]; !ok {
+				curatorRenames[parts[0]] = parts[1]
+			}
 		}
 	}
-	err, trackId := bp.requestRestoration(ctx, dbs, backupId, renames)
-	if err != nil {
-		return nil, err, trackId
+
+	trackId := common.GenerateUUID()
+	if len(dbs) != 0 {
+		var err error
+		if err, trackId = bp.requestRestoration(ctx, dbs, backupId, curatorRenames); err != nil {
+			return nil, err
+		}
+	}
+	job := newJob(RestoreJobType, trackId, restorationRequest, changedDbNames)
+	for _, database := range restorationRequest.Databases {
+		repo, ok := databaseRepositories[database.Name]
+		if !ok {
+			continue
+		}
+		target := database.Name
+		if newName, renamed := changedDbNames[database.Name]; renamed {
+			target = newName
+		}
+		if err := bp.restoreFromRepository(ctx, repo, backupId, database.Name, target, backupJob.Repositories[repo]); err != nil {
+			return nil, err
+		}
+		if job.Repositories == nil {
+			job.Repositories = make(map[string][]string)
+		}
+		job.Repositories[repo] = append(job.Repositories[repo], target)
 	}
-	return changedDbNames, err, trackId
+	if len(job.Repositories) != 0 {
+		job.BackupID = backupId
+		job.WithoutCurator = len(dbs) == 0
+	}
+	return job, nil
 }
 
+// TrackRestore returns status of restoration. Users for new prefixes are provisioned as soon as restoration is
+// completed and their connection properties are returned in the track response. Passwords are not stored, so
+// every next track of the completed restoration resets passwords of these users and returns the new ones, and
+// credentials are not lost if the track response is lost.
 func (bp BackupProvider) TrackRestore(trackId string, ctx context.Context, changedNameDb map[string]string) (ActionTrack, error) {
 	logger.InfoContext(ctx, fmt.Sprintf("Request to track '%s' restoration is received", trackId))
-	jobStatus, err := bp.getJobStatus(trackId, ctx)
+	job := bp.findJob(ctx, RestoreJobType, trackId)
+	jobStatus, err := bp.getRestoreStatus(ctx, trackId, job)
+	var connectionProperties []common.ConnectionProperties
+	recordedStatus := jobStatus
+	if err == nil && jobStatus == "SUCCESS" && job.needsProvisioning() {
+		connectionProperties, err = bp.provisionRestoredJob(ctx, job)
+		if errors.Is(err, errProvisioningInProgress) {
+			// restoration is completed by the caller which is provisioning users
+			jobStatus, recordedStatus, err = "PROCEEDING", "", nil
+		} else if err != nil {
+			err = fmt.Errorf("failed to provision users for restored databases: %w", err)
+		}
+	} else if err == nil && jobStatus == "SUCCESS" && job.provisioned() {
+		connectionProperties, err = bp.resetProvisionedUsers(ctx, job)
+	}
+	job = bp.recordJobStatus(ctx, RestoreJobType, trackId, recordedStatus, err)
 	if err != nil {
-		logger.ErrorContext(ctx, "Failed to find snapshot", slog.String("error", err.Error()))
+		logger.ErrorContext(ctx, "Failed to track restoration", slog.String("error", err.Error()))
 		return backupTrack(trackId, "FAIL"), err
 	}
 	logger.DebugContext(ctx, fmt.Sprintf("'%s' backup status is %s", trackId, jobStatus))
-	return restoreTrack(trackId, jobStatus, changedNameDb), nil
+	if changedNameDb == nil && job != nil {
+		changedNameDb = job.ChangedNameDb
+	}
+	track := restoreTrack(trackId, jobStatus, changedNameDb)
+	track.ConnectionProperties = connectionProperties
+	if job != nil {
+		track.ExpiresAt = job.ExpiresAt
+	}
+	return track, nil
+}
+
+// getRestoreStatus receives status of restoration from Curator and, if some databases are restored from other
+// repositories, from recovery state of restored indices.
+func (bp BackupProvider) getRestoreStatus(ctx context.Context, trackId string, job *Job) (string, error) {
+	status := "SUCCESS"
+	if job == nil || !job.WithoutCurator {
+		var err error
+		if status, _, err = bp.getJobStatus(trackId, ctx); err != nil {
+			return status, err
+		}
+	}
+	if job == nil {
+		return status, nil
+	}
+	for repo, dbs := range job.Repositories {
+		track := bp.TrackRestoreIndices(ctx, job.BackupID, databasePatterns(dbs, nil), repo, nil)
+		status = combineJobStatuses(status, track.Status)
+	}
+	return status, nil
+}
+
+// registerJob stores information about started job in registry. Registry failures do not break the job itself,
+// only tracking of the job after adapter restart is affected.
+func (bp BackupProvider) registerJob(ctx context.Context, job *Job) {
+	if err := bp.Registry.Register(ctx, job); err != nil {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to register '%s' %s job", job.ID, job.Type), slog.Any("error", err))
+	}
+}
+
+// awaitJob tracks the started job as in-flight operation of drainer until the job is finished, so adapter
+// waits for backups and restorations performed by Curator and OpenSearch before shutdown.
+func (bp BackupProvider) awaitJob(ctx context.Context, job *Job) {
+	if bp.baseProvider == nil || bp.baseProvider.Drainer == nil {
+		return
+	}
+	done := bp.baseProvider.Drainer.Track(strings.ToLower(job.Type))
+	jobCtx := context.WithValue(context.Background(), common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
+	go func() {
+		defer done()
+		err := wait.PollUntilContextTimeout(jobCtx, bp.jobPollInterval, jobAwaitingTimeout, true,
+			func(ctx context.Context) (bool, error) {
+				var status string
+				var err error
+				if job.Type == BackupJobType {
+					status, _, err = bp.getBackupStatus(ctx, job.ID, job)
+				} else {
+					status, err = bp.getRestoreStatus(ctx, job.ID, job)
+				}
+				return err == nil && (status == "SUCCESS" || status == "FAIL"), nil
+			})
+		if err != nil {
+			logger.WarnContext(jobCtx, fmt.Sprintf("'%s' %s job is not finished in time", job.ID, job.Type), slog.Any("error", err))
+		}
+	}()
+}
+
+// findJob returns registered job, nil is returned if job is not registered or registry is not available.
+func (bp BackupProvider) findJob(ctx context.Context, jobType string, id string) *Job {
+	job, err := bp.Registry.Get(ctx, jobType, id)
+	if err != nil {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to receive '%s' %s job", id, jobType), slog.Any("error", err))
+		return nil
+	}
+	return job
+}
+
+// recordJobStatus updates status of registered job and returns it, nil is returned if job is not registered
+// or registry is not available.
+func (bp BackupProvider) recordJobStatus(ctx context.Context, jobType string, id string, status string, jobErr error) *Job {
+	var jobError string
+	if jobErr != nil {
+		// status is unknown in case of error, so the last known status is kept
+		status = ""
+		jobError = jobErr.Error()
+	}
+	job, err := bp.Registry.UpdateStatus(ctx, jobType, id, status, jobError)
+	if err != nil {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to update status of '%s' %s job", id, jobType), slog.Any("error", err))
+		return nil
+	}
+	return job
 }
 
 func (bp BackupProvider) checkPrefixUniqueness(prefix string, ctx context.Context) (bool, error) {
@@ -695,11 +982,11 @@ func (bp BackupProvider) checkPrefixUniqueness(prefix string, ctx context.Contex
 		for element, user := range users {
 			if strings.HasPrefix(element, prefix) {
 				logger.ErrorContext(ctx, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
-				return false, fmt.Errorf("provided prefix already exists or a part of another prefix: %+v", prefix)
+				return false, common.NewError(common.ErrConflict, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
 			}
 			if user.Attributes[resourcePrefixAttributeName] != "" && strings.HasPrefix(user.Attributes[resourcePrefixAttributeName], prefix) {
 				logger.ErrorContext(ctx, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
-				return false, fmt.Errorf("provided prefix already exists or a part of another prefix: %+v", prefix)
+				return false, common.NewError(common.ErrConflict, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
 			}
 		}
 	} else if response.StatusCode == http.StatusNotFound {
@@ -708,7 +995,8 @@ func (bp BackupProvider) checkPrefixUniqueness(prefix string, ctx context.Contex
 	return true, nil
 }
 
-// TrackRestoreIndices We keep this logic, but first we need to fix the problem with users and regenerate names for indexes, until then it will not work incorrectly.
+// TrackRestoreIndices tracks restoration of particular indices by their recovery state. It is used by the legacy
+// restore with regenerated index names, users for regenerated prefixes are provisioned by TrackRestore.
 func (bp BackupProvider) TrackRestoreIndices(ctx context.Context, backupId string, indices []string, repoName string, changedNameDb map[string]string) ActionTrack {
 	// TODO should investigate this behavior and try to fix - elastic never return recovery in progress
 	logger.InfoContext(ctx, fmt.Sprintf("Request to track indices restoration from '%s' snapshot in '%s' is received: %v",
@@ -748,133 +1036,82 @@ func (bp BackupProvider) TrackRestoreIndices(ctx context.Context, backupId strin
 }
 
 func (bp BackupProvider) requestRestore(ctx context.Context, dbs []string, backupId string, pattern, replacement string) error {
-	body := strings.NewReader(fmt.Sprintf(`
-		{
-			"vault": "%s",
-			"skip_users_recovery": "true",		
-			"dbs": ["%s"]
-		%s
-		}		
-		`, backupId, strings.Join(dbs, ","), namesRegenerateRequestPart(pattern, replacement)))
-	url := fmt.Sprintf("%s/%s", bp.Curator.url, "restore")
-	request := bp.prepareRestoreRequest(ctx, url, body)
-	logger.DebugContext(ctx, fmt.Sprintf("Request body built to restore '%s' backup: %v", backupId, body))
-	response, err := bp.Curator.client.Do(request)
+	trackId, err := bp.Curator.Restore(ctx, curator.RestoreRequest{
+		Vault:             backupId,
+		SkipUsersRecovery: skipUsersRecovery(pattern != ""),
+		Dbs:               dbs,
+		RenamePattern:     pattern,
+		RenameReplacement: replacement,
+	})
 	if err != nil {
 		return err
 	}
-	defer func(Body io.ReadCloser) {
-		err = Body.Close()
-		if err != nil {
-			logger.Error("failed to close http body", slog.String("error", err.Error()))
-		}
-	}(response.Body)
-
-	if response.StatusCode == 404 {
-		return ErrBackupNotFound
-	}
-
-	if response.StatusCode >= 500 {
-		return ErrCuratorUnavailable
-	}
-	logger.InfoContext(ctx, fmt.Sprintf("'%s' snapshot restoration is started: %s", backupId, response.Body))
+	logger.InfoContext(ctx, fmt.Sprintf("'%s' snapshot restoration is started: %s", backupId, trackId))
 	return nil
 }
 
-func (bp BackupProvider) requestRestoration(ctx context.Context, dbs []string, backupId string, replacement []string) (error, string) {
-	body := strings.NewReader(fmt.Sprintf(`
-		{
-			"vault": "%s",
-			"skip_users_recovery": "true",
-			"dbs": [%s]
-		%s
-		}		
-		`, backupId, strings.Join(dbs, ","), prepareChangeNameRequestPart(replacement)))
-	url := fmt.Sprintf("%s/%s", bp.Curator.url, "restore")
-	request := bp.prepareRestoreRequest(ctx, url, body)
-	logger.DebugContext(ctx, fmt.Sprintf("Request body built to restore '%s' backup: %v", backupId, body))
-	response, err := bp.Curator.client.Do(request)
+func (bp BackupProvider) requestRestoration(ctx context.Context, dbs []string, backupId string, changeDbNames map[string]string) (error, string) {
+	trackId, err := bp.Curator.Restore(ctx, curator.RestoreRequest{
+		Vault:             backupId,
+		SkipUsersRecovery: skipUsersRecovery(len(changeDbNames) != 0),
+		Dbs:               dbs,
+		ChangeDbNames:     changeDbNames,
+	})
 	if err != nil {
 		return err, ""
 	}
-
-	defer func() {
-		err = response.Body.Close()
-		if err != nil {
-			logger.Error("failed to close http body", slog.String("error", err.Error()))
-		}
-	}()
-
-	trackId, err := io.ReadAll(response.Body)
-	if err != nil {
-		logger.ErrorContext(ctx, "Error reading body", "error", err)
-		return err, ""
-	}
-
 	logger.InfoContext(ctx, fmt.Sprintf("'%s' snapshot restoration is started: %s", backupId, trackId))
-	return nil, string(trackId)
+	return nil, trackId
 }
 
-func (bp BackupProvider) prepareRestoreRequest(ctx context.Context, url string, body io.Reader) *http.Request {
-	request, err := http.NewRequest(http.MethodPost, url, body)
-	if err != nil {
-		logger.ErrorContext(ctx, "Failed to prepare request to restore backup", slog.Any("error", err))
-		panic(err)
-	}
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set(common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
-	request.SetBasicAuth(bp.Curator.username, bp.Curator.password)
-	return request
+// skipUsersRecovery returns Curator flag which disables recovery of users from the backup. Users are recovered
+// for databases restored under the same names and are provisioned by the adapter for renamed databases.
+func skipUsersRecovery(renamed bool) string {
+	return strconv.FormatBool(renamed)
 }
 
-func (bp BackupProvider) getJobStatus(snapshotName string, ctx context.Context) (string, error) {
-	url := fmt.Sprintf("%s/%s/%s", bp.Curator.url, "jobstatus", snapshotName)
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		logger.ErrorContext(ctx, "Failed to prepare request to track backup", slog.Any("error", err))
-		return "FAIL", err
-	}
-	request.Header.Set(common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
-	request.SetBasicAuth(bp.Curator.username, bp.Curator.password)
-	response, err := bp.Curator.client.Do(request)
-	if err != nil {
-		logger.ErrorContext(ctx, "Failed to process request by curator", slog.Any("error", err))
-		return "FAIL", err
-	}
-
-	defer func() {
-		err = response.Body.Close()
-		if err != nil {
-			logger.ErrorContext(ctx, "Failed to properly close the response body ")
-		}
-	}()
-
-	if response.StatusCode == 404 {
-		return "FAIL", ErrBackupNotFound
+// legacyProvisioning describes provisioning of databases restored by the legacy restore with regenerated names
+func legacyProvisioning(databases []string, changedNameDb map[string]string, roleTypes []string) *Provisioning {
+	prefixes := regeneratedPrefixes(databases, changedNameDb)
+	provisioning := &Provisioning{Prefixes: prefixes, RoleTypes: roleTypes}
+	for database := range prefixes {
+		provisioning.Databases = append(provisioning.Databases, Database{Name: database})
 	}
+	return provisioning
+}
 
-	var jobStatus JobStatus
-	err = json.NewDecoder(response.Body).Decode(&jobStatus)
+// getJobStatus returns status of Curator job and the reason of failure for failed jobs
+func (bp BackupProvider) getJobStatus(snapshotName string, ctx context.Context) (string, string, error) {
+	jobStatus, err := bp.Curator.JobStatus(ctx, snapshotName)
 	if err != nil {
-		logger.ErrorContext(ctx, "Failed to decode response from JSON", slog.Any("error", err))
-		return "FAIL", fmt.Errorf("failed to decode response from JSON: %w", err)
+		logger.ErrorContext(ctx, "Failed to receive job status from curator", slog.Any("error", err))
+		return "FAIL", "", err
 	}
 
 	var status string
 	switch state := jobStatus.State; state {
-	case "Failed":
+	case curator.FailedState:
 		status = "FAIL"
-	case "Successful":
+	case curator.SuccessfulState:
 		status = "SUCCESS"
-	case "Queued":
+	case curator.QueuedState:
 		status = "PROCEEDING"
-	case "Processing":
+	case curator.ProcessingState:
 		status = "PROCEEDING"
 	default:
 		status = "FAIL"
 	}
-
-	return status, nil
+	if status != "FAIL" {
+		return status, "", nil
+	}
+	reason := jobStatus.Error
+	if reason == "" {
+		reason = jobStatus.Message
+	}
+	if reason == "" {
+		reason = fmt.Sprintf("curator job is in '%s' state", jobStatus.State)
+	}
+	return status, reason, nil
 }
 
 func (bp BackupProvider) getSnapshotStatus(snapshotName string, repo string, ctx context.Context) (SnapshotStatus, error) {
@@ -917,6 +1154,17 @@ func (bp BackupProvider) getActualIndices(backupId string, repoName string, chan
 	return result, nil
 }
 
+// parseBackupRequest supports both the list of databases and the extended BackupRequest form
+func parseBackupRequest(body []byte) (BackupRequest, error) {
+	var request BackupRequest
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		err := common.DecodeJson(bytes.NewReader(body), &request.Databases)
+		return request, err
+	}
+	err := common.DecodeJson(bytes.NewReader(body), &request)
+	return request, err
+}
+
 func backupTrack(backupId string, backupStatus string) ActionTrack {
 	return ActionTrack{
 		Action: "BACKUP",
@@ -942,25 +1190,3 @@ func restoreTrack(backupId string, restoreStatus string, changedNameDb map[strin
 		TrackPath:     nil,
 	}
 }
-
-func namesRegenerateRequestPart(pattern string, replacement string) string {
-	if pattern == "" {
-		return ""
-	}
-	return fmt.Sprintf(`
-		,"rename_pattern": "%s",
-		"rename_replacement": "%s"
-	`, pattern, replacement)
-}
-
-func prepareChangeNameRequestPart(renames []string) string {
-	if len(renames) == 0 {
-		return ""
-	}
-	entries := make([]string, len(renames))
-	for i, pair := range renames {
-		parts := strings.Split(pair, ":")
-		entries[i] = fmt.Sprintf(`"%s":"%s"`, parts[0], parts[1])
-	}
-	return fmt.Sprintf(`,"changeDbNames": {%s}`, strings.Join(entries, ","))
-}
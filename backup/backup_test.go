@@ -16,11 +16,20 @@ package backup
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
+	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/curator"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 var backupProvider BackupProvider
@@ -40,7 +49,13 @@ func setup() {
 	curatorClient := &http.Client{
 		Transport: &common.TransportStub{},
 	}
-	backupProvider = *NewBackupProvider(opensearchClient, curatorClient, "snapshots")
+	opensearch := &cluster.Opensearch{
+		Host:     "localhost",
+		Port:     9200,
+		Protocol: common.Http,
+		Client:   opensearchClient,
+	}
+	backupProvider = *NewBackupProvider(opensearchClient, curatorClient, "snapshots", basic.NewBaseProvider(opensearch))
 	ctx = context.WithValue(context.Background(), common.RequestIdKey, common.GenerateUUID())
 }
 
@@ -66,5 +81,471 @@ func TestRestoreBackupWithEmptyDatabasePrefixes(t *testing.T) {
 	dbs := []string{}
 	restoreInfo, err := backupProvider.RestoreBackup("dbaas_1_1", dbs, "snapshots", false, context.Background())
 	assert.Nil(t, restoreInfo)
+	assert.ErrorIs(t, err, common.ErrValidation)
+}
+
+func TestProvisionRestoredDatabases(t *testing.T) {
+	databases := []Database{
+		{Namespace: "test-namespace", Microservice: "test-service", Name: "db1"},
+		{Namespace: "test-namespace", Microservice: "test-service", Name: "db2"},
+	}
+	changedNameDb := map[string]string{"db1": "restored1"}
+	roleTypes := []string{basic.AdminRoleType, basic.ReadOnlyRoleType}
+	connectionProperties, err := backupProvider.provisionRestoredDatabases(ctx, databases, changedNameDb, roleTypes)
+	assert.Nil(t, err)
+	assert.Len(t, connectionProperties, 2)
+	for i, properties := range connectionProperties {
+		assert.Equal(t, "restored1", properties.ResourcePrefix)
+		assert.Equal(t, roleTypes[i], properties.Role)
+		assert.Contains(t, properties.Username, "restored1_")
+		assert.NotEmpty(t, properties.Password)
+	}
+}
+
+// failingUsersClient fails to receive one user after the given number of them and records removed users
+type failingUsersClient struct {
+	*common.ClientStub
+	received int
+	limit    int
+	deleted  []string
+}
+
+func (c *failingUsersClient) Perform(req *http.Request) (*http.Response, error) {
+	if username, ok := strings.CutPrefix(req.URL.Path, "/_plugins/_security/api/internalusers/"); ok {
+		switch req.Method {
+		case http.MethodGet:
+			c.received++
+			if c.received == c.limit+1 {
+				return nil, errors.New("security plugin is not available")
+			}
+		case http.MethodDelete:
+			c.deleted = append(c.deleted, username)
+		}
+	}
+	return c.ClientStub.Perform(req)
+}
+
+func TestProvisioningRemovesUsersOnFailure(t *testing.T) {
+	client := &failingUsersClient{ClientStub: common.NewClient(), limit: 1}
+	provider := backupProvider
+	provider.baseProvider = basic.NewBaseProvider(&cluster.Opensearch{Host: "localhost", Port: 9200, Protocol: common.Http, Client: client})
+	databases := []Database{{Namespace: "test-namespace", Microservice: "test-service", Name: "db1"}}
+	roleTypes := []string{basic.AdminRoleType, basic.ReadOnlyRoleType}
+
+	connectionProperties, err := provider.provisionRestoredDatabases(ctx, databases, map[string]string{"db1": "restored1"}, roleTypes)
 	assert.NotNil(t, err)
+	assert.Nil(t, connectionProperties)
+	assert.Len(t, client.deleted, 1)
+	assert.Contains(t, client.deleted[0], "restored1_")
+}
+
+func TestRestoreProvisionsUsersWhenCompleted(t *testing.T) {
+	server := curator.NewFakeServer()
+	defer server.Close()
+	provider := backupProvider
+	provider.Curator = curator.NewClient(server.URL, "curator", "password", server.Client())
+	server.SetState("restore_in_progress", curator.ProcessingState, "", "")
+	job := newJob(RestoreJobType, "restore_in_progress", nil, map[string]string{"db1": "provisioned1"})
+	job.Provisioning = &Provisioning{
+		Databases: []Database{{Namespace: "test-namespace", Microservice: "test-service", Name: "db1"}},
+		Prefixes:  job.ChangedNameDb,
+		RoleTypes: []string{basic.AdminRoleType},
+	}
+	provider.registerJob(ctx, job)
+
+	track, err := provider.TrackRestore("restore_in_progress", ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "PROCEEDING", track.Status)
+	assert.Empty(t, track.ConnectionProperties)
+
+	server.SetState("restore_in_progress", curator.SuccessfulState, "", "")
+	track, err = provider.TrackRestore("restore_in_progress", ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "SUCCESS", track.Status)
+	assert.Len(t, track.ConnectionProperties, 1)
+	assert.Equal(t, "provisioned1", track.ConnectionProperties[0].ResourcePrefix)
+	username := track.ConnectionProperties[0].Username
+	password := track.ConnectionProperties[0].Password
+
+	// the retried track resets password, so credentials are not lost with the previous response
+	track, err = provider.TrackRestore("restore_in_progress", ctx, nil)
+	assert.Nil(t, err)
+	assert.Len(t, track.ConnectionProperties, 1)
+	assert.Equal(t, username, track.ConnectionProperties[0].Username)
+	assert.Equal(t, "provisioned1", track.ConnectionProperties[0].ResourcePrefix)
+	assert.NotEmpty(t, track.ConnectionProperties[0].Password)
+	assert.NotEqual(t, password, track.ConnectionProperties[0].Password)
+	job, err = provider.Registry.Get(ctx, RestoreJobType, "restore_in_progress")
+	assert.Nil(t, err)
+	assert.True(t, job.Provisioning.Completed)
+	assert.Equal(t, []string{username}, job.Users)
+}
+
+func TestRegeneratedPrefixes(t *testing.T) {
+	prefixes := regeneratedPrefixes([]string{"db1", "db2"}, map[string]string{
+		"db1test":   "generated_db1test",
+		"db1orders": "generated_db1orders",
+		"db2test":   "dbaas_generated2_5f0c",
+		"db2orders": "dbaas_generated2_9a1e",
+		"db3test":   "unrelated",
+	})
+	assert.Equal(t, map[string]string{"db1": "generated_db1", "db2": "dbaas_generated2", "db3test": "unrelated"}, prefixes)
+}
+
+func TestLegacyProvisioningGroupsIndicesByDatabase(t *testing.T) {
+	provisioning := legacyProvisioning([]string{"db1"}, map[string]string{
+		"db1test":   "dbaas_generated1_5f0c",
+		"db1orders": "dbaas_generated1_9a1e",
+	}, []string{basic.AdminRoleType})
+	assert.Equal(t, []Database{{Name: "db1"}}, provisioning.Databases)
+	assert.Equal(t, map[string]string{"db1": "dbaas_generated1"}, provisioning.Prefixes)
+}
+
+func TestRestoreSkipsUsersRecoveryOnlyForRenamedDatabases(t *testing.T) {
+	server := curator.NewFakeServer()
+	defer server.Close()
+	provider := backupProvider
+	provider.Curator = curator.NewClient(server.URL, "curator", "password", server.Client())
+	server.SetState("users_backup", curator.SuccessfulState, "", "")
+
+	assert.Nil(t, provider.requestRestore(ctx, []string{"db1"}, "users_backup", "", ""))
+	err, _ := provider.requestRestoration(ctx, []string{"db1"}, "users_backup", map[string]string{"db1": "renamed1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "false", server.Restores[0].SkipUsersRecovery)
+	assert.Equal(t, "true", server.Restores[1].SkipUsersRecovery)
+}
+
+func TestTrackRestoreReturnsRegisteredNames(t *testing.T) {
+	changedNameDb := map[string]string{"db1": "restored1"}
+	backupProvider.registerJob(ctx, newJob(RestoreJobType, "20240322T091826", []string{"db1"}, changedNameDb))
+
+	track, err := backupProvider.TrackRestore("20240322T091826", ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "SUCCESS", track.Status)
+	assert.Equal(t, changedNameDb, track.ChangedNameDb)
+
+	job, err := backupProvider.Registry.Get(ctx, RestoreJobType, "20240322T091826")
+	assert.Nil(t, err)
+	assert.Equal(t, "SUCCESS", job.Status)
+	assert.Len(t, job.History, 2)
+	assert.JSONEq(t, `["db1"]`, string(job.Request))
+}
+
+func TestRegistryUpdatesLatestJob(t *testing.T) {
+	registry := NewJobRegistry(common.NewClient())
+	assert.Nil(t, registry.Register(ctx, newJob(BackupJobType, "concurrent_backup", nil, nil)))
+	stale, err := registry.Get(ctx, BackupJobType, "concurrent_backup")
+	assert.Nil(t, err)
+
+	attempts := 0
+	job, err := registry.update(ctx, BackupJobType, "concurrent_backup", func(job *Job) bool {
+		attempts++
+		if attempts == 1 {
+			// the job is changed by another replica after it has been received
+			_, err := registry.UpdateStatus(ctx, BackupJobType, "concurrent_backup", "SUCCESS", "")
+			assert.Nil(t, err)
+		}
+		job.Errors = append(job.Errors, "verification is skipped")
+		return true
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, "SUCCESS", job.Status)
+	assert.Equal(t, []string{"verification is skipped"}, job.Errors)
+
+	stale.Errors = []string{"stale"}
+	assert.ErrorIs(t, registry.save(ctx, stale), common.ErrConflict)
+	job, err = registry.Get(ctx, BackupJobType, "concurrent_backup")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"verification is skipped"}, job.Errors)
+}
+
+func TestRestoreIsProceedingWhileUsersAreProvisionedConcurrently(t *testing.T) {
+	startedAt := time.Now().UTC()
+	job := newJob(RestoreJobType, "restore_provisioned_concurrently", nil, map[string]string{"db1": "concurrent1"})
+	job.Provisioning = &Provisioning{
+		Databases: []Database{{Name: "db1"}},
+		Prefixes:  job.ChangedNameDb,
+		RoleTypes: []string{basic.AdminRoleType},
+		StartedAt: &startedAt,
+	}
+	backupProvider.registerJob(ctx, job)
+
+	track, err := backupProvider.TrackRestore("restore_provisioned_concurrently", ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "PROCEEDING", track.Status)
+	assert.Empty(t, track.ConnectionProperties)
+	job, err = backupProvider.Registry.Get(ctx, RestoreJobType, "restore_provisioned_concurrently")
+	assert.Nil(t, err)
+	assert.Equal(t, "PROCEEDING", job.Status)
+	assert.Empty(t, job.Users)
+}
+
+func TestDrainAwaitsStartedJobs(t *testing.T) {
+	fake := curator.NewFakeServer()
+	defer fake.Close()
+	drainer := common.NewDrainer()
+	provider := backupProvider
+	provider.Curator = curator.NewClient(fake.URL, "", "", fake.Client())
+	provider.baseProvider = basic.NewBaseProvider(nil)
+	provider.baseProvider.Drainer = drainer
+	provider.jobPollInterval = 10 * time.Millisecond
+	fake.SetState("awaited_backup", curator.ProcessingState, "", "")
+
+	provider.awaitJob(ctx, newJob(BackupJobType, "awaited_backup", []string{"db1"}, nil))
+	assert.Equal(t, map[string]int{"backup": 1}, drainer.InFlight())
+	drainer.Drain(ctx)
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, drainer.Wait(waitCtx), context.DeadlineExceeded)
+
+	fake.SetState("awaited_backup", curator.SuccessfulState, "", "")
+	waitCtx, cancel = context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	assert.Nil(t, drainer.Wait(waitCtx))
+}
+
+func TestTrackUnregisteredRestore(t *testing.T) {
+	track, err := backupProvider.TrackRestore("unregistered", ctx, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, track.ChangedNameDb)
+}
+
+func TestVerifyBackupByRestore(t *testing.T) {
+	indices, err := backupProvider.captureIndicesState(ctx, "verified_backup", []string{"snapshots"})
+	assert.Nil(t, err)
+	assert.Equal(t, "snapshots", indices["db1test"].Repository)
+	job := newJob(BackupJobType, "verified_backup", []string{"db1"}, nil)
+	job.Indices = indices
+	backupProvider.registerJob(ctx, job)
+
+	result := backupProvider.verify(ctx, "verified_backup", "snapshots")
+	assert.Equal(t, RestoreVerificationMode, result.Mode)
+	assert.True(t, result.Passed)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, []IndexVerification{
+		{Index: "db1test", SourceDocs: 10, RestoredDocs: 10, MappingsMatched: true, Passed: true},
+	}, result.Indices)
+}
+
+// snapshotRequestsClient records paths of snapshot requests
+type snapshotRequestsClient struct {
+	*common.ClientStub
+	paths []string
+}
+
+func (c *snapshotRequestsClient) Perform(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.URL.Path, "/_snapshot/") {
+		c.paths = append(c.paths, req.URL.Path)
+	}
+	return c.ClientStub.Perform(req)
+}
+
+func TestVerifyBackupFromTenantRepository(t *testing.T) {
+	client := &snapshotRequestsClient{ClientStub: common.NewClient()}
+	provider := backupProvider
+	provider.client = client
+	job := newJob(BackupJobType, "tenant_backup", []string{"db1"}, nil)
+	job.Repositories = map[string][]string{"tenant": {"db1"}}
+	job.WithoutCurator = true
+	provider.registerJob(ctx, job)
+
+	provider.recordIndicesState(ctx, job, "snapshots")
+	job, err := provider.Registry.Get(ctx, BackupJobType, "tenant_backup")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant", job.Indices["db1test"].Repository)
+
+	client.paths = nil
+	result := provider.verify(ctx, "tenant_backup", "snapshots")
+	assert.Equal(t, RestoreVerificationMode, result.Mode)
+	assert.True(t, result.Passed)
+	assert.Contains(t, client.paths, "/_snapshot/tenant/tenant_backup/_restore")
+	for _, path := range client.paths {
+		assert.False(t, strings.HasPrefix(path, "/_snapshot/snapshots/"), path)
+	}
+}
+
+func TestVerifyBackupBySnapshotStatus(t *testing.T) {
+	result := backupProvider.verify(ctx, "unknown_backup", "snapshots")
+	assert.Equal(t, SnapshotStatusVerificationMode, result.Mode)
+	assert.Equal(t, "SUCCESS", result.Snapshot)
+	assert.True(t, result.Passed)
+}
+
+func TestHashMappingIgnoresKeysOrder(t *testing.T) {
+	first, err := hashMapping([]byte(`{"mappings":{"properties":{"a":{"type":"keyword"},"b":{"type":"long"}}}}`))
+	assert.Nil(t, err)
+	second, err := hashMapping([]byte(`{"mappings":{"properties":{"b":{"type":"long"},"a":{"type":"keyword"}}}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestRestoreSibling(t *testing.T) {
+	databases := []Database{{Namespace: "test-namespace", Microservice: "test-service", Name: "db1"}}
+	track, err := backupProvider.RestoreSibling(ctx, "20240322T091826", databases, time.Hour)
+	assert.Nil(t, err)
+	assert.NotNil(t, track.ExpiresAt)
+	assert.Len(t, track.ChangedNameDb, 1)
+	assert.Len(t, track.ConnectionProperties, 1)
+	assert.Equal(t, basic.ReadOnlyRoleType, track.ConnectionProperties[0].Role)
+	assert.Equal(t, track.ChangedNameDb["db1"], track.ConnectionProperties[0].ResourcePrefix)
+
+	job, err := backupProvider.Registry.Get(ctx, RestoreJobType, track.TrackID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{track.ConnectionProperties[0].Username}, job.Users)
+	assert.Nil(t, job.ExpiredAt)
+}
+
+func TestRestoreSiblingRequiresDatabases(t *testing.T) {
+	_, err := backupProvider.RestoreSibling(ctx, "20240322T091826", nil, time.Hour)
+	assert.ErrorIs(t, err, common.ErrValidation)
+}
+
+func TestRestoreSiblingIntoReservedPrefix(t *testing.T) {
+	assert.Nil(t, backupProvider.Registry.ReservePrefix(ctx, "reserved1"))
+	databases := []Database{{Namespace: "test-namespace", Microservice: "test-service", Name: "db1", Prefix: "reserved1"}}
+	_, err := backupProvider.RestoreSibling(ctx, "20240322T091826", databases, time.Hour)
+	assert.ErrorIs(t, err, common.ErrConflict)
+}
+
+func TestRestoreSiblingRemovesUsersOnFailure(t *testing.T) {
+	client := &failingUsersClient{ClientStub: common.NewClient(), limit: 1}
+	provider := backupProvider
+	provider.baseProvider = basic.NewBaseProvider(&cluster.Opensearch{Host: "localhost", Port: 9200, Protocol: common.Http, Client: client})
+	databases := []Database{
+		{Namespace: "test-namespace", Microservice: "test-service", Name: "db1", Prefix: "failed_sibling1"},
+		{Namespace: "test-namespace", Microservice: "test-service", Name: "db2", Prefix: "failed_sibling2"},
+	}
+
+	_, err := provider.RestoreSibling(ctx, "20240322T091826", databases, time.Hour)
+	assert.NotNil(t, err)
+	assert.Len(t, client.deleted, 1)
+	assert.Contains(t, client.deleted[0], "failed_sibling1_")
+}
+
+func TestExpireSiblings(t *testing.T) {
+	expiresAt := time.Now().UTC().Add(-time.Minute)
+	job := newJob(RestoreJobType, "expired_sibling", nil, map[string]string{"db1": "sibling1"})
+	job.ExpiresAt = &expiresAt
+	job.Users = []string{"sibling1_user"}
+	backupProvider.registerJob(ctx, job)
+	assert.Nil(t, backupProvider.Registry.ReservePrefix(ctx, "sibling1"))
+
+	backupProvider.ExpireSiblings(ctx)
+
+	job, err := backupProvider.Registry.Get(ctx, RestoreJobType, "expired_sibling")
+	assert.Nil(t, err)
+	assert.NotNil(t, job.ExpiredAt)
+	assert.Nil(t, backupProvider.Registry.ReservePrefix(ctx, "sibling1"))
+}
+
+func TestParseSiblingTtl(t *testing.T) {
+	ttl, err := parseSiblingTtl("")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultSiblingTtl, ttl)
+	ttl, err = parseSiblingTtl("90m")
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Minute, ttl)
+	_, err = parseSiblingTtl("-1h")
+	assert.NotNil(t, err)
+}
+
+func TestCuratorRequests(t *testing.T) {
+	server := curator.NewFakeServer()
+	defer server.Close()
+	provider := backupProvider
+	provider.Curator = curator.NewClient(server.URL, "curator", "password", server.Client())
+
+	backupID, err := provider.CollectBackup([]string{`db"1`}, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`db"1`}, server.Backups[0].Dbs)
+	status, _, err := provider.getJobStatus(backupID, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "SUCCESS", status)
+
+	server.SetState(backupID, curator.ProcessingState, "", "")
+	status, _, err = provider.getJobStatus(backupID, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "PROCEEDING", status)
+
+	_, statusCode, err := provider.DeleteBackup("unknown", ctx)
+	assert.ErrorIs(t, err, ErrBackupNotFound)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
+
+func TestDeleteUnknownBackupHandler(t *testing.T) {
+	server := curator.NewFakeServer()
+	defer server.Close()
+	provider := backupProvider
+	provider.Curator = curator.NewClient(server.URL, "curator", "password", server.Client())
+
+	request := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/backups/unknown/delete", nil),
+		map[string]string{"backupID": "unknown"})
+	request.Header.Set(common.RequestIdKey, "42")
+	recorder := httptest.NewRecorder()
+	provider.DeleteBackupHandler()(recorder, request)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var response common.ErrorResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, common.ErrNotFound.Code, response.Code)
+	assert.Equal(t, "42", response.RequestId)
+	assert.NotEmpty(t, response.Message)
+}
+
+func TestTrackFailedBackup(t *testing.T) {
+	server := curator.NewFakeServer()
+	defer server.Close()
+	provider := backupProvider
+	provider.Curator = curator.NewClient(server.URL, "curator", "password", server.Client())
+
+	backupID, err := provider.CollectBackup([]string{"db1"}, ctx)
+	assert.Nil(t, err)
+	server.SetState(backupID, curator.FailedState, "snapshot failed", "repository is read-only")
+	track, err := provider.TrackBackup(backupID, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "FAIL", track.Status)
+	assert.Equal(t, "repository is read-only", track.Error)
+
+	server.SetState(backupID, curator.FailedState, "snapshot failed", "")
+	track, err = provider.TrackBackup(backupID, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "snapshot failed", track.Error)
+}
+
+func TestTrackBackupProgress(t *testing.T) {
+	track, err := backupProvider.trackBackup(ctx, "20240322T091826", "snapshots")
+	assert.Nil(t, err)
+	assert.Equal(t, "SUCCESS", track.Status)
+	assert.Empty(t, track.Error)
+	assert.NotNil(t, track.Progress)
+	assert.Equal(t, 100, track.Progress.Percent)
+	assert.Equal(t, 2, track.Progress.ShardsDone)
+	assert.Equal(t, int64(2048), track.Progress.ProcessedBytes)
+	assert.Equal(t, IndexProgress{Percent: 100, ShardsTotal: 2, ShardsDone: 2, ProcessedBytes: 2048, TotalBytes: 2048},
+		track.Progress.Indices["db1test"])
+}
+
+func TestProgressPercent(t *testing.T) {
+	assert.Equal(t, 25, progressPercent(512, 2048, 0, 2))
+	assert.Equal(t, 50, progressPercent(0, 0, 1, 2))
+	assert.Equal(t, 0, progressPercent(0, 0, 0, 0))
+}
+
+func TestValidateDatabases(t *testing.T) {
+	assert.Nil(t, validateDatabases([]Database{{Name: "dbaas_db1", Prefix: "db1restored"}}))
+	assert.ErrorIs(t, validateDatabases(nil), common.ErrValidation)
+	assert.ErrorIs(t, validateDatabases([]Database{{Name: "dbaas_db1", Prefix: "DB1"}}), common.ErrValidation)
+	assert.Nil(t, validateDatabaseNames([]string{"db1", "db2"}))
+	assert.ErrorIs(t, validateDatabaseNames([]string{"db*"}), common.ErrValidation)
+}
+
+func TestWithApiVersionProvisionsRoleTypesOfVersion(t *testing.T) {
+	v1Provider := backupProvider.WithApiVersion(common.ApiV1)
+	v2Provider := backupProvider.WithApiVersion(common.ApiV2)
+	assert.Equal(t, []string{basic.AdminRoleType}, v1Provider.baseProvider.GetProvisionedRoleTypes())
+	assert.Equal(t, v2Provider.baseProvider.GetSupportedRoleTypes(), v2Provider.baseProvider.GetProvisionedRoleTypes())
+	assert.Same(t, backupProvider.Registry, v1Provider.Registry)
+	assert.Same(t, backupProvider.baseProvider.Drainer, v1Provider.baseProvider.Drainer)
 }
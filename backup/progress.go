@@ -0,0 +1,102 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// BackupProgress describes progress of backup calculated from snapshot status. Sizes are specified for the part
+// of the snapshot which is copied to the repository by this backup, files already stored in the repository
+// by previous snapshots are not counted.
+type BackupProgress struct {
+	Percent        int                      `json:"percent"`
+	ShardsTotal    int                      `json:"shardsTotal"`
+	ShardsDone     int                      `json:"shardsDone"`
+	ShardsFailed   int                      `json:"shardsFailed"`
+	ProcessedBytes int64                    `json:"processedBytes"`
+	TotalBytes     int64                    `json:"totalBytes"`
+	Indices        map[string]IndexProgress `json:"indices,omitempty"`
+}
+
+type IndexProgress struct {
+	Percent        int   `json:"percent"`
+	ShardsTotal    int   `json:"shardsTotal"`
+	ShardsDone     int   `json:"shardsDone"`
+	ShardsFailed   int   `json:"shardsFailed"`
+	ProcessedBytes int64 `json:"processedBytes"`
+	TotalBytes     int64 `json:"totalBytes"`
+}
+
+// getBackupProgress collects progress of backup snapshots in all repositories. Progress is optional part of backup
+// track, so nil is returned if status of any snapshot can not be received.
+func (bp BackupProvider) getBackupProgress(ctx context.Context, backupID string, repos []string) *BackupProgress {
+	progress := &BackupProgress{Indices: make(map[string]IndexProgress)}
+	for _, repo := range repos {
+		snapshot, err := bp.getSnapshotStatus(backupID, repo, ctx)
+		if err != nil {
+			logger.WarnContext(ctx, fmt.Sprintf("Failed to receive progress of '%s' snapshot in '%s' repository", backupID, repo),
+				slog.Any("error", err))
+			return nil
+		}
+		progress.ShardsTotal += snapshot.ShardsStats.Total
+		progress.ShardsDone += snapshot.ShardsStats.Done
+		progress.ShardsFailed += snapshot.ShardsStats.Failed
+		progress.ProcessedBytes += snapshot.Stats.Processed.SizeInBytes
+		progress.TotalBytes += snapshot.Stats.Incremental.SizeInBytes
+		for name, index := range snapshot.Indices {
+			indexProgress := IndexProgress{
+				ShardsTotal:    index.ShardsStats.Total,
+				ShardsDone:     index.ShardsStats.Done,
+				ShardsFailed:   index.ShardsStats.Failed,
+				ProcessedBytes: index.Stats.Processed.SizeInBytes,
+				TotalBytes:     index.Stats.Incremental.SizeInBytes,
+			}
+			indexProgress.Percent = progressPercent(indexProgress.ProcessedBytes, indexProgress.TotalBytes,
+				indexProgress.ShardsDone, indexProgress.ShardsTotal)
+			progress.Indices[name] = indexProgress
+		}
+	}
+	progress.Percent = progressPercent(progress.ProcessedBytes, progress.TotalBytes, progress.ShardsDone, progress.ShardsTotal)
+	return progress
+}
+
+// progressPercent calculates percent of processed bytes, percent of done shards is used if there is nothing to copy
+func progressPercent(processedBytes int64, totalBytes int64, shardsDone int, shardsTotal int) int {
+	if totalBytes > 0 {
+		return int(min(processedBytes, totalBytes) * 100 / totalBytes)
+	}
+	if shardsTotal > 0 {
+		return shardsDone * 100 / shardsTotal
+	}
+	return 0
+}
+
+// backupRepositories returns repositories which contain snapshots of the backup, repo is the repository used by Curator
+func backupRepositories(job *Job, repo string) []string {
+	var repos []string
+	if job == nil || !job.WithoutCurator {
+		repos = append(repos, repo)
+	}
+	if job == nil {
+		return repos
+	}
+	for jobRepo := range job.Repositories {
+		repos = append(repos, jobRepo)
+	}
+	return repos
+}
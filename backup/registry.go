@@ -0,0 +1,347 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+const (
+	JobsIndex      = common.SystemIndexPrefix + "jobs"
+	BackupJobType  = "BACKUP"
+	RestoreJobType = "RESTORE"
+
+	expiredJobsBatchSize = 100
+	// jobUpdateAttempts is the number of attempts to update the job which is changed concurrently
+	jobUpdateAttempts = 5
+)
+
+// Job is a backup or restore procedure started by the adapter. Jobs are stored in the adapter's system index,
+// so they survive adapter restarts and are shared between adapter replicas.
+type Job struct {
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`
+	Request       json.RawMessage   `json:"request,omitempty"`
+	ChangedNameDb map[string]string `json:"changedNameDb,omitempty"`
+	Status        string            `json:"status"`
+	History       []JobStatusChange `json:"history,omitempty"`
+	Errors        []string          `json:"errors,omitempty"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+
+	// Indices contains state of source indices at backup time, it is used for backup verification
+	Indices      map[string]IndexState `json:"indices,omitempty"`
+	Verification *VerificationResult   `json:"verification,omitempty"`
+
+	// ExpiresAt is specified for restorations into sibling prefixes, such restored databases with their users
+	// are removed after expiration time and ExpiredAt is set.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	ExpiredAt *time.Time `json:"expiredAt,omitempty"`
+
+	// Provisioning is specified for restorations under new prefixes, Users contains names of users which are
+	// created for new prefixes when restoration is completed.
+	Provisioning *Provisioning `json:"provisioning,omitempty"`
+	Users        []string      `json:"users,omitempty"`
+
+	// Repositories maps snapshot repositories other than the default one to databases which are backed up into them
+	// (or restored from them), such snapshots are managed by the adapter directly instead of Curator.
+	// WithoutCurator is set if all databases of the job are stored in such repositories.
+	Repositories   map[string][]string `json:"repositories,omitempty"`
+	BackupID       string              `json:"backupId,omitempty"`
+	WithoutCurator bool                `json:"withoutCurator,omitempty"`
+
+	// seqNo and primaryTerm identify the stored version of the job, the received job is stored only if it has not
+	// been changed since it was received
+	seqNo       *int
+	primaryTerm *int
+}
+
+// Provisioning describes metadata and users which should be created for restored databases, Prefixes maps names
+// of databases to their new prefixes. StartedAt is set by the adapter which is provisioning them.
+type Provisioning struct {
+	Databases []Database        `json:"databases"`
+	Prefixes  map[string]string `json:"prefixes"`
+	RoleTypes []string          `json:"roleTypes"`
+	StartedAt *time.Time        `json:"startedAt,omitempty"`
+	Completed bool              `json:"completed,omitempty"`
+}
+
+type JobStatusChange struct {
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+}
+
+type storedJob struct {
+	Found       bool `json:"found"`
+	SeqNo       *int `json:"_seq_no"`
+	PrimaryTerm *int `json:"_primary_term"`
+	Source      *Job `json:"_source"`
+}
+
+func (stored storedJob) job() *Job {
+	if stored.Source != nil {
+		stored.Source.seqNo = stored.SeqNo
+		stored.Source.primaryTerm = stored.PrimaryTerm
+	}
+	return stored.Source
+}
+
+type foundJobs struct {
+	Hits struct {
+		Hits []storedJob `json:"hits"`
+	} `json:"hits"`
+}
+
+// JobRegistry keeps track of backup and restore jobs in JobsIndex.
+type JobRegistry struct {
+	client common.Client
+}
+
+func NewJobRegistry(client common.Client) *JobRegistry {
+	return &JobRegistry{client: client}
+}
+
+func (jr JobRegistry) EnsureIndex(ctx context.Context) error {
+	return common.EnsureIndex(ctx, jr.client, JobsIndex)
+}
+
+func newJob(jobType string, id string, request interface{}, changedNameDb map[string]string) *Job {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to marshal request of '%s' job", id), slog.Any("error", err))
+	}
+	return &Job{
+		ID:            id,
+		Type:          jobType,
+		Request:       requestBody,
+		ChangedNameDb: changedNameDb,
+		Status:        "PROCEEDING",
+	}
+}
+
+// Register stores new job with its initial status, the job with the same identifier is replaced.
+func (jr JobRegistry) Register(ctx context.Context, job *Job) error {
+	now := time.Now().UTC()
+	job.seqNo, job.primaryTerm = nil, nil
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	job.History = []JobStatusChange{{Status: job.Status, Time: now}}
+	return jr.save(ctx, job)
+}
+
+// Get returns job of the given type by its identifier, nil is returned if there is no such job.
+func (jr JobRegistry) Get(ctx context.Context, jobType string, id string) (*Job, error) {
+	getRequest := opensearchapi.GetRequest{
+		Index:      JobsIndex,
+		DocumentID: jobDocumentId(jobType, id),
+	}
+	response, err := getRequest.Do(ctx, jr.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive '%s' job: %w", id, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to receive '%s' job, status code is %d", id, response.StatusCode)
+	}
+	var stored storedJob
+	if err = common.ProcessBody(response.Body, &stored); err != nil {
+		return nil, err
+	}
+	if !stored.Found {
+		return nil, nil
+	}
+	return stored.job(), nil
+}
+
+// ListExpired returns jobs which expiration time has come before the given time, but which are not expired yet.
+func (jr JobRegistry) ListExpired(ctx context.Context, before time.Time) ([]*Job, error) {
+	query := fmt.Sprintf(`{"size":%d,"seq_no_primary_term":true,"query":{"bool":{"filter":[{"range":{"expiresAt":{"lte":"%s"}}}],"must_not":[{"exists":{"field":"expiredAt"}}]}}}`,
+		expiredJobsBatchSize, before.UTC().Format(time.RFC3339))
+	searchRequest := opensearchapi.SearchRequest{
+		Index: []string{JobsIndex},
+		Body:  strings.NewReader(query),
+	}
+	var found foundJobs
+	if err := common.DoRequest(searchRequest, jr.client, &found, ctx); err != nil {
+		return nil, fmt.Errorf("failed to search expired jobs: %w", err)
+	}
+	var jobs []*Job
+	for _, hit := range found.Hits.Hits {
+		// search results may not reflect the latest changes because of index refresh interval
+		if job := hit.job(); job != nil && job.isExpired(before) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// UpdateStatus records the current status of the job and an error if it is specified. Status history is
+// extended only if status is changed, empty status keeps the previous one. Nil is returned if job is not registered.
+func (jr JobRegistry) UpdateStatus(ctx context.Context, jobType string, id string, status string, jobError string) (*Job, error) {
+	return jr.update(ctx, jobType, id, func(job *Job) bool {
+		return job.applyStatus(status, jobError)
+	})
+}
+
+// update applies change to the stored job and stores it if change returns true. The job is received and
+// changed again if it is changed concurrently. Nil is returned if job is not registered.
+func (jr JobRegistry) update(ctx context.Context, jobType string, id string, change func(job *Job) bool) (*Job, error) {
+	for attempt := 0; attempt < jobUpdateAttempts; attempt++ {
+		job, err := jr.Get(ctx, jobType, id)
+		if err != nil || job == nil {
+			return job, err
+		}
+		if !change(job) {
+			return job, nil
+		}
+		job.UpdatedAt = time.Now().UTC()
+		err = jr.save(ctx, job)
+		if common.ErrorKindOf(err) != common.ErrConflict {
+			return job, err
+		}
+		logger.DebugContext(ctx, fmt.Sprintf("'%s' %s job is changed concurrently, update is repeated", id, jobType))
+	}
+	return nil, common.NewError(common.ErrConflict, fmt.Sprintf("'%s' %s job is changed concurrently", id, jobType))
+}
+
+// save stores the job, ErrConflict error is returned if the job is received from registry and the stored one
+// has been changed since then
+func (jr JobRegistry) save(ctx context.Context, job *Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	indexRequest := opensearchapi.IndexRequest{
+		Index:         JobsIndex,
+		DocumentID:    jobDocumentId(job.Type, job.ID),
+		Body:          strings.NewReader(string(body)),
+		IfSeqNo:       job.seqNo,
+		IfPrimaryTerm: job.primaryTerm,
+	}
+	response, err := indexRequest.Do(ctx, jr.client)
+	if err != nil {
+		return fmt.Errorf("failed to store '%s' job: %w", job.ID, err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusConflict:
+		return common.NewError(common.ErrConflict, fmt.Sprintf("'%s' %s job is changed concurrently", job.ID, job.Type))
+	default:
+		return fmt.Errorf("failed to store '%s' job, status code is %d", job.ID, response.StatusCode)
+	}
+	var stored storedJob
+	if err = common.ProcessBody(response.Body, &stored); err != nil {
+		return err
+	}
+	job.seqNo, job.primaryTerm = stored.SeqNo, stored.PrimaryTerm
+	logger.DebugContext(ctx, fmt.Sprintf("'%s' %s job is stored with '%s' status", job.ID, job.Type, job.Status))
+	return nil
+}
+
+// ReservePrefix reserves the prefix for databases restored under it, ErrConflict error is returned if the prefix
+// is already reserved. Reservation is created atomically, so concurrent restorations cannot take the same prefix.
+func (jr JobRegistry) ReservePrefix(ctx context.Context, prefix string) error {
+	body, err := json.Marshal(map[string]interface{}{"prefix": prefix, "createdAt": time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	indexRequest := opensearchapi.IndexRequest{
+		Index:      JobsIndex,
+		DocumentID: prefixDocumentId(prefix),
+		Body:       strings.NewReader(string(body)),
+		OpType:     "create",
+	}
+	response, err := indexRequest.Do(ctx, jr.client)
+	if err != nil {
+		return fmt.Errorf("failed to reserve '%s' prefix: %w", prefix, err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return common.NewError(common.ErrConflict, fmt.Sprintf("provided prefix is already reserved: %s", prefix))
+	default:
+		return fmt.Errorf("failed to reserve '%s' prefix, status code is %d", prefix, response.StatusCode)
+	}
+}
+
+// ReleasePrefix removes reservation of the prefix, absent reservation is not an error
+func (jr JobRegistry) ReleasePrefix(ctx context.Context, prefix string) error {
+	deleteRequest := opensearchapi.DeleteRequest{
+		Index:      JobsIndex,
+		DocumentID: prefixDocumentId(prefix),
+	}
+	response, err := deleteRequest.Do(ctx, jr.client)
+	if err != nil {
+		return fmt.Errorf("failed to release '%s' prefix: %w", prefix, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to release '%s' prefix, status code is %d", prefix, response.StatusCode)
+	}
+	return nil
+}
+
+// applyStatus changes status of the job and adds an error, it returns false if the job is not changed
+func (job *Job) applyStatus(status string, jobError string) bool {
+	changed := false
+	if status != "" && job.Status != status {
+		job.Status = status
+		job.History = append(job.History, JobStatusChange{Status: status, Time: time.Now().UTC()})
+		changed = true
+	}
+	if jobError != "" && (len(job.Errors) == 0 || job.Errors[len(job.Errors)-1] != jobError) {
+		job.Errors = append(job.Errors, jobError)
+		changed = true
+	}
+	return changed
+}
+
+// needsProvisioning checks that users for new prefixes of the job are not created yet
+func (job *Job) needsProvisioning() bool {
+	return job != nil && job.Provisioning != nil && !job.Provisioning.Completed
+}
+
+// provisioned returns true if users for databases of the job are provisioned
+func (job *Job) provisioned() bool {
+	return job != nil && job.Provisioning != nil && job.Provisioning.Completed && len(job.Users) != 0
+}
+
+func (job *Job) isExpired(now time.Time) bool {
+	return job.ExpiresAt != nil && job.ExpiredAt == nil && !job.ExpiresAt.After(now)
+}
+
+// jobDocumentId separates backup and restore jobs, because restore may be tracked by backup identifier
+func jobDocumentId(jobType string, id string) string {
+	return fmt.Sprintf("%s_%s", strings.ToLower(jobType), id)
+}
+
+func prefixDocumentId(prefix string) string {
+	return fmt.Sprintf("prefix_%s", prefix)
+}
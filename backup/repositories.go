@@ -0,0 +1,447 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/gorilla/mux"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+const (
+	FsRepositoryType = "fs"
+	S3RepositoryType = "s3"
+)
+
+var ErrRepositoryNotFound = common.NewError(common.ErrNotFound, "snapshot repository not found")
+var ErrInvalidRepository = common.NewError(common.ErrValidation, "snapshot repository is invalid")
+
+// SnapshotRepository describes OpenSearch snapshot repository. Settings are passed to OpenSearch as is,
+// `location` is required for filesystem repositories and `bucket` is required for S3-compatible ones.
+type SnapshotRepository struct {
+	Name     string                 `json:"name,omitempty"`
+	Type     string                 `json:"type"`
+	Settings map[string]interface{} `json:"settings"`
+	Default  bool                   `json:"default,omitempty"`
+}
+
+func (bp BackupProvider) RegisterRepositoryHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		name := mux.Vars(r)["repository"]
+		logger.InfoContext(ctx, fmt.Sprintf("Request to register '%s' snapshot repository is received", name))
+		defer r.Body.Close()
+
+		var repository SnapshotRepository
+		if err := common.DecodeJson(r.Body, &repository); err != nil {
+			logger.ErrorContext(ctx, "Failed to decode request body", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		repository.Name = name
+		if err := bp.RegisterRepository(ctx, repository); err != nil {
+			logger.ErrorContext(ctx, "Failed to register snapshot repository", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		responseBody, err := json.Marshal(repository)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, http.StatusOK)
+	}
+}
+
+func (bp BackupProvider) VerifyRepositoryHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		name := mux.Vars(r)["repository"]
+		logger.InfoContext(ctx, fmt.Sprintf("Request to verify '%s' snapshot repository is received", name))
+		result, err := bp.VerifyRepository(ctx, name)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to verify snapshot repository", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, result, http.StatusOK)
+	}
+}
+
+func (bp BackupProvider) ListRepositoriesHandler(defaultRepo string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		logger.InfoContext(ctx, "Request to list snapshot repositories is received")
+		repositories, err := bp.ListRepositories(ctx, defaultRepo)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to receive snapshot repositories", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		responseBody, err := json.Marshal(repositories)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, http.StatusOK)
+	}
+}
+
+func (bp BackupProvider) DeleteRepositoryHandler(defaultRepo string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		name := mux.Vars(r)["repository"]
+		logger.InfoContext(ctx, fmt.Sprintf("Request to remove '%s' snapshot repository is received", name))
+		if name == defaultRepo {
+			message := fmt.Sprintf("'%s' repository is used by default and cannot be removed", name)
+			logger.ErrorContext(ctx, message)
+			common.WriteError(ctx, w, common.NewError(common.ErrValidation, message))
+			return
+		}
+		if err := bp.DeleteRepository(ctx, name); err != nil {
+			logger.ErrorContext(ctx, "Failed to remove snapshot repository", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// RegisterRepository creates or updates snapshot repository. OpenSearch verifies repository on registration,
+// so repository which is not accessible from all nodes is not registered.
+func (bp BackupProvider) RegisterRepository(ctx context.Context, repository SnapshotRepository) error {
+	if err := validateRepository(repository); err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"type":     repository.Type,
+		"settings": repository.Settings,
+	})
+	if err != nil {
+		return err
+	}
+	createRequest := opensearchapi.SnapshotCreateRepositoryRequest{
+		Repository: repository.Name,
+		Body:       strings.NewReader(string(body)),
+	}
+	response, err := createRequest.Do(ctx, bp.client)
+	if err != nil {
+		return fmt.Errorf("failed to register '%s' repository: %w", repository.Name, err)
+	}
+	defer response.Body.Close()
+	if response.IsError() {
+		return fmt.Errorf("failed to register '%s' repository: %s", repository.Name, response.String())
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("'%s' snapshot repository of '%s' type is registered", repository.Name, repository.Type))
+	return nil
+}
+
+// VerifyRepository checks that repository is accessible from all nodes and returns nodes which verified it.
+func (bp BackupProvider) VerifyRepository(ctx context.Context, name string) ([]byte, error) {
+	verifyRequest := opensearchapi.SnapshotVerifyRepositoryRequest{
+		Repository: name,
+	}
+	response, err := verifyRequest.Do(ctx, bp.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify '%s' repository: %w", name, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrRepositoryNotFound, name)
+	}
+	if response.IsError() {
+		return nil, fmt.Errorf("failed to verify '%s' repository: %s", name, response.String())
+	}
+	var result map[string]interface{}
+	if err = common.ProcessBody(response.Body, &result); err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// ListRepositories returns all registered snapshot repositories sorted by name.
+func (bp BackupProvider) ListRepositories(ctx context.Context, defaultRepo string) ([]SnapshotRepository, error) {
+	getRequest := opensearchapi.SnapshotGetRepositoryRequest{}
+	var found map[string]SnapshotRepository
+	if err := common.DoRequest(getRequest, bp.client, &found, ctx); err != nil {
+		return nil, fmt.Errorf("failed to receive snapshot repositories: %w", err)
+	}
+	repositories := make([]SnapshotRepository, 0, len(found))
+	for name, repository := range found {
+		repository.Name = name
+		repository.Default = name == defaultRepo
+		repositories = append(repositories, repository)
+	}
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].Name < repositories[j].Name
+	})
+	return repositories, nil
+}
+
+// DeleteRepository unregisters snapshot repository, snapshots themselves are kept in the storage.
+func (bp BackupProvider) DeleteRepository(ctx context.Context, name string) error {
+	deleteRequest := opensearchapi.SnapshotDeleteRepositoryRequest{
+		Repository: []string{name},
+	}
+	response, err := deleteRequest.Do(ctx, bp.client)
+	if err != nil {
+		return fmt.Errorf("failed to remove '%s' repository: %w", name, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrRepositoryNotFound, name)
+	}
+	if response.IsError() {
+		return fmt.Errorf("failed to remove '%s' repository: %s", name, response.String())
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("'%s' snapshot repository is removed", name))
+	return nil
+}
+
+// checkRepository returns ErrRepositoryNotFound error if the repository is not registered
+func (bp BackupProvider) checkRepository(ctx context.Context, name string) error {
+	getRequest := opensearchapi.SnapshotGetRepositoryRequest{
+		Repository: []string{name},
+	}
+	response, err := getRequest.Do(ctx, bp.client)
+	if err != nil {
+		return fmt.Errorf("failed to receive '%s' repository: %w", name, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrRepositoryNotFound, name)
+	}
+	if response.IsError() {
+		return fmt.Errorf("failed to receive '%s' repository: %s", name, response.String())
+	}
+	return nil
+}
+
+// createSnapshot collects snapshot of databases in the given repository without waiting for its completion.
+// Other databases of the backup are excluded from the snapshot if their names start with names of the databases.
+func (bp BackupProvider) createSnapshot(ctx context.Context, repo string, snapshot string, dbs []string, known []string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"indices":              strings.Join(databasePatterns(dbs, known), ","),
+		"include_global_state": false,
+	})
+	if err != nil {
+		return err
+	}
+	createRequest := opensearchapi.SnapshotCreateRequest{
+		Repository: repo,
+		Snapshot:   snapshot,
+		Body:       strings.NewReader(string(body)),
+	}
+	response, err := createRequest.Do(ctx, bp.client)
+	if err != nil {
+		return fmt.Errorf("failed to create '%s' snapshot in '%s' repository: %w", snapshot, repo, err)
+	}
+	defer response.Body.Close()
+	if response.IsError() {
+		return fmt.Errorf("failed to create '%s' snapshot in '%s' repository: %s", snapshot, repo, response.String())
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("'%s' snapshot of %v databases is started in '%s' repository", snapshot, dbs, repo))
+	return nil
+}
+
+func (bp BackupProvider) deleteSnapshot(ctx context.Context, repo string, snapshot string) error {
+	deleteRequest := opensearchapi.SnapshotDeleteRequest{
+		Repository: repo,
+		Snapshot:   snapshot,
+	}
+	response, err := deleteRequest.Do(ctx, bp.client)
+	if err != nil {
+		return fmt.Errorf("failed to delete '%s' snapshot in '%s' repository: %w", snapshot, repo, err)
+	}
+	defer response.Body.Close()
+	if response.IsError() && response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete '%s' snapshot in '%s' repository: %s", snapshot, repo, response.String())
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("'%s' snapshot is deleted from '%s' repository", snapshot, repo))
+	return nil
+}
+
+// restoreFromRepository restores database from the snapshot in the given repository under the target name.
+// If database is restored under the same name, existing indices are closed to be replaced by restored ones.
+// Known are all databases of the snapshot, their indices are not restored as indices of the database.
+func (bp BackupProvider) restoreFromRepository(ctx context.Context, repo string, snapshot string, db string, target string,
+	known []string) error {
+	patterns := databasePatterns([]string{db}, known)
+	request := map[string]interface{}{
+		"indices":              strings.Join(patterns, ","),
+		"include_global_state": false,
+	}
+	if target != db {
+		request["rename_pattern"] = fmt.Sprintf("^%s(_.*)$", regexp.QuoteMeta(db))
+		request["rename_replacement"] = target + "$1"
+	} else {
+		// only indices which are replaced are closed, live indices of other databases are kept open
+		snapshotStatus, err := bp.getSnapshotStatus(snapshot, repo, ctx)
+		if err != nil {
+			return err
+		}
+		var indices []string
+		for index := range snapshotStatus.Indices {
+			if matchesPatterns(index, patterns) {
+				indices = append(indices, index)
+			}
+		}
+		if err = bp.closeIndices(ctx, indices); err != nil {
+			return err
+		}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	restoreRequest := opensearchapi.SnapshotRestoreRequest{
+		Repository: repo,
+		Snapshot:   snapshot,
+		Body:       strings.NewReader(string(body)),
+	}
+	response, err := restoreRequest.Do(ctx, bp.client)
+	if err != nil {
+		return fmt.Errorf("failed to restore '%s' database from '%s' repository: %w", db, repo, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return ErrBackupNotFound
+	}
+	if response.IsError() {
+		return fmt.Errorf("failed to restore '%s' database from '%s' repository: %s", db, repo, response.String())
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("'%s' database restoration as '%s' from '%s' snapshot in '%s' repository is started",
+		db, target, snapshot, repo))
+	return nil
+}
+
+func (bp BackupProvider) closeIndices(ctx context.Context, indices []string) error {
+	if len(indices) == 0 {
+		return nil
+	}
+	ignoreUnavailable := true
+	closeRequest := opensearchapi.IndicesCloseRequest{
+		Index:             indices,
+		IgnoreUnavailable: &ignoreUnavailable,
+	}
+	response, err := closeRequest.Do(ctx, bp.client)
+	if err != nil {
+		return fmt.Errorf("failed to close %v indices: %w", indices, err)
+	}
+	defer response.Body.Close()
+	if response.IsError() && response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to close %v indices: %s", indices, response.String())
+	}
+	return nil
+}
+
+// snapshotJobStatus converts state of snapshot to the status of backup job
+func snapshotJobStatus(state string) string {
+	switch state {
+	case "SUCCESS":
+		return "SUCCESS"
+	case "FAILED", "PARTIAL", "ABORTED":
+		return "FAIL"
+	default:
+		return "PROCEEDING"
+	}
+}
+
+// combineJobStatuses returns status of job consisting of several parts: job is failed if any part is failed,
+// and it is in progress if any part is in progress.
+func combineJobStatuses(first string, second string) string {
+	if first == "FAIL" || second == "FAIL" {
+		return "FAIL"
+	}
+	if first == "PROCEEDING" || second == "PROCEEDING" {
+		return "PROCEEDING"
+	}
+	return "SUCCESS"
+}
+
+func validateRepository(repository SnapshotRepository) error {
+	if repository.Name == "" {
+		return fmt.Errorf("%w: name is not specified", ErrInvalidRepository)
+	}
+	var requiredSetting string
+	switch repository.Type {
+	case FsRepositoryType:
+		requiredSetting = "location"
+	case S3RepositoryType:
+		requiredSetting = "bucket"
+	default:
+		return fmt.Errorf("%w: '%s' type is not supported, supported types are '%s' and '%s'",
+			ErrInvalidRepository, repository.Type, FsRepositoryType, S3RepositoryType)
+	}
+	if value, ok := repository.Settings[requiredSetting].(string); !ok || value == "" {
+		return fmt.Errorf("%w: '%s' setting is required for '%s' repository", ErrInvalidRepository, requiredSetting, repository.Type)
+	}
+	return nil
+}
+
+// databasePatterns returns patterns of indices of the given databases. Indices of the database are named
+// with "_" after the database name, so known databases which names start with the name of the database
+// (e.g. "orders_archive" for "orders") are excluded unless they are requested too.
+func databasePatterns(dbs []string, known []string) []string {
+	requested := make(map[string]struct{}, len(dbs))
+	patterns := make([]string, 0, len(dbs))
+	for _, db := range dbs {
+		requested[db] = struct{}{}
+		patterns = append(patterns, db+"_*")
+	}
+	excluded := make(map[string]struct{})
+	for _, other := range known {
+		if _, ok := requested[other]; ok {
+			continue
+		}
+		if _, ok := excluded[other]; ok {
+			continue
+		}
+		for _, db := range dbs {
+			if strings.HasPrefix(other, db+"_") {
+				excluded[other] = struct{}{}
+				patterns = append(patterns, "-"+other+"_*")
+				break
+			}
+		}
+	}
+	return patterns
+}
+
+// matchesPatterns checks that the index matches patterns returned by databasePatterns
+func matchesPatterns(index string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		if excluded, ok := strings.CutPrefix(pattern, "-"); ok {
+			if strings.HasPrefix(index, strings.TrimSuffix(excluded, "*")) {
+				return false
+			}
+		} else if strings.HasPrefix(index, strings.TrimSuffix(pattern, "*")) {
+			matched = true
+		}
+	}
+	return matched
+}
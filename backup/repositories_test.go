@@ -0,0 +1,240 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/curator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRepository(t *testing.T) {
+	err := backupProvider.RegisterRepository(ctx, SnapshotRepository{
+		Name:     "tenant",
+		Type:     S3RepositoryType,
+		Settings: map[string]interface{}{"bucket": "tenant-bucket", "base_path": "opensearch"},
+	})
+	assert.Nil(t, err)
+}
+
+func TestRegisterInvalidRepository(t *testing.T) {
+	repositories := []SnapshotRepository{
+		{Name: "", Type: FsRepositoryType, Settings: map[string]interface{}{"location": "/backups"}},
+		{Name: "tenant", Type: FsRepositoryType, Settings: map[string]interface{}{}},
+		{Name: "tenant", Type: S3RepositoryType, Settings: map[string]interface{}{"bucket": ""}},
+		{Name: "tenant", Type: "hdfs", Settings: map[string]interface{}{"uri": "hdfs://namenode:8020/"}},
+	}
+	for _, repository := range repositories {
+		err := backupProvider.RegisterRepository(ctx, repository)
+		assert.ErrorIs(t, err, ErrInvalidRepository)
+	}
+}
+
+func TestListRepositories(t *testing.T) {
+	repositories, err := backupProvider.ListRepositories(ctx, "snapshots")
+	assert.Nil(t, err)
+	assert.Len(t, repositories, 2)
+	assert.Equal(t, "snapshots", repositories[0].Name)
+	assert.True(t, repositories[0].Default)
+	assert.Equal(t, "tenant", repositories[1].Name)
+	assert.Equal(t, S3RepositoryType, repositories[1].Type)
+	assert.False(t, repositories[1].Default)
+}
+
+func TestVerifyRepository(t *testing.T) {
+	result, err := backupProvider.VerifyRepository(ctx, "tenant")
+	assert.Nil(t, err)
+	assert.Contains(t, string(result), "opensearch-1")
+}
+
+func TestDeleteMissingRepository(t *testing.T) {
+	err := backupProvider.DeleteRepository(ctx, "missing")
+	assert.ErrorIs(t, err, ErrRepositoryNotFound)
+}
+
+func TestCollectBackupIntoRepositories(t *testing.T) {
+	job, err := backupProvider.collectBackup(ctx, BackupRequest{
+		Databases:    []string{"db1", "db2"},
+		Repositories: map[string]string{"db2": "tenant"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "20240322T091826", job.ID)
+	assert.Equal(t, map[string][]string{"tenant": {"db2"}}, job.Repositories)
+	assert.False(t, job.WithoutCurator)
+
+	job, err = backupProvider.collectBackup(ctx, BackupRequest{
+		Databases:    []string{"db3"},
+		Repositories: map[string]string{"db3": "tenant"},
+	})
+	assert.Nil(t, err)
+	assert.True(t, job.WithoutCurator)
+	timestamp, _, _ := strings.Cut(job.ID, "_")
+	_, err = time.Parse(backupIdLayout, timestamp)
+	assert.Nil(t, err)
+	backupProvider.registerJob(ctx, job)
+
+	track, err := backupProvider.TrackBackup(job.ID, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "SUCCESS", track.Status)
+}
+
+func TestCollectBackupWithoutCuratorGeneratesUniqueIds(t *testing.T) {
+	request := BackupRequest{Databases: []string{"db3"}, Repositories: map[string]string{"db3": "tenant"}}
+	first, err := backupProvider.collectBackup(ctx, request)
+	assert.Nil(t, err)
+	second, err := backupProvider.collectBackup(ctx, request)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	backupProvider.registerJob(ctx, first)
+	backupProvider.registerJob(ctx, second)
+	job, err := backupProvider.Registry.Get(ctx, BackupJobType, first.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, first.ID, job.ID)
+}
+
+func TestCollectBackupIntoMissingRepository(t *testing.T) {
+	fake := curator.NewFakeServer()
+	defer fake.Close()
+	provider := backupProvider
+	provider.Curator = curator.NewClient(fake.URL, "", "", fake.Client())
+
+	_, err := provider.collectBackup(ctx, BackupRequest{
+		Databases:    []string{"db1", "db2"},
+		Repositories: map[string]string{"db2": "missing"},
+	})
+	assert.ErrorIs(t, err, ErrRepositoryNotFound)
+	assert.Empty(t, fake.Backups)
+}
+
+// failingSnapshotClient fails to create snapshots in the given repository and records removed snapshots
+type failingSnapshotClient struct {
+	*common.ClientStub
+	repo    string
+	deleted []string
+}
+
+func (c *failingSnapshotClient) Perform(req *http.Request) (*http.Response, error) {
+	if path, ok := strings.CutPrefix(req.URL.Path, "/_snapshot/"); ok && strings.Contains(path, "/") {
+		switch {
+		case req.Method == http.MethodPut && strings.HasPrefix(path, c.repo+"/"):
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody}, nil
+		case req.Method == http.MethodDelete:
+			c.deleted = append(c.deleted, path)
+		}
+	}
+	return c.ClientStub.Perform(req)
+}
+
+func TestCollectBackupRemovesStartedSnapshotsOnFailure(t *testing.T) {
+	fake := curator.NewFakeServer()
+	defer fake.Close()
+	client := &failingSnapshotClient{ClientStub: common.NewClient(), repo: "tenant"}
+	provider := backupProvider
+	provider.client = client
+	provider.Curator = curator.NewClient(fake.URL, "", "", fake.Client())
+
+	_, err := provider.collectBackup(ctx, BackupRequest{
+		Databases:    []string{"db1", "db2", "db3"},
+		Repositories: map[string]string{"db2": "snapshots", "db3": "tenant"},
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, fake.Backups, 1)
+	assert.Len(t, fake.Evicted, 1)
+	assert.Equal(t, []string{"snapshots/" + fake.Evicted[0]}, client.deleted)
+}
+
+func TestDatabasePatterns(t *testing.T) {
+	known := []string{"orders", "orders_archive"}
+	patterns := databasePatterns([]string{"orders"}, known)
+	assert.Equal(t, []string{"orders_*", "-orders_archive_*"}, patterns)
+	assert.True(t, matchesPatterns("orders_2024", patterns))
+	assert.False(t, matchesPatterns("orders_archive_2024", patterns))
+	assert.False(t, matchesPatterns("orders2_2024", patterns))
+
+	patterns = databasePatterns(known, known)
+	assert.Equal(t, []string{"orders_*", "orders_archive_*"}, patterns)
+	assert.True(t, matchesPatterns("orders_archive_2024", patterns))
+}
+
+func TestRestoreFromRepository(t *testing.T) {
+	backupJob := newJob(BackupJobType, "tenant_backup", []string{"db4"}, nil)
+	backupJob.Repositories = map[string][]string{"tenant": {"db4"}}
+	backupJob.WithoutCurator = true
+	backupProvider.registerJob(ctx, backupJob)
+
+	job, err := backupProvider.processRestoration(ctx, "tenant_backup", RestorationRequest{
+		Databases:       []Database{{Namespace: "test-namespace", Microservice: "test-service", Name: "db4", Prefix: "db4restored"}},
+		RegenerateNames: true,
+	})
+	assert.Nil(t, err)
+	assert.True(t, job.WithoutCurator)
+	assert.Equal(t, "tenant_backup", job.BackupID)
+	assert.Equal(t, map[string]string{"db4": "db4restored"}, job.ChangedNameDb)
+	assert.Equal(t, map[string][]string{"tenant": {"db4restored"}}, job.Repositories)
+}
+
+// usersClient returns the given users of OpenSearch security plugin
+type usersClient struct {
+	*common.ClientStub
+	users string
+}
+
+func (c *usersClient) Perform(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet && strings.TrimSuffix(req.URL.Path, "/") == "/_plugins/_security/api/internalusers" {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(c.users))}, nil
+	}
+	return c.ClientStub.Perform(req)
+}
+
+func TestRestorationIntoExistingPrefix(t *testing.T) {
+	provider := backupProvider
+	provider.client = &usersClient{ClientStub: common.NewClient(),
+		users: `{"orders_admin":{"attributes":{"resource_prefix":"orders"}}}`}
+
+	_, err := provider.processRestoration(ctx, "tenant_backup", RestorationRequest{
+		Databases:       []Database{{Namespace: "test-namespace", Microservice: "test-service", Name: "db4", Prefix: "orders"}},
+		RegenerateNames: true,
+	})
+	assert.ErrorIs(t, err, common.ErrConflict)
+	assert.Equal(t, http.StatusConflict, common.ErrorKindOf(err).Status)
+}
+
+func TestParseBackupRequest(t *testing.T) {
+	request, err := parseBackupRequest([]byte(` ["db1","db2"]`))
+	assert.Nil(t, err)
+	assert.Equal(t, BackupRequest{Databases: []string{"db1", "db2"}}, request)
+
+	request, err = parseBackupRequest([]byte(`{"databases":["db1"],"repositories":{"db1":"tenant"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, BackupRequest{Databases: []string{"db1"}, Repositories: map[string]string{"db1": "tenant"}}, request)
+
+	_, err = parseBackupRequest([]byte(`{"databases":"db1"}`))
+	assert.ErrorIs(t, err, common.ErrValidation)
+	_, err = parseBackupRequest([]byte(`{"databases":`))
+	assert.ErrorIs(t, err, common.ErrBadRequest)
+}
+
+func TestCombineJobStatuses(t *testing.T) {
+	assert.Equal(t, "SUCCESS", combineJobStatuses("SUCCESS", snapshotJobStatus("SUCCESS")))
+	assert.Equal(t, "PROCEEDING", combineJobStatuses("SUCCESS", snapshotJobStatus("IN_PROGRESS")))
+	assert.Equal(t, "FAIL", combineJobStatuses("PROCEEDING", snapshotJobStatus("PARTIAL")))
+}
@@ -0,0 +1,217 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
+	"github.com/gorilla/mux"
+)
+
+const DefaultSiblingTtl = 24 * time.Hour
+
+// SiblingRestoreRequest describes restoration of databases into new prefixes next to the live ones.
+// Ttl is specified in Go duration format (e.g. "12h"), DefaultSiblingTtl is used if it is empty.
+type SiblingRestoreRequest struct {
+	Databases []Database `json:"databases"`
+	Ttl       string     `json:"ttl,omitempty"`
+}
+
+// RestoreSiblingHandler restores databases from the backup into sibling prefixes, so old data can be compared
+// with live data. Only read-only users are created for sibling prefixes, and the siblings are removed after TTL.
+func (bp BackupProvider) RestoreSiblingHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		backupID := mux.Vars(r)["backupID"]
+		logger.InfoContext(ctx, fmt.Sprintf("Request to restore '%s' backup into sibling prefixes is received", backupID))
+		defer r.Body.Close()
+
+		var req SiblingRestoreRequest
+		err := common.DecodeJson(r.Body, &req)
+		if err == nil {
+			err = validateDatabases(req.Databases)
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Invalid request to restore backup into sibling prefixes", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		ttl, err := parseSiblingTtl(req.Ttl)
+		if err != nil {
+			logger.ErrorContext(ctx, "Invalid TTL is specified for sibling restoration", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+
+		response, err := bp.RestoreSibling(ctx, backupID, req.Databases, ttl)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to restore backup into sibling prefixes", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		responseBody, err := json.Marshal(response)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, http.StatusAccepted)
+	}
+}
+
+// RestoreSibling starts restoration of databases into regenerated (or explicitly specified) prefixes and creates
+// read-only users for them. Restoration is registered with expiration time, so it is removed by ExpireSiblings.
+func (bp BackupProvider) RestoreSibling(ctx context.Context, backupID string, databases []Database,
+	ttl time.Duration) (ActionTrack, error) {
+	if err := validateDatabases(databases); err != nil {
+		return ActionTrack{}, err
+	}
+	// restoration without renaming would overwrite live data, so prefixes are reserved in advance
+	// and checked after reservation, when concurrent restorations cannot take them anymore
+	var reserved []string
+	releasePrefixes := func() {
+		for _, prefix := range reserved {
+			if err := bp.Registry.ReleasePrefix(ctx, prefix); err != nil {
+				logger.WarnContext(ctx, fmt.Sprintf("Failed to release '%s' prefix", prefix), slog.Any("error", err))
+			}
+		}
+	}
+	for _, database := range databases {
+		if database.Prefix == "" {
+			continue
+		}
+		err := bp.Registry.ReservePrefix(ctx, database.Prefix)
+		if err == nil {
+			reserved = append(reserved, database.Prefix)
+			_, err = bp.checkPrefixUniqueness(database.Prefix, ctx)
+		}
+		if err != nil {
+			releasePrefixes()
+			return ActionTrack{}, err
+		}
+	}
+
+	request := RestorationRequest{Databases: databases, RegenerateNames: true}
+	job, err := bp.processRestoration(ctx, backupID, request)
+	if err != nil {
+		releasePrefixes()
+		return ActionTrack{}, err
+	}
+	changedNameDb, trackId := job.ChangedNameDb, job.ID
+	expiresAt := time.Now().UTC().Add(ttl)
+	job.ExpiresAt = &expiresAt
+	job.Provisioning = &Provisioning{
+		Databases: databases,
+		Prefixes:  changedNameDb,
+		RoleTypes: []string{basic.ReadOnlyRoleType},
+	}
+	// sibling cannot be expired and its users cannot be tracked without registry
+	if err = bp.Registry.Register(ctx, job); err != nil {
+		return ActionTrack{}, fmt.Errorf("failed to register '%s' sibling restoration: %w", trackId, err)
+	}
+	bp.awaitJob(ctx, job)
+
+	// users are created before restoration is completed, so they are returned in this response
+	connectionProperties, err := bp.provisionRestoredJob(ctx, job)
+	if err != nil {
+		return ActionTrack{}, err
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("'%s' backup is being restored into sibling prefixes %v, they expire at %s",
+		backupID, changedNameDb, expiresAt.Format(time.RFC3339)))
+
+	response, err := bp.TrackRestore(trackId, ctx, changedNameDb)
+	if err != nil {
+		return ActionTrack{}, err
+	}
+	response.ConnectionProperties = connectionProperties
+	response.ExpiresAt = &expiresAt
+	return response, nil
+}
+
+// ExpireSiblingsPeriodically removes expired sibling restorations with the given interval until context is done.
+func (bp BackupProvider) ExpireSiblingsPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		bp.ExpireSiblings(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireSiblings removes indices, metadata and users of sibling restorations which TTL is over.
+// Restorations which resources cannot be removed are kept and retried next time.
+func (bp BackupProvider) ExpireSiblings(ctx context.Context) {
+	now := time.Now().UTC()
+	jobs, err := bp.Registry.ListExpired(ctx, now)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to receive expired sibling restorations", slog.Any("error", err))
+		return
+	}
+	for _, job := range jobs {
+		logger.InfoContext(ctx, fmt.Sprintf("'%s' sibling restoration is expired, removing %v prefixes", job.ID, job.ChangedNameDb))
+		failed := bp.baseProvider.DropResources(siblingResources(job), ctx)
+		if len(failed) > 0 {
+			logger.ErrorContext(ctx, fmt.Sprintf("Failed to remove resources of '%s' sibling restoration: %+v", job.ID, failed))
+			continue
+		}
+		for _, prefix := range job.ChangedNameDb {
+			if err = bp.Registry.ReleasePrefix(ctx, prefix); err != nil {
+				logger.WarnContext(ctx, fmt.Sprintf("Failed to release '%s' prefix", prefix), slog.Any("error", err))
+			}
+		}
+		job.ExpiredAt = &now
+		job.UpdatedAt = now
+		if err = bp.Registry.save(ctx, job); err != nil {
+			logger.ErrorContext(ctx, fmt.Sprintf("Failed to mark '%s' sibling restoration as expired", job.ID), slog.Any("error", err))
+		}
+	}
+}
+
+func siblingResources(job *Job) []dao.DbResource {
+	var resources []dao.DbResource
+	for _, prefix := range job.ChangedNameDb {
+		resources = append(resources, dao.DbResource{Kind: common.ResourcePrefixKind, Name: prefix})
+	}
+	for _, user := range job.Users {
+		resources = append(resources, dao.DbResource{Kind: common.UserKind, Name: user})
+	}
+	return resources
+}
+
+func parseSiblingTtl(ttl string) (time.Duration, error) {
+	if ttl == "" {
+		return DefaultSiblingTtl, nil
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, &common.Error{Kind: common.ErrValidation, Message: "failed to parse TTL", Cause: err}
+	}
+	if duration <= 0 {
+		return 0, common.NewError(common.ErrValidation, fmt.Sprintf("TTL must be positive, but '%s' is specified", ttl))
+	}
+	return duration, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
+)
+
+// provisioningTimeout is the time after which provisioning which has not been completed, e.g. because adapter
+// was restarted, can be started again
+const provisioningTimeout = 5 * time.Minute
+
+var errProvisioningInProgress = common.NewError(common.ErrConflict, "users for restored databases are being provisioned")
+
+// provisionRestoredDatabases prepares databases restored under regenerated prefixes for usage: it copies metadata
+// documents of source databases (or regenerates them if source metadata is absent) and creates users of the given
+// role types for each new prefix. It returns connection properties of all created users, users which are created
+// before a failure are removed.
+func (bp BackupProvider) provisionRestoredDatabases(ctx context.Context, databases []Database,
+	changedNameDb map[string]string, roleTypes []string) ([]common.ConnectionProperties, error) {
+	var connectionProperties []common.ConnectionProperties
+	for _, database := range databases {
+		prefix, ok := changedNameDb[database.Name]
+		if !ok {
+			continue
+		}
+		logger.InfoContext(ctx, fmt.Sprintf("Provisioning restored database '%s' under '%s' prefix", database.Name, prefix))
+		if err := bp.restoreMetadata(ctx, database, prefix); err != nil {
+			bp.dropProvisionedUsers(ctx, connectionProperties)
+			return nil, err
+		}
+		for _, roleType := range roleTypes {
+			username, password, _, err := bp.baseProvider.CreateUserByPrefix(prefix, "", prefix, roleType, ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, fmt.Sprintf("Failed to create user with '%s' role for '%s' prefix", roleType, prefix),
+					slog.Any("error", err))
+				bp.dropProvisionedUsers(ctx, connectionProperties)
+				return nil, err
+			}
+			connectionProperties = append(connectionProperties,
+				bp.baseProvider.GetExtendedConnectionProperties("", username, password, prefix, roleType))
+		}
+	}
+	return connectionProperties, nil
+}
+
+// provisionRestoredJob provisions databases of completed restore job and stores names of created users in the job,
+// so provisioning is performed only once. Connection properties are returned only to the caller which has
+// performed provisioning, because passwords are not stored. errProvisioningInProgress is returned if databases
+// of the job are being provisioned concurrently.
+func (bp BackupProvider) provisionRestoredJob(ctx context.Context, job *Job) ([]common.ConnectionProperties, error) {
+	now := time.Now().UTC()
+	started := false
+	job, err := bp.Registry.update(ctx, RestoreJobType, job.ID, func(job *Job) bool {
+		provisioning := job.Provisioning
+		if !job.needsProvisioning() ||
+			provisioning.StartedAt != nil && now.Sub(*provisioning.StartedAt) < provisioningTimeout {
+			return false
+		}
+		provisioning.StartedAt = &now
+		started = true
+		return true
+	})
+	if err != nil || job == nil {
+		return nil, err
+	}
+	if !started {
+		if job.needsProvisioning() {
+			return nil, errProvisioningInProgress
+		}
+		return nil, nil
+	}
+
+	provisioning := job.Provisioning
+	connectionProperties, err := bp.provisionRestoredDatabases(ctx, provisioning.Databases, provisioning.Prefixes,
+		provisioning.RoleTypes)
+	if err == nil {
+		_, err = bp.Registry.update(ctx, RestoreJobType, job.ID, func(job *Job) bool {
+			for _, properties := range connectionProperties {
+				job.Users = append(job.Users, properties.Username)
+			}
+			job.Provisioning.Completed = true
+			return true
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, fmt.Sprintf("Failed to store users of '%s' restoration", job.ID), slog.Any("error", err))
+			bp.dropProvisionedUsers(ctx, connectionProperties)
+		}
+	}
+	if err != nil {
+		// provisioning can be started again by the next tracking of restoration
+		if _, releaseErr := bp.Registry.update(ctx, RestoreJobType, job.ID, func(job *Job) bool {
+			job.Provisioning.StartedAt = nil
+			return true
+		}); releaseErr != nil {
+			logger.WarnContext(ctx, fmt.Sprintf("Failed to release provisioning of '%s' restoration", job.ID), slog.Any("error", releaseErr))
+		}
+		return nil, err
+	}
+	return connectionProperties, nil
+}
+
+// dropProvisionedUsers removes users created during failed provisioning
+func (bp BackupProvider) dropProvisionedUsers(ctx context.Context, connectionProperties []common.ConnectionProperties) {
+	if len(connectionProperties) == 0 {
+		return
+	}
+	var resources []dao.DbResource
+	for _, properties := range connectionProperties {
+		resources = append(resources, dao.DbResource{Kind: common.UserKind, Name: properties.Username})
+	}
+	if failed := bp.baseProvider.DropResources(resources, ctx); len(failed) > 0 {
+		logger.ErrorContext(ctx, fmt.Sprintf("Failed to remove users of failed provisioning: %+v", failed))
+	}
+}
+
+// regeneratedPrefixes returns new prefixes of databases restored by the legacy restore with regenerated index
+// names. Indices restored one by one are named '<database prefix>_<uuid>', so all renamed indices of the same
+// database share one prefix and one set of users.
+func regeneratedPrefixes(databases []string, changedNameDb map[string]string) map[string]string {
+	prefixes := make(map[string]string)
+	for index, newName := range changedNameDb {
+		database := databaseOf(index, databases)
+		if strings.HasSuffix(newName, index) {
+			prefixes[database] = strings.TrimSuffix(newName, index) + database
+		} else if i := strings.LastIndex(newName, "_"); i > 0 {
+			prefixes[database] = newName[:i]
+		} else {
+			prefixes[database] = newName
+		}
+	}
+	return prefixes
+}
+
+// databaseOf returns the database of the given index, the index itself is returned if it does not belong
+// to any of databases
+func databaseOf(index string, databases []string) string {
+	for _, db := range databases {
+		if strings.HasPrefix(index, db) {
+			return db
+		}
+	}
+	return index
+}
+
+// resetProvisionedUsers generates new passwords for users provisioned for the job. Passwords are not stored, so
+// it is the way to return credentials to the caller which has lost the track response with them.
+func (bp BackupProvider) resetProvisionedUsers(ctx context.Context, job *Job) ([]common.ConnectionProperties, error) {
+	var connectionProperties []common.ConnectionProperties
+	for _, username := range job.Users {
+		properties, err := bp.baseProvider.ResetPassword(username, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reset password of '%s' user: %w", username, err)
+		}
+		connectionProperties = append(connectionProperties, properties)
+	}
+	return connectionProperties, nil
+}
+
+// restoreMetadata copies metadata document of the source database to the new prefix. If there is no metadata
+// for source database, it is regenerated from the namespace and microservice of the restoring database.
+func (bp BackupProvider) restoreMetadata(ctx context.Context, database Database, prefix string) error {
+	metadata, err := bp.baseProvider.GetMetadata(database.Name, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to receive metadata for '%s' database: %w", database.Name, err)
+	}
+	if metadata == nil {
+		logger.InfoContext(ctx, fmt.Sprintf("Metadata for '%s' database is not found, regenerate it", database.Name))
+		metadata = map[string]interface{}{
+			"classifier": map[string]interface{}{
+				"namespace":        database.Namespace,
+				"microserviceName": database.Microservice,
+			},
+			"microserviceName": database.Microservice,
+		}
+	}
+	if _, err = bp.baseProvider.CreateMetadata(prefix, metadata, ctx); err != nil {
+		return fmt.Errorf("failed to create metadata for '%s' prefix: %w", prefix, err)
+	}
+	return nil
+}
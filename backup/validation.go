@@ -0,0 +1,50 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+)
+
+// validateDatabaseNames checks list of databases to restore, names of existing databases may be names of indices
+// created by v1 API, so they are checked against limits of index names rather than prefixes
+func validateDatabaseNames(dbs []string) error {
+	var validator common.Validator
+	if len(dbs) == 0 {
+		validator.Add("", "list of databases must not be empty")
+	}
+	for i, db := range dbs {
+		validator.Check(fmt.Sprintf("[%d]", i), common.ValidateIndexName(db, common.MaxIndexNameLength))
+	}
+	return validator.Err()
+}
+
+// validateDatabases checks databases of restoration requests, new prefix is validated only if it is specified
+func validateDatabases(dbs []Database) error {
+	var validator common.Validator
+	if len(dbs) == 0 {
+		validator.Add("databases", "must not be empty")
+	}
+	for i, db := range dbs {
+		field := fmt.Sprintf("databases[%d]", i)
+		validator.Check(field+".name", common.ValidateIndexName(db.Name, common.MaxIndexNameLength))
+		if db.Prefix != "" {
+			validator.Check(field+".prefix", common.ValidatePrefix(db.Prefix))
+		}
+	}
+	return validator.Err()
+}
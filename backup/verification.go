@@ -0,0 +1,424 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/gorilla/mux"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	VerifyJobType = "VERIFY"
+	VerifyAction  = "VERIFY"
+
+	// SnapshotStatusVerificationMode is used when there is no information about source indices at backup time,
+	// so only snapshot state can be checked.
+	SnapshotStatusVerificationMode = "SNAPSHOT_STATUS"
+	// RestoreVerificationMode restores snapshot into temporary indices and compares them with source indices.
+	RestoreVerificationMode = "RESTORE"
+
+	verificationIndexPrefix          = "dbaas_verify"
+	verificationBackupPollInterval   = 10 * time.Second
+	verificationBackupWaitingTimeout = 6 * time.Hour
+)
+
+// IndexState describes source index at backup time. Mapping is stored as a hash to keep job documents small.
+// Repository is the snapshot repository which contains the index.
+type IndexState struct {
+	DocsCount   int64  `json:"docsCount"`
+	MappingHash string `json:"mappingHash"`
+	Repository  string `json:"repository,omitempty"`
+}
+
+type VerificationResult struct {
+	Mode       string              `json:"mode"`
+	Passed     bool                `json:"passed"`
+	Snapshot   string              `json:"snapshotState,omitempty"`
+	Indices    []IndexVerification `json:"indices,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty"`
+}
+
+type IndexVerification struct {
+	Index           string `json:"index"`
+	SourceDocs      int64  `json:"sourceDocs"`
+	RestoredDocs    int64  `json:"restoredDocs"`
+	MappingsMatched bool   `json:"mappingsMatched"`
+	Passed          bool   `json:"passed"`
+}
+
+type indicesStats struct {
+	Indices map[string]struct {
+		Primaries struct {
+			Docs struct {
+				Count int64 `json:"count"`
+			} `json:"docs"`
+		} `json:"primaries"`
+	} `json:"indices"`
+}
+
+// EnableVerificationAfterBackup makes the adapter verify each collected backup when it is completed.
+func (bp *BackupProvider) EnableVerificationAfterBackup(repo string) {
+	bp.verifyAfterBackup = true
+	bp.verificationRepo = repo
+}
+
+func (bp BackupProvider) VerifyBackupHandler(repo string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		backupID := mux.Vars(r)["backupID"]
+		logger.InfoContext(ctx, fmt.Sprintf("Request to verify '%s' backup is received", backupID))
+		err := bp.StartVerification(ctx, backupID, repo)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to start backup verification", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		responseBody, err := json.Marshal(verificationTrack(backupID, "PROCEEDING"))
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, http.StatusAccepted)
+	}
+}
+
+func (bp BackupProvider) GetVerificationHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		backupID := mux.Vars(r)["backupID"]
+		logger.InfoContext(ctx, fmt.Sprintf("Request to get verification of '%s' backup is received", backupID))
+		job, err := bp.Registry.Get(ctx, VerifyJobType, backupID)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to receive backup verification", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		if job == nil {
+			common.WriteError(ctx, w, common.NewError(common.ErrNotFound, fmt.Sprintf("verification of '%s' backup is not found", backupID)))
+			return
+		}
+		responseBody, err := json.Marshal(job)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, http.StatusOK)
+	}
+}
+
+// StartVerification registers verification job for the backup and runs it in background.
+func (bp BackupProvider) StartVerification(ctx context.Context, backupID string, repo string) error {
+	job := newJob(VerifyJobType, backupID, map[string]string{"repository": repo}, nil)
+	if err := bp.Registry.Register(ctx, job); err != nil {
+		return err
+	}
+	verificationCtx := context.WithValue(context.Background(), common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
+	// verification removes temporary indices at the end, so it should not be interrupted by shutdown
+	done := bp.baseProvider.Drainer.Track("backup-verification")
+	go func() {
+		defer done()
+		bp.runVerification(verificationCtx, backupID, repo)
+	}()
+	return nil
+}
+
+// verifyWhenCompleted waits for the backup to be finished and verifies it if the backup is successful.
+func (bp BackupProvider) verifyWhenCompleted(ctx context.Context, backupID string) {
+	var status string
+	err := wait.PollUntilContextTimeout(ctx, verificationBackupPollInterval, verificationBackupWaitingTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			track, err := bp.trackBackup(ctx, backupID, bp.verificationRepo)
+			if err != nil {
+				return false, nil
+			}
+			status = track.Status
+			return status != "PROCEEDING", nil
+		})
+	if err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("'%s' backup is not completed in time, skip verification", backupID),
+			slog.Any("error", err))
+		return
+	}
+	if status != "SUCCESS" {
+		logger.WarnContext(ctx, fmt.Sprintf("'%s' backup is finished with '%s' status, skip verification", backupID, status))
+		return
+	}
+	if err = bp.StartVerification(ctx, backupID, bp.verificationRepo); err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("Failed to start verification of '%s' backup", backupID), slog.Any("error", err))
+	}
+}
+
+func (bp BackupProvider) runVerification(ctx context.Context, backupID string, repo string) {
+	result := bp.verify(ctx, backupID, repo)
+	finishedAt := time.Now().UTC()
+	result.FinishedAt = &finishedAt
+	status := "SUCCESS"
+	if !result.Passed {
+		status = "FAIL"
+		logger.ErrorContext(ctx, fmt.Sprintf("Verification of '%s' backup is failed: %+v", backupID, result))
+	} else {
+		logger.InfoContext(ctx, fmt.Sprintf("Verification of '%s' backup is passed in %s mode", backupID, result.Mode))
+	}
+	_, err := bp.Registry.update(ctx, VerifyJobType, backupID, func(job *Job) bool {
+		job.applyStatus(status, result.Error)
+		job.Verification = &result
+		return true
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("Failed to record verification result of '%s' backup", backupID), slog.Any("error", err))
+	}
+}
+
+// verify checks state of snapshots of the backup and, if source indices state is known, restores recorded indices
+// from their repositories into temporary indices to compare their documents count and mappings with source indices
+// at backup time. Repo is the repository used by Curator.
+func (bp BackupProvider) verify(ctx context.Context, backupID string, repo string) VerificationResult {
+	result := VerificationResult{Mode: SnapshotStatusVerificationMode}
+	backupJob, err := bp.Registry.Get(ctx, BackupJobType, backupID)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, snapshotRepo := range backupRepositories(backupJob, repo) {
+		snapshot, err := bp.getSnapshotStatus(backupID, snapshotRepo, ctx)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Snapshot = snapshot.State
+		if snapshot.State != "SUCCESS" {
+			result.Error = fmt.Sprintf("state of snapshot in '%s' repository is '%s'", snapshotRepo, snapshot.State)
+			return result
+		}
+	}
+	if backupJob == nil || len(backupJob.Indices) == 0 {
+		logger.InfoContext(ctx, fmt.Sprintf("There is no information about source indices of '%s' backup, only snapshot state is verified", backupID))
+		result.Passed = true
+		return result
+	}
+
+	result.Mode = RestoreVerificationMode
+	prefix := fmt.Sprintf("%s_%s_", verificationIndexPrefix, common.GenerateUUID()[:8])
+	defer bp.deleteVerificationIndices(ctx, prefix)
+	repositoryIndices := make(map[string][]string)
+	for index, source := range backupJob.Indices {
+		indexRepo := source.Repository
+		if indexRepo == "" {
+			indexRepo = repo
+		}
+		repositoryIndices[indexRepo] = append(repositoryIndices[indexRepo], index)
+	}
+	restored := make(map[string]IndexState)
+	for indexRepo, indices := range repositoryIndices {
+		states, err := bp.restoreForVerification(ctx, backupID, indexRepo, indices, prefix)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		for index, state := range states {
+			restored[index] = state
+		}
+	}
+	result.Passed = true
+	for index, source := range backupJob.Indices {
+		indexVerification := IndexVerification{
+			Index:      index,
+			SourceDocs: source.DocsCount,
+		}
+		if state, ok := restored[prefix+index]; ok {
+			indexVerification.RestoredDocs = state.DocsCount
+			indexVerification.MappingsMatched = state.MappingHash == source.MappingHash
+			indexVerification.Passed = indexVerification.MappingsMatched && state.DocsCount == source.DocsCount
+		}
+		result.Passed = result.Passed && indexVerification.Passed
+		result.Indices = append(result.Indices, indexVerification)
+	}
+	return result
+}
+
+// restoreForVerification restores the given indices of the snapshot under the verification prefix
+func (bp BackupProvider) restoreForVerification(ctx context.Context, backupID string, repo string,
+	indices []string, prefix string) (map[string]IndexState, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"indices":              strings.Join(indices, ","),
+		"include_global_state": false,
+		"include_aliases":      false,
+		"rename_pattern":       "(.+)",
+		"rename_replacement":   prefix + "$1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	waitForCompletion := true
+	restoreRequest := opensearchapi.SnapshotRestoreRequest{
+		Repository:        repo,
+		Snapshot:          backupID,
+		Body:              strings.NewReader(string(body)),
+		WaitForCompletion: &waitForCompletion,
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("Restoring %d indices of '%s' backup from '%s' repository with '%s' prefix for verification",
+		len(indices), backupID, repo, prefix))
+	response, err := restoreRequest.Do(ctx, bp.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore '%s' snapshot for verification: %w", backupID, err)
+	}
+	defer response.Body.Close()
+	if response.IsError() {
+		return nil, fmt.Errorf("failed to restore '%s' snapshot for verification: %s", backupID, response.String())
+	}
+	restoredIndices := make([]string, len(indices))
+	for i, index := range indices {
+		restoredIndices[i] = prefix + index
+	}
+	return bp.getIndicesState(ctx, restoredIndices)
+}
+
+func (bp BackupProvider) deleteVerificationIndices(ctx context.Context, prefix string) {
+	deleteRequest := opensearchapi.IndicesDeleteRequest{
+		Index: []string{prefix + "*"},
+	}
+	response, err := deleteRequest.Do(ctx, bp.client)
+	if err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("Failed to delete verification indices with '%s' prefix", prefix), slog.Any("error", err))
+		return
+	}
+	defer response.Body.Close()
+	logger.DebugContext(ctx, fmt.Sprintf("Verification indices with '%s' prefix are removed", prefix))
+}
+
+// recordIndicesState stores state of indices of the completed backup in the backup job, so the backup can be
+// verified later. Backup is verified by snapshot state only if the state cannot be captured.
+func (bp BackupProvider) recordIndicesState(ctx context.Context, job *Job, repo string) {
+	indices, err := bp.captureIndicesState(ctx, job.ID, backupRepositories(job, repo))
+	if err == nil {
+		_, err = bp.Registry.update(ctx, BackupJobType, job.ID, func(job *Job) bool {
+			if job.Indices != nil {
+				return false
+			}
+			job.Indices = indices
+			return true
+		})
+	}
+	if err != nil {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to capture state of indices of '%s' backup, it can be verified by snapshot state only", job.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// captureIndicesState receives documents count and mappings of indices included into snapshots of the backup
+// in the given repositories. It is called right after snapshots are completed, so changes made to indices
+// during snapshot creation do not fail verification.
+func (bp BackupProvider) captureIndicesState(ctx context.Context, backupID string, repos []string) (map[string]IndexState, error) {
+	indexRepositories := make(map[string]string)
+	for _, repo := range repos {
+		snapshot, err := bp.getSnapshotStatus(backupID, repo, ctx)
+		if err != nil {
+			return nil, err
+		}
+		for index := range snapshot.Indices {
+			indexRepositories[index] = repo
+		}
+	}
+	if len(indexRepositories) == 0 {
+		return nil, errors.New("there are no indices in snapshots of the backup")
+	}
+	indices := make([]string, 0, len(indexRepositories))
+	for index := range indexRepositories {
+		indices = append(indices, index)
+	}
+	result, err := bp.getIndicesState(ctx, indices)
+	if err != nil {
+		return nil, err
+	}
+	for index, state := range result {
+		state.Repository = indexRepositories[index]
+		result[index] = state
+	}
+	return result, nil
+}
+
+func (bp BackupProvider) getIndicesState(ctx context.Context, indices []string) (map[string]IndexState, error) {
+	statsRequest := opensearchapi.IndicesStatsRequest{
+		Index:  indices,
+		Metric: []string{"docs"},
+	}
+	var stats indicesStats
+	if err := common.DoRequest(statsRequest, bp.client, &stats, ctx); err != nil {
+		return nil, fmt.Errorf("failed to receive indices stats: %w", err)
+	}
+	mappingRequest := opensearchapi.IndicesGetMappingRequest{
+		Index: indices,
+	}
+	var mappings map[string]json.RawMessage
+	if err := common.DoRequest(mappingRequest, bp.client, &mappings, ctx); err != nil {
+		return nil, fmt.Errorf("failed to receive indices mappings: %w", err)
+	}
+	result := make(map[string]IndexState, len(stats.Indices))
+	for index, indexStats := range stats.Indices {
+		mappingHash, err := hashMapping(mappings[index])
+		if err != nil {
+			return nil, fmt.Errorf("failed to process mapping of '%s' index: %w", index, err)
+		}
+		result[index] = IndexState{
+			DocsCount:   indexStats.Primaries.Docs.Count,
+			MappingHash: mappingHash,
+		}
+	}
+	return result, nil
+}
+
+// hashMapping calculates hash of mapping in canonical form, so mappings with different order of keys are equal
+func hashMapping(mapping json.RawMessage) (string, error) {
+	if len(mapping) == 0 {
+		return "", nil
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(mapping, &parsed); err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(canonical)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func verificationTrack(backupId string, status string) ActionTrack {
+	return ActionTrack{
+		Action: VerifyAction,
+		Details: TrackDetails{
+			LocalId: backupId,
+		},
+		Status:  status,
+		TrackID: backupId,
+	}
+}
@@ -17,7 +17,6 @@ package basic
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -34,7 +33,7 @@ import (
 )
 
 const (
-	DbaasMetadata        = "dbaas_opensearch_metadata"
+	DbaasMetadata        = common.SystemIndexPrefix + "metadata"
 	DeletedStatus        = "DELETED"
 	DeletionFailedStatus = "DELETE_FAILED"
 )
@@ -45,8 +44,13 @@ type BaseProvider struct {
 	opensearch        *cluster.Opensearch
 	mutex             *sync.Mutex
 	passwordGenerator PasswordGenerator
+	// ApiVersion is the fixed API version of provider which serves the versioned routes, negotiatedVersion is used
+	// if it is not set
 	ApiVersion        string
-	recoveryState     string
+	negotiatedVersion *common.NegotiatedApiVersion
+	recovery          *usersRecovery
+	// Drainer tracks background operations which should be finished before adapter shutdown
+	Drainer *common.Drainer
 }
 
 type DbCreateRequest struct {
@@ -91,33 +95,58 @@ func NewBaseProvider(opensearch *cluster.Opensearch) *BaseProvider {
 		opensearch:        opensearch,
 		mutex:             &sync.Mutex{},
 		passwordGenerator: NewPasswordGenerator(),
-		recoveryState:     RecoveryIdleState,
+		negotiatedVersion: common.NewNegotiatedApiVersion(common.GetEnv("API_VERSION", common.ApiV2)),
+		recovery:          newUsersRecovery(),
+		Drainer:           common.NewDrainer(),
 	}
 }
 
+// NegotiatedApiVersion returns API version negotiated with DBaaS aggregator which is shared by all providers
+func (bp *BaseProvider) NegotiatedApiVersion() *common.NegotiatedApiVersion {
+	return bp.negotiatedVersion
+}
+
+// Version returns API version of provider, it is the negotiated one for provider without fixed API version
+func (bp *BaseProvider) Version() string {
+	if bp.ApiVersion != "" {
+		return bp.ApiVersion
+	}
+	return bp.negotiatedVersion.Get()
+}
+
+// WithApiVersion returns provider for the given API version, it shares OpenSearch client and state with bp,
+// so adapter is able to serve several API versions at the same time.
+func (bp *BaseProvider) WithApiVersion(apiVersion string) *BaseProvider {
+	provider := *bp
+	provider.ApiVersion = apiVersion
+	return &provider
+}
+
 func (bp BaseProvider) CreateDatabaseHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := common.PrepareContext(r)
 		logger.InfoContext(ctx, "Request to create new database is received")
-		decoder := json.NewDecoder(r.Body)
 		var dbCreateRequest DbCreateRequest
-		err := decoder.Decode(&dbCreateRequest)
+		err := common.DecodeJson(r.Body, &dbCreateRequest)
+		if err == nil {
+			err = bp.validateDbCreateRequest(dbCreateRequest)
+		}
 		if err != nil {
-			logger.ErrorContext(ctx, "Failed to decode request in create database handler", slog.String("error", err.Error()))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			logger.ErrorContext(ctx, "Invalid request in create database handler", slog.String("error", err.Error()))
+			common.WriteError(ctx, w, err)
 			return
 		}
 		defer r.Body.Close()
 		response, err := bp.createDatabase(dbCreateRequest, ctx)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to create database", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed during response serialization in create database handler", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -132,13 +161,13 @@ func (bp BaseProvider) ListDatabasesHandler() func(w http.ResponseWriter, r *htt
 		databases, err := bp.listDatabases()
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to get indices list", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		listIndicesBytes, err := json.Marshal(databases)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to serialize indices list", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		common.ProcessResponseBody(ctx, w, listIndicesBytes, http.StatusOK)
@@ -149,53 +178,51 @@ func (bp BaseProvider) BulkDropResourceHandler() func(w http.ResponseWriter, r *
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := common.PrepareContext(r)
 		logger.InfoContext(ctx, "Request to delete OpenSearch resources is received")
-		decoder := json.NewDecoder(r.Body)
 		var resources []dao.DbResource
-		err := decoder.Decode(&resources)
+		err := common.DecodeJson(r.Body, &resources)
+		if err == nil {
+			err = validateResources(resources)
+		}
 		if err != nil {
-			logger.ErrorContext(ctx, "Failed to decode request in delete resources method", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			logger.ErrorContext(ctx, "Invalid request in delete resources method", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
 			return
 		}
 		defer r.Body.Close()
 
 		deletedResources := bp.deleteResources(resources, ctx)
 		failedResources := getResourcesWithFailedStatus(deletedResources)
-		var resourcesToReturn []dao.DbResource
+		resourcesToReturn, status := deletedResources, http.StatusOK
 		if len(failedResources) > 0 {
-			resourcesToReturn = failedResources
-			w.WriteHeader(http.StatusInternalServerError)
-		} else {
-			resourcesToReturn = deletedResources
-			w.WriteHeader(http.StatusOK)
+			resourcesToReturn, status = failedResources, http.StatusInternalServerError
 		}
 		bytesResult, err := json.Marshal(resourcesToReturn)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to serialize resources list", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
-		common.ProcessResponseBody(ctx, w, bytesResult, 0)
+		common.ProcessResponseBody(ctx, w, bytesResult, status)
 	}
 }
 
 func (bp BaseProvider) UpdateMetadataHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := common.PrepareContext(r)
 		indexName := mux.Vars(r)["dbName"]
+		ctx := common.WithDbPrefix(common.PrepareContext(r), indexName)
 		logger.InfoContext(ctx, fmt.Sprintf("Request to update metadata for '%s' index is received", indexName))
 		var metadata map[string]interface{}
 		decoder := json.NewDecoder(r.Body)
 		err := decoder.Decode(&metadata)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to decode request in update metadata method", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, common.DecodeError(err))
 			return
 		}
 		_, err = bp.updateMetadata(indexName, metadata, ctx)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to update metadata for index", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -214,7 +241,7 @@ func (bp BaseProvider) SupportsHandler() func(w http.ResponseWriter, r *http.Req
 		responseBody, err := json.Marshal(supports)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to serialize information about supported features", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -225,56 +252,8 @@ func (bp BaseProvider) SupportsHandler() func(w http.ResponseWriter, r *http.Req
 func (bp BaseProvider) EnsureAggregationIndex(ctx context.Context) error {
 	bp.mutex.Lock()
 	defer bp.mutex.Unlock()
-	existsRequest := opensearchapi.IndicesExistsRequest{
-		Index: []string{DbaasMetadata},
-	}
-
 	childCtx := context.WithValue(ctx, common.RequestIdKey, common.GenerateUUID())
-	exist, err := existsRequest.Do(childCtx, bp.opensearch.Client)
-	if err != nil {
-		logger.ErrorContext(childCtx, fmt.Sprintf("Failed to check if '%s' index exists", DbaasMetadata), slog.String("error", err.Error()))
-		return fmt.Errorf("failed to check if '%s' index exists %w", DbaasMetadata, err)
-	}
-
-	logger.DebugContext(childCtx, fmt.Sprintf("Check if index exists: %v", exist))
-	if exist.StatusCode == 200 {
-		logger.DebugContext(childCtx, fmt.Sprintf("'%s' index already exists", DbaasMetadata))
-		return nil
-	}
-	createRequest := opensearchapi.IndicesCreateRequest{
-		Index: DbaasMetadata,
-	}
-	createResponse, err := createRequest.Do(childCtx, bp.opensearch.Client)
-	if err != nil {
-		exist, err = existsRequest.Do(childCtx, bp.opensearch.Client)
-		if err != nil {
-			logger.ErrorContext(childCtx, fmt.Sprintf("failed to check if '%s' index exists", DbaasMetadata), slog.Any("error", err))
-			return fmt.Errorf("failed to check if '%s' index exists %w", DbaasMetadata, err)
-		}
-		logger.DebugContext(childCtx, fmt.Sprintf("Check if index exists: %v", exist))
-		if exist.StatusCode == 200 {
-			logger.DebugContext(childCtx, fmt.Sprintf("'%s' index already exists", DbaasMetadata))
-			return nil
-		}
-		logger.ErrorContext(childCtx, fmt.Sprintf("failed to create '%s' index", DbaasMetadata), slog.Any("error", err))
-		return fmt.Errorf("failed to create '%s' index %w", DbaasMetadata, err)
-	}
-	defer createResponse.Body.Close()
-
-	if createResponse.StatusCode != http.StatusCreated && createResponse.StatusCode != http.StatusOK {
-		var body []byte
-		body, err = io.ReadAll(createResponse.Body)
-		if err != nil {
-			logger.ErrorContext(childCtx, "failed to read from http response body", slog.String("error", err.Error()))
-			return err
-		}
-		logger.ErrorContext(childCtx, fmt.Sprintf("%s index cannot be created because of error: [%d] %s", DbaasMetadata,
-			createResponse.StatusCode, string(body)))
-		return fmt.Errorf("%s index cannot be created because of error: [%d]", DbaasMetadata,
-			createResponse.StatusCode)
-	}
-	logger.DebugContext(childCtx, fmt.Sprintf("'%s' index is created", DbaasMetadata))
-	return nil
+	return common.EnsureIndex(childCtx, bp.opensearch.Client, DbaasMetadata)
 }
 
 func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx context.Context) (interface{}, error) {
@@ -324,8 +303,8 @@ func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx con
 		}
 		resources = append(resources, dao.DbResource{Kind: common.ResourcePrefixKind, Name: prefix})
 	} else {
-		if bp.ApiVersion == common.ApiV2 {
-			return nil, fmt.Errorf("'resourcePrefix' must be set to 'true' for v2 version of OpenSearch DBaaS adapter")
+		if bp.Version() == common.ApiV2 {
+			return nil, common.NewError(common.ErrValidation, "'resourcePrefix' must be set to 'true' for v2 version of OpenSearch DBaaS adapter")
 		}
 		prefix = requestOnCreateDb.NamePrefix
 		if prefix == "" {
@@ -333,6 +312,7 @@ func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx con
 		}
 	}
 
+	ctx = common.WithDbPrefix(ctx, prefix)
 	if ok, err := common.CheckPrefixUniqueness(prefix, ctx, bp.opensearch.Client); !ok {
 		if err != nil {
 			return nil, err
@@ -342,7 +322,7 @@ func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx con
 	resourcesToCreate := requestOnCreateDb.Settings.CreateOnly
 	if len(resourcesToCreate) == 0 {
 		resourcesToCreate = []string{common.UserKind, common.IndexKind}
-		if bp.ApiVersion == common.ApiV2 {
+		if bp.Version() == common.ApiV2 {
 			resourcesToCreate = []string{common.UserKind}
 		}
 	}
@@ -372,7 +352,7 @@ func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx con
 				dbName = buildIndexName(requestOnCreateDb.DbName, prefix)
 			}
 			var securityResources []dao.DbResource
-			if bp.ApiVersion == common.ApiV1 {
+			if bp.Version() == common.ApiV1 {
 				username, password, securityResources, err =
 					bp.createOrUpdateUser(username, requestOnCreateDb.Password, dbName, AdminRoleType, ctx)
 				if err != nil {
@@ -382,7 +362,7 @@ func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx con
 			}
 			// Possibly need to move additionalRoles and response logic into separate methods for v2
 			// and check apiVersion once to improve readability
-			if bp.ApiVersion == common.ApiV2 {
+			if bp.Version() == common.ApiV2 {
 				for _, roleType := range bp.GetSupportedRoleTypes() {
 					additionalUsername := prefix
 					additionalPassword := ""
@@ -417,14 +397,14 @@ func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx con
 	resources = append(resources, dao.DbResource{Kind: common.MetadataKind, Name: metadataID})
 
 	var result interface{}
-	if bp.ApiVersion == common.ApiV1 {
+	if bp.Version() == common.ApiV1 {
 		connection := bp.getConnectionProperties(indexName, username, password)
 		response := DbCreateResponse{Name: indexName, ConnectionProperties: connection, Resources: resources}
 		if requestOnCreateDb.Settings.ResourcePrefix {
 			response.ConnectionProperties.ResourcePrefix = prefix
 		}
 		result = response
-	} else if bp.ApiVersion == common.ApiV2 {
+	} else if bp.Version() == common.ApiV2 {
 		response := DbCreateResponseMultiUser{Name: indexName, ConnectionProperties: connections, Resources: resources}
 		result = response
 	}
@@ -434,7 +414,7 @@ func (bp BaseProvider) createDatabase(requestOnCreateDb DbCreateRequest, ctx con
 
 func checkForbiddenSymbolPrefix(namePrefix string) error {
 	if strings.HasPrefix(namePrefix, ".") || strings.Contains(namePrefix, "*") {
-		return errors.New("prefix contains forbidden symbols")
+		return common.NewError(common.ErrValidation, "prefix contains forbidden symbols")
 	}
 	return nil
 }
@@ -447,7 +427,7 @@ func (bp BaseProvider) createIndex(requestOnCreateDb DbCreateRequest, prefix str
 		Body:  body,
 	}
 	logger.InfoContext(ctx, fmt.Sprintf("Creating index with name '%s'", indexName))
-	indexResponse, err := indexRequest.Do(context.Background(), bp.opensearch.Client)
+	indexResponse, err := indexRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		logger.ErrorContext(ctx, fmt.Sprintf("Error occurred during creating '%s' index", indexName), slog.Any("error", err))
 		return indexName, err
@@ -511,7 +491,7 @@ func (bp BaseProvider) deleteDatabase(name string, ctx context.Context) error {
 	indicesDeleteRequest := opensearchapi.IndicesDeleteRequest{
 		Index: []string{name},
 	}
-	response, err := indicesDeleteRequest.Do(context.Background(), bp.opensearch.Client)
+	response, err := indicesDeleteRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return err
 	}
@@ -554,7 +534,7 @@ func (bp BaseProvider) CreateMetadata(identifier string, metadata map[string]int
 		DocumentID: identifier,
 		Body:       body,
 	}
-	response, err := indexRequest.Do(context.Background(), bp.opensearch.Client)
+	response, err := indexRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return "", fmt.Errorf("error occurred during insert metadata for '%s' ID to '%s' index : %+v", identifier, DbaasMetadata, err)
 	}
@@ -579,7 +559,7 @@ func (bp BaseProvider) updateMetadata(indexName string, metadata map[string]inte
 			DocumentID: indexName,
 			Body:       bodyReader,
 		}
-		response, err := updateRequest.Do(context.Background(), bp.opensearch.Client)
+		response, err := updateRequest.Do(ctx, bp.opensearch.Client)
 		if err != nil {
 			logger.ErrorContext(ctx, "Error occurred during update metadata", slog.Any("error", err))
 			return "", err
@@ -612,7 +592,7 @@ func (bp BaseProvider) deleteMetadata(indexName string, ctx context.Context) err
 		Index:      DbaasMetadata,
 		DocumentID: indexName,
 	}
-	response, err := deleteMetadataRequest.Do(context.Background(), bp.opensearch.Client)
+	response, err := deleteMetadataRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return err
 	}
@@ -675,7 +655,7 @@ func (bp BaseProvider) deleteTemplate(template string, ctx context.Context) erro
 	deleteTemplateRequest := opensearchapi.IndicesDeleteTemplateRequest{
 		Name: template,
 	}
-	response, err := deleteTemplateRequest.Do(context.Background(), bp.opensearch.Client)
+	response, err := deleteTemplateRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return err
 	}
@@ -688,7 +668,7 @@ func (bp BaseProvider) deleteIndexTemplate(template string, ctx context.Context)
 	deleteIndexTemplateRequest := opensearchapi.IndicesDeleteIndexTemplateRequest{
 		Name: template,
 	}
-	response, err := deleteIndexTemplateRequest.Do(context.Background(), bp.opensearch.Client)
+	response, err := deleteIndexTemplateRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return err
 	}
@@ -729,7 +709,7 @@ func (bp BaseProvider) deleteAlias(alias string, ctx context.Context) error {
 		Index: []string{alias},
 		Name:  []string{alias},
 	}
-	response, err := aliasDeleteTemplate.Do(context.Background(), bp.opensearch.Client)
+	response, err := aliasDeleteTemplate.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return err
 	}
@@ -751,7 +731,7 @@ func (bp BaseProvider) getConnectionProperties(dbName string, username string, p
 }
 
 func (bp BaseProvider) IsOpenSearchTlsEnabled() bool {
-	return bp.ApiVersion == common.ApiV2 && bp.opensearch.Protocol == common.Https
+	return bp.Version() == common.ApiV2 && bp.opensearch != nil && bp.opensearch.Protocol == common.Https
 }
 
 func (bp BaseProvider) GetExtendedConnectionProperties(dbName string, username string, password string, prefix string,
@@ -792,12 +772,18 @@ func (bp BaseProvider) deleteResources(resources []dao.DbResource, ctx context.C
 	return deletedResources
 }
 
+// DropResources deletes the given resources and returns resources which are failed to be deleted.
+func (bp BaseProvider) DropResources(resources []dao.DbResource, ctx context.Context) []dao.DbResource {
+	return getResourcesWithFailedStatus(bp.deleteResources(resources, ctx))
+}
+
 func (bp BaseProvider) processResourcePrefixKind(resources []dao.DbResource, ctx context.Context) []dao.DbResource {
 	var additionalResources []dao.DbResource
 	for _, resource := range resources {
 		if resource.Kind == common.ResourcePrefixKind {
+			ctx := common.WithDbPrefix(ctx, resource.Name)
 			namePattern := fmt.Sprintf("%s*", resource.Name)
-			if bp.ApiVersion == common.ApiV1 {
+			if bp.Version() == common.ApiV1 {
 				additionalResources = append(additionalResources, []dao.DbResource{
 					{Kind: common.UserKind, Name: resource.Name},
 					{Kind: common.IndexKind, Name: namePattern},
@@ -806,7 +792,7 @@ func (bp BaseProvider) processResourcePrefixKind(resources []dao.DbResource, ctx
 					{Kind: common.IndexTemplateKind, Name: namePattern},
 					{Kind: common.AliasKind, Name: namePattern},
 				}...)
-			} else if bp.ApiVersion == common.ApiV2 {
+			} else if bp.Version() == common.ApiV2 {
 				additionalResources = append(additionalResources, []dao.DbResource{
 					{Kind: common.IndexKind, Name: namePattern},
 					{Kind: common.MetadataKind, Name: resource.Name},
@@ -875,7 +861,7 @@ func (bp BaseProvider) deleteResource(resource dao.DbResource, ctx context.Conte
 			return getResourceDeletionFailedStatus(resource, err)
 		}
 	} else if resource.Kind == common.UserKind {
-		user, err := bp.GetUser(resource.Name)
+		user, err := bp.GetUser(resource.Name, ctx)
 		if err != nil {
 			logger.ErrorContext(ctx, fmt.Sprintf("Failed to receive '%s' user information", resource.Name), slog.Any("error", err))
 			return getResourceDeletionFailedStatus(resource, err)
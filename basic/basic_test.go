@@ -531,3 +531,15 @@ func TestCreateUserWithNamespaceInMetadata(t *testing.T) {
 	}
 	assert.ElementsMatch(t, response.Resources, expectedResources)
 }
+
+func TestWithApiVersionSharesState(t *testing.T) {
+	provider := NewBaseProvider(baseProvider.opensearch)
+	provider.ApiVersion = common.ApiV1
+	v2Provider := provider.WithApiVersion(common.ApiV2)
+	assert.Equal(t, common.ApiV1, provider.ApiVersion)
+	assert.Equal(t, common.ApiV2, v2Provider.ApiVersion)
+
+	assert.True(t, v2Provider.recovery.start())
+	assert.False(t, provider.recovery.start())
+	assert.Equal(t, RecoveryRunningState, provider.recovery.getState())
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/Netcracker/dbaas-opensearch-adapter/api"
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -71,6 +72,14 @@ func (bp BaseProvider) GetSupportedRoleTypes() []string {
 	return []string{ReadOnlyRoleType, DmlRoleType, AdminRoleType, IsmRoleType}
 }
 
+// GetProvisionedRoleTypes returns role types of users created for database, API v1 provides only admin user
+func (bp BaseProvider) GetProvisionedRoleTypes() []string {
+	if bp.Version() == common.ApiV1 {
+		return []string{AdminRoleType}
+	}
+	return bp.GetSupportedRoleTypes()
+}
+
 func (bp BaseProvider) DefineRoleType(roleName string) string {
 	for _, roleType := range bp.GetSupportedRoleTypes() {
 		if strings.Contains(roleName, roleType) {
@@ -85,6 +94,36 @@ func (bp BaseProvider) GetBackendRoles(roleType string) []string {
 }
 
 func (bp BaseProvider) CreateRoleWithISMPermissions(enhancedSecurityPluginEnabled bool) error {
+	return bp.createRole(ismRole(enhancedSecurityPluginEnabled), IsmRoleType)
+}
+
+func (bp BaseProvider) CreateRoleWithAdminPermissions() error {
+	return bp.createRole(adminRole(), AdminRoleType)
+}
+
+func (bp BaseProvider) CreateRoleWithDMLPermissions() error {
+	return bp.createRole(dmlRole(), DmlRoleType)
+}
+
+func (bp BaseProvider) CreateRoleWithReadOnlyPermissions() error {
+	return bp.createRole(readOnlyRole(), ReadOnlyRoleType)
+}
+
+// roleDefinition returns the role of the given type which is created by adapter
+func roleDefinition(roleType string, enhancedSecurityPluginEnabled bool) Role {
+	switch roleType {
+	case IsmRoleType:
+		return ismRole(enhancedSecurityPluginEnabled)
+	case DmlRoleType:
+		return dmlRole()
+	case ReadOnlyRoleType:
+		return readOnlyRole()
+	default:
+		return adminRole()
+	}
+}
+
+func ismRole(enhancedSecurityPluginEnabled bool) Role {
 	clusterPermissions := []string{
 		ClusterAdminIsmPermissions,
 	}
@@ -97,10 +136,10 @@ func (bp BaseProvider) CreateRoleWithISMPermissions(enhancedSecurityPluginEnable
 			IndicesRolloverPermission,
 			IndicesDeletePermission)
 	}
-	return bp.createRole(clusterPermissions, []string{}, indexGlobalPermissions, IsmRoleType)
+	return newRole(clusterPermissions, []string{}, indexGlobalPermissions)
 }
 
-func (bp BaseProvider) CreateRoleWithAdminPermissions() error {
+func adminRole() Role {
 	indexPermissions := []string{
 		IndicesAllActionPermission,
 		strings.ToUpper(IndicesAllActionPermission),
@@ -123,10 +162,10 @@ func (bp BaseProvider) CreateRoleWithAdminPermissions() error {
 		ClusterManageAliasesPermissions,
 		"indices:admin/resize",
 	}
-	return bp.createRole(clusterPermissions, indexPermissions, indexGlobalPermissions, AdminRoleType)
+	return newRole(clusterPermissions, indexPermissions, indexGlobalPermissions)
 }
 
-func (bp BaseProvider) CreateRoleWithDMLPermissions() error {
+func dmlRole() Role {
 	indexPermissions := []string{
 		IndicesDMLActionPermission,
 		strings.ToUpper(IndicesDMLActionPermission),
@@ -145,10 +184,10 @@ func (bp BaseProvider) CreateRoleWithDMLPermissions() error {
 		ClusterMonitorStatePermission,
 		ClusterMonitorMainPermission,
 	}
-	return bp.createRole(clusterPermissions, indexPermissions, []string{}, DmlRoleType)
+	return newRole(clusterPermissions, indexPermissions, []string{})
 }
 
-func (bp BaseProvider) CreateRoleWithReadOnlyPermissions() error {
+func readOnlyRole() Role {
 	indexPermissions := []string{
 		IndicesROActionPermission,
 		IndicesExistPermission,
@@ -164,13 +203,10 @@ func (bp BaseProvider) CreateRoleWithReadOnlyPermissions() error {
 		ClusterMonitorStatePermission,
 		ClusterMonitorMainPermission,
 	}
-	return bp.createRole(clusterPermissions, indexPermissions, []string{}, ReadOnlyRoleType)
+	return newRole(clusterPermissions, indexPermissions, []string{})
 }
 
-func (bp BaseProvider) createRole(clusterPermissions []string, indexPermissions []string,
-	globalIndexPermissions []string, roleType string) error {
-	name := fmt.Sprintf(common.RoleNamePattern, roleType)
-	logger.Debug(fmt.Sprintf("Creating role with name [%s]", name))
+func newRole(clusterPermissions []string, indexPermissions []string, globalIndexPermissions []string) Role {
 	role := Role{
 		ClusterPermissions: clusterPermissions,
 		IndexPermissions: []IndexPermission{
@@ -186,6 +222,12 @@ func (bp BaseProvider) createRole(clusterPermissions []string, indexPermissions
 			AllowedActions: globalIndexPermissions,
 		})
 	}
+	return role
+}
+
+func (bp BaseProvider) createRole(role Role, roleType string) error {
+	name := fmt.Sprintf(common.RoleNamePattern, roleType)
+	logger.Debug(fmt.Sprintf("Creating role with name [%s]", name))
 	body, err := json.Marshal(role)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to marshal body for '%s' role", name))
@@ -213,6 +255,60 @@ func (bp BaseProvider) createRole(clusterPermissions []string, indexPermissions
 	return fmt.Errorf("role with name [%s] is not created: %+v", name, response.Body)
 }
 
+// VerifyRole checks that the role of the given type and its mapping exist and contain permissions and backend roles
+// which are configured by adapter. Additional permissions and backend roles are allowed.
+func (bp BaseProvider) VerifyRole(roleType string, enhancedSecurityPluginEnabled bool) error {
+	name := fmt.Sprintf(common.RoleNamePattern, roleType)
+	role, err := bp.GetRole(name)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return fmt.Errorf("'%s' role is not found", name)
+	}
+	expected := roleDefinition(roleType, enhancedSecurityPluginEnabled)
+	if missing := missingElements(expected.ClusterPermissions, role.ClusterPermissions); len(missing) > 0 {
+		return fmt.Errorf("'%s' role does not have cluster permissions %v", name, missing)
+	}
+	for _, expectedPermission := range expected.IndexPermissions {
+		if len(expectedPermission.AllowedActions) == 0 {
+			continue
+		}
+		var actions []string
+		for _, permission := range role.IndexPermissions {
+			if len(missingElements(expectedPermission.IndexPatterns, permission.IndexPatterns)) == 0 {
+				actions = append(actions, permission.AllowedActions...)
+			}
+		}
+		if missing := missingElements(expectedPermission.AllowedActions, actions); len(missing) > 0 {
+			return fmt.Errorf("'%s' role does not have permissions %v for %v indices", name, missing,
+				expectedPermission.IndexPatterns)
+		}
+	}
+	mapping, err := bp.GetRoleMapping(name)
+	if err != nil {
+		return err
+	}
+	if mapping == nil {
+		return fmt.Errorf("role mapping for '%s' role is not found", name)
+	}
+	if missing := missingElements(bp.GetBackendRolesForMapping(roleType), mapping.BackendRoles); len(missing) > 0 {
+		return fmt.Errorf("role mapping for '%s' role does not have backend roles %v", name, missing)
+	}
+	return nil
+}
+
+// missingElements returns elements of expected slice which are absent in actual one
+func missingElements(expected []string, actual []string) []string {
+	var missing []string
+	for _, element := range expected {
+		if !slices.Contains(actual, element) {
+			missing = append(missing, element)
+		}
+	}
+	return missing
+}
+
 func (bp BaseProvider) GetRole(name string) (*Role, error) {
 	logger.Debug(fmt.Sprintf("Getting role with name '%s'", name))
 	getRoleRequest := api.GetRoleRequest{
@@ -26,8 +26,11 @@ import (
 
 	"github.com/Netcracker/dbaas-opensearch-adapter/api"
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/tracing"
 	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -70,12 +73,14 @@ func (bp BaseProvider) CreateUserHandler() func(w http.ResponseWriter, r *http.R
 		}
 		logger.InfoContext(ctx, fmt.Sprintf("Request to create user with [%s] name is received", username))
 
-		decoder := json.NewDecoder(r.Body)
 		var userCreateRequest dao.UserCreateRequest
-		err := decoder.Decode(&userCreateRequest)
+		err := common.DecodeJson(r.Body, &userCreateRequest)
+		if err == nil {
+			err = bp.validateUserCreateRequest(username, userCreateRequest)
+		}
 		if err != nil {
-			logger.ErrorContext(ctx, "Failed to decode request in create database handler", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			logger.ErrorContext(ctx, "Invalid request in create user handler", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
 			return
 		}
 		defer r.Body.Close()
@@ -83,13 +88,13 @@ func (bp BaseProvider) CreateUserHandler() func(w http.ResponseWriter, r *http.R
 		response, err := bp.ensureUser(username, userCreateRequest, ctx)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to ensure user", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		responseBody, err := json.Marshal(response)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal response to JSON", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 
@@ -99,6 +104,9 @@ func (bp BaseProvider) CreateUserHandler() func(w http.ResponseWriter, r *http.R
 
 func (bp BaseProvider) ensureUser(username string, userCreateRequest dao.UserCreateRequest, ctx context.Context) (*CreatedUser, error) {
 	dbName := userCreateRequest.DbName
+	if dbName != "" {
+		ctx = common.WithDbPrefix(ctx, dbName)
+	}
 	roleType := userCreateRequest.Role
 	if roleType == "" {
 		roleType = AdminRoleType
@@ -113,7 +121,7 @@ func (bp BaseProvider) ensureUser(username string, userCreateRequest dao.UserCre
 		resources = append(resources, dao.DbResource{Kind: common.ResourcePrefixKind, Name: dbName})
 	}
 	connectionProperties := bp.GetExtendedConnectionProperties("", username, password, "", roleType)
-	user, err := bp.GetUser(username)
+	user, err := bp.GetUser(username, ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +143,12 @@ func (bp BaseProvider) CreateUserByPrefix(prefix string, password string, dbName
 	return bp.createOrUpdateUser(username, password, dbName, roleType, ctx)
 }
 
+// CreateOrUpdateUser creates user with the given name or updates existing one, password is generated
+// if it is not specified.
+func (bp BaseProvider) CreateOrUpdateUser(username string, password string, dbName string, roleType string, ctx context.Context) (string, string, []dao.DbResource, error) {
+	return bp.createOrUpdateUser(username, password, dbName, roleType, ctx)
+}
+
 func (bp BaseProvider) createOrUpdateUser(username string, password string, dbName string, roleType string, ctx context.Context) (string, string, []dao.DbResource, error) {
 	bp.mutex.Lock()
 	defer bp.mutex.Unlock()
@@ -152,7 +166,7 @@ func (bp BaseProvider) createOrUpdateUser(username string, password string, dbNa
 		}
 	}
 
-	user, err := bp.GetUser(username)
+	user, err := bp.GetUser(username, ctx)
 	if err != nil {
 		logger.ErrorContext(ctx, fmt.Sprintf("Error occurred during getting user '%s': %v", username, err))
 		return username, password, resources, err
@@ -195,7 +209,10 @@ func (bp BaseProvider) createUser(username string, password string, prefix strin
 		Header:   header,
 	}
 
+	ctx, span := tracing.Start(ctx, "create user", trace.WithAttributes(attribute.String("user.name", username)))
+	attempts := 0
 	err = wait.PollImmediate(interval, timeout, func() (done bool, err error) {
+		attempts++
 		response, err := userRequest.Do(ctx, bp.opensearch.Client)
 		if err != nil {
 			logger.ErrorContext(ctx, "Can't process create user request", slog.Any("error", err))
@@ -215,6 +232,8 @@ func (bp BaseProvider) createUser(username string, password string, prefix strin
 			return false, nil
 		}
 	})
+	span.SetAttributes(attribute.Int("attempts", attempts))
+	tracing.End(span, err)
 
 	if err != nil {
 		return err
@@ -249,8 +268,11 @@ func (bp BaseProvider) PatchUser(username string, password string, prefix string
 		Body:     bodyReader,
 		Header:   header,
 	}
+	ctx, span := tracing.Start(ctx, "update user", trace.WithAttributes(attribute.String("user.name", username)))
+	attempts := 0
 	err = wait.PollImmediate(interval, timeout, func() (done bool, err error) {
-		response, err := patchUserRequest.Do(context.Background(), bp.opensearch.Client)
+		attempts++
+		response, err := patchUserRequest.Do(ctx, bp.opensearch.Client)
 		if err != nil {
 			logger.DebugContext(ctx, "Can't perform patch user request", slog.Any("error", err))
 			return false, nil
@@ -269,6 +291,8 @@ func (bp BaseProvider) PatchUser(username string, password string, prefix string
 			return false, nil
 		}
 	})
+	span.SetAttributes(attribute.Int("attempts", attempts))
+	tracing.End(span, err)
 
 	if err != nil {
 		return err
@@ -276,6 +300,31 @@ func (bp BaseProvider) PatchUser(username string, password string, prefix string
 	return nil
 }
 
+// ResetPassword generates new password for the existing user and returns connection properties of the user with
+// the new password. It is used to return credentials which are not stored by the adapter again.
+func (bp BaseProvider) ResetPassword(username string, ctx context.Context) (common.ConnectionProperties, error) {
+	user, err := bp.GetUser(username, ctx)
+	if err != nil {
+		return common.ConnectionProperties{}, err
+	}
+	if user == nil {
+		return common.ConnectionProperties{}, common.NewError(common.ErrNotFound, fmt.Sprintf("user '%s' is not found", username))
+	}
+	password, err := bp.passwordGenerator.Generate()
+	if err != nil {
+		return common.ConnectionProperties{}, err
+	}
+	if err = bp.PatchUser(username, password, "", "", ctx); err != nil {
+		return common.ConnectionProperties{}, err
+	}
+	roleType := ""
+	if len(user.Roles) != 0 {
+		roleType = bp.DefineRoleType(user.Roles[0])
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("Password of '%s' user is reset", username))
+	return bp.GetExtendedConnectionProperties("", username, password, user.Attributes[resourcePrefixAttributeName], roleType), nil
+}
+
 func (bp BaseProvider) patchUsers(changes []Change, ctx context.Context) error {
 	if len(changes) == 0 {
 		return nil
@@ -291,7 +340,7 @@ func (bp BaseProvider) patchUsers(changes []Change, ctx context.Context) error {
 		Body:   bodyReader,
 		Header: header,
 	}
-	response, err := patchUsersRequest.Do(context.Background(), bp.opensearch.Client)
+	response, err := patchUsersRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return err
 	}
@@ -308,11 +357,11 @@ func (bp BaseProvider) patchUsers(changes []Change, ctx context.Context) error {
 		string(responseBody))
 }
 
-func (bp BaseProvider) GetUser(username string) (*User, error) {
+func (bp BaseProvider) GetUser(username string, ctx context.Context) (*User, error) {
 	getUserRequest := api.GetUserRequest{
 		Username: username,
 	}
-	response, err := getUserRequest.Do(context.Background(), bp.opensearch.Client)
+	response, err := getUserRequest.Do(ctx, bp.opensearch.Client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to receive user with '%s' name: %+v", username, err)
 	}
@@ -361,6 +410,33 @@ func (bp BaseProvider) getUsersByPrefix(prefix string) ([]string, error) {
 	return nil, fmt.Errorf("during receiving users by prefix %s error occurred: %+v", prefix, response.Body)
 }
 
+// CountManagedResources returns the number of databases and users managed by adapter. Managed users are users
+// with resource prefix attribute and each distinct resource prefix is considered as a database.
+func (bp BaseProvider) CountManagedResources(ctx context.Context) (int, int, error) {
+	getUsersRequest := api.GetUsersRequest{}
+	response, err := getUsersRequest.Do(ctx, bp.opensearch.Client)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to receive users: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("failed to receive users, status code is %d", response.StatusCode)
+	}
+	var users map[string]User
+	if err = common.ProcessBody(response.Body, &users); err != nil {
+		return 0, 0, err
+	}
+	prefixes := make(map[string]bool)
+	managedUsers := 0
+	for _, user := range users {
+		if prefix := user.Attributes[resourcePrefixAttributeName]; prefix != "" {
+			prefixes[prefix] = true
+			managedUsers++
+		}
+	}
+	return len(prefixes), managedUsers, nil
+}
+
 func (bp BaseProvider) deleteUser(username string, ctx context.Context) error {
 	deleteUserRequest := api.DeleteUserRequest{
 		Username: username,
@@ -134,7 +134,7 @@ func TestUpdateDmlUserWithPassword(t *testing.T) {
 
 func TestGetUser(t *testing.T) {
 	username := "a73a026a-da44-4257-aed8-b1ee1bff5b7c"
-	response, err := baseProvider.GetUser(username)
+	response, err := baseProvider.GetUser(username, ctx)
 	assert.Empty(t, err)
 	assert.ElementsMatch(t, []string{username}, response.Roles)
 	assert.EqualValues(t, map[string]string{resourcePrefixAttributeName: username}, response.Attributes)
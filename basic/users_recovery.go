@@ -16,13 +16,14 @@ package basic
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/metrics"
 )
 
 const (
@@ -33,6 +34,42 @@ const (
 	batchSize            = 100
 )
 
+// usersRecovery is the state of users recovery, it is shared by providers of all API versions
+type usersRecovery struct {
+	mutex sync.Mutex
+	state string
+}
+
+func newUsersRecovery() *usersRecovery {
+	metrics.SetRecoveryState(RecoveryIdleState)
+	return &usersRecovery{state: RecoveryIdleState}
+}
+
+// start switches recovery to running state, false is returned if recovery is already running
+func (ur *usersRecovery) start() bool {
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	if ur.state == RecoveryRunningState {
+		return false
+	}
+	ur.state = RecoveryRunningState
+	metrics.SetRecoveryState(ur.state)
+	return true
+}
+
+func (ur *usersRecovery) setState(state string) {
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	ur.state = state
+	metrics.SetRecoveryState(state)
+}
+
+func (ur *usersRecovery) getState() string {
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	return ur.state
+}
+
 type UsersToRecover struct {
 	Settings             map[string]interface{}        `json:"settings,omitempty"`
 	ConnectionProperties []common.ConnectionProperties `json:"connectionProperties"`
@@ -41,17 +78,24 @@ type UsersToRecover struct {
 func (bp *BaseProvider) RecoverUsersHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := common.PrepareContext(r)
-		decoder := json.NewDecoder(r.Body)
 		var usersToRecover UsersToRecover
-		err := decoder.Decode(&usersToRecover)
+		err := common.DecodeJson(r.Body, &usersToRecover)
+		if err == nil {
+			err = bp.validateUsersToRecover(usersToRecover)
+		}
 		if err != nil {
-			logger.ErrorContext(ctx, "Failed to decode request in recover users handler", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			logger.ErrorContext(ctx, "Invalid request in recover users handler", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
 			return
 		}
-		if bp.recoveryState != RecoveryRunningState {
-			bp.recoveryState = RecoveryRunningState
-			go bp.recovery(usersToRecover.ConnectionProperties, ctx)
+		if bp.recovery.start() {
+			done := bp.Drainer.Track("users-recovery")
+			// recovery continues after the response is sent, so it must not be cancelled with the request
+			ctx := context.WithoutCancel(ctx)
+			go func() {
+				defer done()
+				bp.recoverUsers(usersToRecover.ConnectionProperties, ctx)
+			}()
 		}
 		w.WriteHeader(http.StatusOK)
 	}
@@ -60,12 +104,12 @@ func (bp *BaseProvider) RecoverUsersHandler() func(w http.ResponseWriter, r *htt
 func (bp *BaseProvider) GetRecoveryStateHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		responseBody := []byte(bp.recoveryState)
+		responseBody := []byte(bp.recovery.getState())
 		common.ProcessResponseBody(ctx, w, responseBody, http.StatusOK)
 	}
 }
 
-func (bp *BaseProvider) recovery(connectionProperties []common.ConnectionProperties, ctx context.Context) {
+func (bp *BaseProvider) recoverUsers(connectionProperties []common.ConnectionProperties, ctx context.Context) {
 	var changes []Change
 	for _, properties := range connectionProperties {
 		changes = append(changes, Change{
@@ -75,6 +119,7 @@ func (bp *BaseProvider) recovery(connectionProperties []common.ConnectionPropert
 		})
 	}
 	position := 0
+	metrics.SetRecoveryProgress(position, len(changes))
 	var batch []Change
 	for position < len(changes) {
 		if position+batchSize < len(changes) {
@@ -93,13 +138,14 @@ func (bp *BaseProvider) recovery(connectionProperties []common.ConnectionPropert
 			time.Sleep(10 * time.Second)
 		}
 		if err != nil {
-			bp.recoveryState = RecoveryFailedState
+			bp.recovery.setState(RecoveryFailedState)
 			logger.ErrorContext(ctx, fmt.Sprintf("Unable to restore users because of error: %+v", err))
 			return
 		}
-		position += batchSize
+		position += len(batch)
+		metrics.SetRecoveryProgress(position, len(changes))
 	}
-	bp.recoveryState = RecoveryDoneState
+	bp.recovery.setState(RecoveryDoneState)
 	logger.InfoContext(ctx, "Users recovery is successfully finished")
 }
 
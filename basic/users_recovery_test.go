@@ -15,10 +15,17 @@
 package basic
 
 import (
+	"context"
 	"fmt"
+	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestUserContentWithResourcePrefix(t *testing.T) {
@@ -149,3 +156,42 @@ func TestUserContentWithoutRole(t *testing.T) {
 	assert.EqualValues(t, expectedAttributes, content.Attributes)
 	assert.EqualValues(t, expectedBackendRoles, content.BackendRoles)
 }
+
+// requestContextClient patches users only after released is closed and fails requests with cancelled context
+// like OpenSearch client does
+type requestContextClient struct {
+	*common.ClientStub
+	released chan struct{}
+	patches  atomic.Int32
+}
+
+func (c *requestContextClient) Perform(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPatch && strings.HasPrefix(req.URL.Path, "/_plugins/_security/api/internalusers") {
+		<-c.released
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		c.patches.Add(1)
+	}
+	return c.ClientStub.Perform(req)
+}
+
+func TestRecoverUsersAfterResponse(t *testing.T) {
+	client := &requestContextClient{ClientStub: common.NewClient(), released: make(chan struct{})}
+	provider := NewBaseProvider(&cluster.Opensearch{Host: "localhost", Port: 9200, Protocol: common.Http, Client: client})
+	body := `{"connectionProperties":[{"username":"orders_admin","password":"secret","resourcePrefix":"orders","role":"admin"}]}`
+	requestCtx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodPost, "/users/restore-password", strings.NewReader(body)).WithContext(requestCtx)
+	recorder := httptest.NewRecorder()
+	provider.RecoverUsersHandler()(recorder, request)
+	// net/http cancels context of the request as soon as the handler returns
+	cancel()
+	close(client.released)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Eventually(t, func() bool {
+		return provider.recovery.getState() != RecoveryRunningState
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, RecoveryDoneState, provider.recovery.getState())
+	assert.Equal(t, int32(1), client.patches.Load())
+}
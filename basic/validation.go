@@ -0,0 +1,101 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
+)
+
+// maxDbNameLength leaves room for the prefix and separator in the name of index "<prefix>_<dbName>"
+const maxDbNameLength = common.MaxIndexNameLength - common.MaxPrefixLength - 1
+
+func (bp BaseProvider) validateDbCreateRequest(request DbCreateRequest) error {
+	var validator common.Validator
+	if request.NamePrefix != "" {
+		validator.Check("namePrefix", common.ValidatePrefix(request.NamePrefix))
+	}
+	if request.DbName != "" {
+		validator.Check("dbName", validateDbName(request.DbName))
+	}
+	if bp.Version() == common.ApiV2 && !request.Settings.ResourcePrefix {
+		validator.Add("settings.resourcePrefix", "must be 'true' for v2 version of OpenSearch DBaaS adapter")
+	}
+	for i, kind := range request.Settings.CreateOnly {
+		validator.OneOf(fmt.Sprintf("settings.createOnly[%d]", i), kind, []string{common.UserKind, common.IndexKind})
+	}
+	return validator.Err()
+}
+
+func (bp BaseProvider) validateUserCreateRequest(username string, request dao.UserCreateRequest) error {
+	var validator common.Validator
+	if strings.Contains(username, ":") {
+		validator.Add("name", "must not contain ':' character")
+	}
+	if request.DbName != "" {
+		validator.Check("dbName", common.ValidatePrefix(request.DbName))
+	}
+	if request.Role != "" {
+		validator.OneOf("role", request.Role, bp.GetSupportedRoleTypes())
+	}
+	return validator.Err()
+}
+
+func (bp BaseProvider) validateUsersToRecover(request UsersToRecover) error {
+	var validator common.Validator
+	if len(request.ConnectionProperties) == 0 {
+		validator.Add("connectionProperties", "must not be empty")
+	}
+	for i, properties := range request.ConnectionProperties {
+		field := fmt.Sprintf("connectionProperties[%d]", i)
+		validator.Required(field+".username", properties.Username)
+		validator.Required(field+".password", properties.Password)
+		if properties.ResourcePrefix != "" {
+			validator.Check(field+".resourcePrefix", common.ValidatePrefix(properties.ResourcePrefix))
+		}
+		if properties.Role != "" {
+			validator.OneOf(field+".role", properties.Role, bp.GetSupportedRoleTypes())
+		}
+	}
+	return validator.Err()
+}
+
+func validateResources(resources []dao.DbResource) error {
+	var validator common.Validator
+	for i, resource := range resources {
+		field := fmt.Sprintf("[%d]", i)
+		validator.OneOf(field+".kind", resource.Kind, common.ResourceKinds)
+		validator.Required(field+".name", resource.Name)
+	}
+	return validator.Err()
+}
+
+// validateDbName checks name of the database which is the suffix of index name,
+// so rules for the first character of index name are not applied
+func validateDbName(dbName string) error {
+	switch {
+	case len(dbName) > maxDbNameLength:
+		return fmt.Errorf("must not be longer than %d bytes", maxDbNameLength)
+	case strings.ToLower(dbName) != dbName:
+		return errors.New("must be lowercase")
+	case strings.ContainsAny(dbName, common.IndexNameForbiddenChars):
+		return fmt.Errorf("must not contain any of '%s' characters", common.IndexNameForbiddenChars)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package basic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDbCreateRequest(t *testing.T) {
+	assert.Nil(t, baseProvider.validateDbCreateRequest(DbCreateRequest{NamePrefix: "orders", DbName: "_orders",
+		Settings: Settings{CreateOnly: []string{common.UserKind, common.IndexKind}}}))
+
+	err := baseProvider.WithApiVersion(common.ApiV2).validateDbCreateRequest(DbCreateRequest{
+		NamePrefix: "Test_Prefix",
+		DbName:     "orders#1",
+		Settings:   Settings{CreateOnly: []string{"alias"}},
+	})
+	assert.ErrorIs(t, err, common.ErrValidation)
+	var fields []string
+	for _, fieldError := range err.(*common.Error).Details.([]common.FieldError) {
+		fields = append(fields, fieldError.Field)
+	}
+	assert.Equal(t, []string{"namePrefix", "dbName", "settings.resourcePrefix", "settings.createOnly[0]"}, fields)
+}
+
+func TestValidateResources(t *testing.T) {
+	assert.Nil(t, validateResources([]dao.DbResource{{Kind: common.IndexKind, Name: "dbaas_test"}}))
+	assert.ErrorIs(t, validateResources([]dao.DbResource{{Kind: "database", Name: "dbaas_test"}}), common.ErrValidation)
+	assert.ErrorIs(t, validateResources([]dao.DbResource{{Kind: common.UserKind}}), common.ErrValidation)
+}
+
+func TestCreateUserHandlerRejectsInvalidRequest(t *testing.T) {
+	body := `{"dbName":"dbaas_test","role":"owner","comment":"test"}`
+	request := mux.SetURLVars(httptest.NewRequest(http.MethodPut, "/users/test", strings.NewReader(body)),
+		map[string]string{"name": "test"})
+	recorder := httptest.NewRecorder()
+	baseProvider.CreateUserHandler()(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	var response common.ErrorResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, common.ErrValidation.Code, response.Code)
+	assert.NotContains(t, response.Message, "comment")
+
+	request = mux.SetURLVars(httptest.NewRequest(http.MethodPut, "/users/test", strings.NewReader(`{"role":"owner"}`)),
+		map[string]string{"name": "test"})
+	recorder = httptest.NewRecorder()
+	baseProvider.CreateUserHandler()(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"field":   "role",
+		"message": "must be one of readonly, dml, admin, ism, but 'owner' is specified",
+	}}, response.Details)
+}
+
+func TestCreateDatabaseHandlerIgnoresAggregatorFields(t *testing.T) {
+	body := `{
+		"metadata": {
+			"classifier": {"microserviceName": "order-service", "namespace": "orders", "scope": "service"},
+			"microserviceName": "order-service",
+			"namespace": "orders"
+		},
+		"namePrefix": "orders-order-service",
+		"dbName": "events",
+		"password": null,
+		"username": null,
+		"settings": {"resourcePrefix": true, "createOnly": ["user"]},
+		"initScriptIdentifiers": null,
+		"originService": "order-service",
+		"userRole": "admin"
+	}`
+	recorder := httptest.NewRecorder()
+	baseProvider.WithApiVersion(common.ApiV2).CreateDatabaseHandler()(recorder,
+		httptest.NewRequest(http.MethodPost, "/databases", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var response DbCreateResponseMultiUser
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.ConnectionProperties)
+}
@@ -25,6 +25,8 @@ import (
 	"strings"
 
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/metrics"
+	"github.com/Netcracker/dbaas-opensearch-adapter/tracing"
 	"github.com/opensearch-project/opensearch-go"
 	"github.com/opensearch-project/opensearch-go/opensearchapi"
 )
@@ -39,7 +41,8 @@ type Opensearch struct {
 	Client   common.Client
 }
 
-const trustCertsFolder = "/trusted-certs"
+// TrustCertsFolder contains certificates which are trusted by OpenSearch client
+const TrustCertsFolder = "/trusted-certs"
 
 func NewOpensearch(host string, port int, protocol string, username string, password string) *Opensearch {
 	address := fmt.Sprintf("%s://%s:%d", protocol, host, port)
@@ -47,9 +50,9 @@ func NewOpensearch(host string, port int, protocol string, username string, pass
 
 	var transport *http.Transport
 	if strings.EqualFold(protocol, common.Https) {
-		certsDir, err := os.ReadDir(trustCertsFolder)
+		certsDir, err := os.ReadDir(TrustCertsFolder)
 		if err != nil || len(certsDir) == 0 {
-			logger.Info(fmt.Sprintf("Cannot load trusted TLS certificates from path '%s'. InsecureSkipVerify is used.", trustCertsFolder))
+			logger.Info(fmt.Sprintf("Cannot load trusted TLS certificates from path '%s'. InsecureSkipVerify is used.", TrustCertsFolder))
 			transport = &http.Transport{
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 			}
@@ -58,7 +61,7 @@ func NewOpensearch(host string, port int, protocol string, username string, pass
 			successfullyAppendedCerts := 0
 			for _, cert := range certsDir {
 				if common.IsNotDir(cert) {
-					pemData, err := os.ReadFile(fmt.Sprintf("%s/%s", trustCertsFolder, cert.Name()))
+					pemData, err := os.ReadFile(fmt.Sprintf("%s/%s", TrustCertsFolder, cert.Name()))
 					if err != nil {
 						logger.Error(fmt.Sprintf("Failed to read certificate '%s': %+v", cert.Name(), err))
 						panic(err)
@@ -70,7 +73,7 @@ func NewOpensearch(host string, port int, protocol string, username string, pass
 				}
 			}
 			if successfullyAppendedCerts == 0 {
-				logger.Warn(fmt.Sprintf("Cannot load valid trusted TLS certificates from path '%s'. InsecureSkipVerify mode is used. Do not use this mode in production.", trustCertsFolder))
+				logger.Warn(fmt.Sprintf("Cannot load valid trusted TLS certificates from path '%s'. InsecureSkipVerify mode is used. Do not use this mode in production.", TrustCertsFolder))
 				transport = &http.Transport{
 					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 				}
@@ -101,7 +104,7 @@ func NewOpensearch(host string, port int, protocol string, username string, pass
 		Port:     port,
 		Protocol: protocol,
 		Health:   common.ComponentHealth{Status: common.Up},
-		Client:   oc,
+		Client:   tracing.InstrumentClient(metrics.InstrumentClient(oc)),
 	}
 
 	service.Health.Status = service.GetHealth(context.Background())
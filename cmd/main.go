@@ -33,7 +33,7 @@ import (
 var (
 	//nolint:errcheck
 	tlsEnabled, _   = strconv.ParseBool(common.GetEnv("TLS_ENABLED", "false"))
-	adapterPort     = 8080
+	adapterPort     = common.GetIntEnv("DBAAS_ADAPTER_PORT", 8080)
 	adapterProtocol = common.Http
 	adapterUsername = common.GetEnv("DBAAS_ADAPTER_USERNAME", "dbaas-aggregator")
 	adapterPassword = common.GetEnv("DBAAS_ADAPTER_PASSWORD", "dbaas-aggregator")
@@ -56,7 +56,6 @@ func main() {
 	logger.Info(fmt.Sprintf("Run build %s / %s with %+v ...", buildstamp, githash, os.Args))
 	flag.Parse()
 	if tlsEnabled {
-		adapterPort = 8443
 		adapterProtocol = common.Https
 	}
 	cl := client.NewAdapterClient(adapterProtocol, "", adapterPort, adapterUsername, adapterPassword)
@@ -74,7 +73,7 @@ func main() {
 		return
 	}
 
-	server.Server(ctx, adapterAddress, adapterUsername, adapterPassword)
+	server.Server(ctx, adapterAddress, adapterPort, adapterUsername, adapterPassword)
 }
 
 func terminal(reader *bufio.Reader, cl *client.AdapterClient) {
@@ -0,0 +1,42 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import "sync/atomic"
+
+// NegotiatedApiVersion holds adapter API version negotiated with DBaaS aggregator. It is shared by registration
+// and providers, so all of them observe the switch of API version at the same time. Nil NegotiatedApiVersion
+// holds empty version.
+type NegotiatedApiVersion struct {
+	value atomic.Value
+}
+
+func NewNegotiatedApiVersion(apiVersion string) *NegotiatedApiVersion {
+	version := &NegotiatedApiVersion{}
+	version.Set(apiVersion)
+	return version
+}
+
+func (v *NegotiatedApiVersion) Get() string {
+	if v == nil {
+		return ""
+	}
+	apiVersion, _ := v.value.Load().(string)
+	return apiVersion
+}
+
+func (v *NegotiatedApiVersion) Set(apiVersion string) {
+	v.value.Store(apiVersion)
+}
@@ -21,7 +21,6 @@ import (
 	"github.com/Netcracker/dbaas-opensearch-adapter/api"
 	"io"
 	"io/fs"
-	"log"
 	"log/slog"
 	"net/http"
 	"os"
@@ -88,11 +87,6 @@ type Supports struct {
 	DescribeDatabases bool `json:"describeDatabases"`
 }
 
-type CustomLogHandler struct {
-	slog.Handler
-	l *log.Logger
-}
-
 type User struct {
 	Attributes map[string]string `json:"attributes,omitempty"`
 	Hash       string            `json:"hash"`
@@ -103,39 +97,6 @@ func GetBasePath() string {
 	return fmt.Sprintf("/api/%s/dbaas/adapter/opensearch", GetEnv("API_VERSION", ApiV2))
 }
 
-func NewCustomLogHandler(out io.Writer) *CustomLogHandler {
-	handlerOptions := &slog.HandlerOptions{}
-	if _, ok := os.LookupEnv("DEBUG"); ok {
-		handlerOptions.Level = slog.LevelDebug
-	}
-
-	return &CustomLogHandler{
-		Handler: slog.NewTextHandler(out, handlerOptions),
-		l:       log.New(out, "", 0),
-	}
-}
-
-func GetLogger() *slog.Logger {
-	handler := NewCustomLogHandler(os.Stdout)
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
-	return logger
-}
-
-func (h *CustomLogHandler) Handle(ctx context.Context, record slog.Record) error {
-	level := fmt.Sprintf("[%v]", record.Level.String())
-	timeStr := record.Time.Format("[2006-01-02T15:04:05.999]")
-	msg := record.Message
-	requestId := ctx.Value(RequestIdKey)
-	if requestId == nil {
-		requestId = " "
-	}
-
-	h.l.Println(timeStr, level, fmt.Sprintf("[request_id=%s] [tenant_id= ] [thread= ] [class= ]", requestId), msg)
-
-	return nil
-}
-
 func GetCtxStringValue(ctx context.Context, key string) string {
 	value := ctx.Value(key)
 	return ConvertAnyToString(value)
@@ -232,12 +193,18 @@ func GetUUID() string {
 	return uuidValue.String()
 }
 
+// PrepareContext returns context of the request with request ID and tenant taken from request headers,
+// request ID is generated if it is not specified
 func PrepareContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if tenant := r.Header.Get(TenantKey); tenant != "" {
+		ctx = context.WithValue(ctx, TenantKey, tenant)
+	}
 	requestId := r.Header.Get(RequestIdKey)
 	if requestId == "" {
-		return context.WithValue(r.Context(), RequestIdKey, GenerateUUID())
+		return context.WithValue(ctx, RequestIdKey, GenerateUUID())
 	}
-	return context.WithValue(r.Context(), RequestIdKey, requestId)
+	return context.WithValue(ctx, RequestIdKey, requestId)
 }
 
 func CheckPrefixUniqueness(prefix string, ctx context.Context, opensearchcli Client) (bool, error) {
@@ -257,11 +224,11 @@ func CheckPrefixUniqueness(prefix string, ctx context.Context, opensearchcli Cli
 		for element, user := range users {
 			if strings.HasPrefix(element, prefix) {
 				logger.ErrorContext(ctx, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
-				return false, fmt.Errorf("provided prefix already exists or a part of another prefix: %+v", prefix)
+				return false, NewError(ErrConflict, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
 			}
 			if user.Attributes[resourcePrefixAttributeName] != "" && strings.HasPrefix(user.Attributes[resourcePrefixAttributeName], prefix) {
 				logger.ErrorContext(ctx, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
-				return false, fmt.Errorf("provided prefix already exists or a part of another prefix: %+v", prefix)
+				return false, NewError(ErrConflict, fmt.Sprintf("provided prefix already exists or a part of another prefix: %+v", prefix))
 			}
 		}
 	} else if response.StatusCode == http.StatusNotFound {
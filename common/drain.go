@@ -0,0 +1,167 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+)
+
+var ErrDraining = NewError(ErrServiceUnavailable, "adapter is shutting down and does not accept new operations")
+
+// Drainer tracks in-flight operations, so adapter can wait for them before shutdown. New operations are rejected
+// after Drain is called. Nil Drainer does not track anything.
+type Drainer struct {
+	mutex    sync.Mutex
+	draining bool
+	inFlight map[string]int
+	total    int
+	// idle is closed when there are no in-flight operations
+	idle  chan struct{}
+	hooks []func(ctx context.Context)
+}
+
+func NewDrainer() *Drainer {
+	idle := make(chan struct{})
+	close(idle)
+	return &Drainer{inFlight: make(map[string]int), idle: idle}
+}
+
+// Begin starts tracking of new operation, ErrDraining is returned if adapter is draining. The returned function
+// must be called when operation is finished.
+func (d *Drainer) Begin(operation string) (func(), error) {
+	if d == nil {
+		return func() {}, nil
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.draining {
+		return nil, ErrDraining
+	}
+	return d.track(operation), nil
+}
+
+// Track starts tracking of operation even if adapter is draining, it is used for background parts of operations
+// which have been already accepted. The returned function must be called when operation is finished.
+func (d *Drainer) Track(operation string) func() {
+	if d == nil {
+		return func() {}
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.track(operation)
+}
+
+func (d *Drainer) track(operation string) func() {
+	if d.total == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.total++
+	d.inFlight[operation]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mutex.Lock()
+			defer d.mutex.Unlock()
+			d.total--
+			if d.inFlight[operation]--; d.inFlight[operation] == 0 {
+				delete(d.inFlight, operation)
+			}
+			if d.total == 0 {
+				close(d.idle)
+			}
+		})
+	}
+}
+
+// OnDrain registers hook which is called when draining is started, e.g. to notify other services
+func (d *Drainer) OnDrain(hook func(ctx context.Context)) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.hooks = append(d.hooks, hook)
+}
+
+// Drain makes adapter reject new operations and calls registered hooks
+func (d *Drainer) Drain(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	d.draining = true
+	hooks := d.hooks
+	d.hooks = nil
+	d.mutex.Unlock()
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+}
+
+func (d *Drainer) Draining() bool {
+	if d == nil {
+		return false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.draining
+}
+
+// InFlight returns the number of in-flight operations by their names
+func (d *Drainer) InFlight() map[string]int {
+	if d == nil {
+		return nil
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return maps.Clone(d.inFlight)
+}
+
+// Wait blocks until all in-flight operations are finished or ctx is done, in the last case the error describes
+// unfinished operations.
+func (d *Drainer) Wait(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+	d.mutex.Lock()
+	idle := d.idle
+	d.mutex.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("operations are not finished: %v: %w", d.InFlight(), ctx.Err())
+	}
+}
+
+// Guard rejects requests with 503 status while adapter is draining and tracks accepted requests as in-flight operation
+func (d *Drainer) Guard(operation string, h func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		done, err := d.Begin(operation)
+		if err != nil {
+			ctx := PrepareContext(r)
+			logger.WarnContext(ctx, fmt.Sprintf("Rejecting %s request, because adapter is draining", operation))
+			w.Header().Set("Retry-After", "5")
+			WriteError(ctx, w, err)
+			return
+		}
+		defer done()
+		h(w, r)
+	}
+}
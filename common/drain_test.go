@@ -0,0 +1,81 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainerWaitsForInFlightOperations(t *testing.T) {
+	drainer := NewDrainer()
+	assert.Nil(t, drainer.Wait(context.Background()))
+
+	done, err := drainer.Begin("backup")
+	assert.Nil(t, err)
+	hookCalled := false
+	drainer.OnDrain(func(ctx context.Context) { hookCalled = true })
+	drainer.Drain(context.Background())
+	assert.True(t, hookCalled)
+	assert.True(t, drainer.Draining())
+
+	_, err = drainer.Begin("backup")
+	assert.ErrorIs(t, err, ErrDraining)
+	finishBackground := drainer.Track("users-recovery")
+	assert.Equal(t, map[string]int{"backup": 1, "users-recovery": 1}, drainer.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, drainer.Wait(ctx), context.DeadlineExceeded)
+
+	done()
+	done()
+	finishBackground()
+	assert.Empty(t, drainer.InFlight())
+	assert.Nil(t, drainer.Wait(context.Background()))
+}
+
+func TestDrainerGuard(t *testing.T) {
+	drainer := NewDrainer()
+	handler := drainer.Guard("database-creation", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, map[string]int{"database-creation": 1}, drainer.InFlight())
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodPost, "/databases", nil))
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	drainer.Drain(context.Background())
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodPost, "/databases", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+}
+
+func TestNilDrainer(t *testing.T) {
+	var drainer *Drainer
+	done, err := drainer.Begin("backup")
+	assert.Nil(t, err)
+	done()
+	drainer.Drain(context.Background())
+	assert.False(t, drainer.Draining())
+	assert.Nil(t, drainer.Wait(context.Background()))
+}
@@ -0,0 +1,124 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// ErrorKind classifies errors returned by adapter API, each kind has its own error code and HTTP status.
+// Kinds are matched with errors.Is.
+type ErrorKind struct {
+	Code   string
+	Status int
+}
+
+func (k *ErrorKind) Error() string {
+	return k.Code
+}
+
+var (
+	ErrBadRequest          = &ErrorKind{Code: "BAD_REQUEST", Status: http.StatusBadRequest}
+	ErrValidation          = &ErrorKind{Code: "VALIDATION_FAILED", Status: http.StatusBadRequest}
+	ErrUnauthorized        = &ErrorKind{Code: "UNAUTHORIZED", Status: http.StatusUnauthorized}
+	ErrForbidden           = &ErrorKind{Code: "FORBIDDEN", Status: http.StatusForbidden}
+	ErrNotFound            = &ErrorKind{Code: "NOT_FOUND", Status: http.StatusNotFound}
+	ErrConflict            = &ErrorKind{Code: "CONFLICT", Status: http.StatusConflict}
+	ErrInternal            = &ErrorKind{Code: "INTERNAL_ERROR", Status: http.StatusInternalServerError}
+	ErrUpstreamUnavailable = &ErrorKind{Code: "UPSTREAM_UNAVAILABLE", Status: http.StatusBadGateway}
+	ErrServiceUnavailable  = &ErrorKind{Code: "SERVICE_UNAVAILABLE", Status: http.StatusServiceUnavailable}
+)
+
+// Error is an error of the given kind. Errors created by NewError can be used as sentinel errors,
+// errors wrapping them are matched by both the sentinel and its kind.
+type Error struct {
+	Kind    *ErrorKind
+	Message string
+	// Details are returned to client as is, they have to be serializable to JSON
+	Details interface{}
+	Cause   error
+}
+
+func NewError(kind *ErrorKind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// DecodeError is returned when request body can not be decoded
+func DecodeError(err error) *Error {
+	return &Error{Kind: ErrBadRequest, Message: "failed to decode request body", Cause: err}
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Kind, e.Cause}
+	}
+	return []error{e.Kind}
+}
+
+// ErrorResponse is the body of responses of failed requests
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	RequestId string      `json:"requestId,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// ErrorKindOf returns kind of the error, ErrInternal is returned for errors of unknown kind
+func ErrorKindOf(err error) *ErrorKind {
+	var kind *ErrorKind
+	if errors.As(err, &kind) {
+		return kind
+	}
+	return ErrInternal
+}
+
+// internalErrorMessage is returned instead of the message of internal errors, so their causes are not exposed
+// to clients. The cause is logged with the request id returned in ErrorResponse.
+const internalErrorMessage = "internal error occurred, see adapter logs by request id"
+
+// WriteError writes ErrorResponse with the status corresponding to the kind of the error
+func WriteError(ctx context.Context, w http.ResponseWriter, err error) {
+	kind := ErrorKindOf(err)
+	response := ErrorResponse{
+		Code:      kind.Code,
+		Message:   err.Error(),
+		RequestId: GetCtxStringValue(ctx, RequestIdKey),
+	}
+	var apiError *Error
+	if kind == ErrInternal {
+		logger.ErrorContext(ctx, "Internal error occurred", slog.Any("error", err))
+		response.Message = internalErrorMessage
+	} else if errors.As(err, &apiError) {
+		response.Details = apiError.Details
+	}
+	body, marshalErr := json.Marshal(response)
+	if marshalErr != nil {
+		logger.ErrorContext(ctx, "Failed to marshal error response to JSON", slog.Any("error", marshalErr))
+		body, _ = json.Marshal(ErrorResponse{Code: kind.Code, Message: response.Message, RequestId: response.RequestId})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	ProcessResponseBody(ctx, w, body, kind.Status)
+}
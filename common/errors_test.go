@@ -0,0 +1,66 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteError(t *testing.T) {
+	ctx := context.WithValue(context.Background(), RequestIdKey, "42")
+	errNotFound := NewError(ErrNotFound, "backup not found")
+	tests := []struct {
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{fmt.Errorf("failed to track backup: %w", errNotFound), http.StatusNotFound, "NOT_FOUND", "failed to track backup: backup not found"},
+		{DecodeError(errors.New("unexpected EOF")), http.StatusBadRequest, "BAD_REQUEST", "failed to decode request body: unexpected EOF"},
+		{ErrDraining, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", ErrDraining.Message},
+		{errors.New("connection refused"), http.StatusInternalServerError, "INTERNAL_ERROR", internalErrorMessage},
+		{NewError(ErrInternal, "failed to read users"), http.StatusInternalServerError, "INTERNAL_ERROR", internalErrorMessage},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		WriteError(ctx, recorder, test.err)
+
+		assert.Equal(t, test.status, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		var response ErrorResponse
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, ErrorResponse{Code: test.code, Message: test.message, RequestId: "42"}, response)
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	cause := errors.New("name is too long")
+	err := &Error{Kind: ErrValidation, Message: "request is invalid", Details: map[string]string{"name": "too long"}, Cause: cause}
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.ErrorIs(t, err, cause)
+
+	recorder := httptest.NewRecorder()
+	WriteError(context.Background(), recorder, err)
+	assert.JSONEq(t, `{"code":"VALIDATION_FAILED","message":"request is invalid: name is too long","details":{"name":"too long"}}`,
+		recorder.Body.String())
+}
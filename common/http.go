@@ -0,0 +1,85 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// apiSegments are path segments which identify OpenSearch API, all other segments not starting with "_"
+// are names of indices, users, snapshots, etc.
+var apiSegments = map[string]bool{
+	"api":                    true,
+	"roles":                  true,
+	"rolesmapping":           true,
+	"internalusers":          true,
+	"actiongroups":           true,
+	"health":                 true,
+	"indices":                true,
+	"reload_secure_settings": true,
+}
+
+// OpensearchApiName returns method and path of the request to OpenSearch where names of indices, users, etc.
+// are replaced with "{name}", e.g. "GET /{name}/_doc/{name}"
+func OpensearchApiName(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, segment := range segments {
+		if segment != "" && !strings.HasPrefix(segment, "_") && !apiSegments[segment] {
+			segments[i] = "{name}"
+		}
+	}
+	return req.Method + " /" + strings.Join(segments, "/")
+}
+
+// RouteTemplate returns path template of the mux route matched by the request or "unknown" if no route is matched
+func RouteTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
+
+// StatusRecorder remembers status code of the response written by handler
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status      int
+	wroteHeader bool
+}
+
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (sr *StatusRecorder) WriteHeader(status int) {
+	if !sr.wroteHeader {
+		sr.Status = status
+		sr.wroteHeader = true
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *StatusRecorder) Write(body []byte) (int, error) {
+	sr.wroteHeader = true
+	return sr.ResponseWriter.Write(body)
+}
+
+func (sr *StatusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
@@ -0,0 +1,464 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+const (
+	IdempotencyIndex = SystemIndexPrefix + "idempotency_keys"
+
+	// IdempotencyKeyHeader is the request header which identifies retries of the same operation
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to "true" in responses which are replayed from the stored ones
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyReservationTimeout is the time after which key of the request which has not been completed,
+	// e.g. because adapter was restarted, can be used again
+	idempotencyReservationTimeout = 5 * time.Minute
+)
+
+// IdempotentRequest is the stored request with Idempotency-Key header, response is empty until the request is completed
+type IdempotentRequest struct {
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body,omitempty"`
+	// Redacted is true if secret fields are removed from the stored body
+	Redacted  bool      `json:"redacted,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type storedIdempotentRequest struct {
+	Found       bool               `json:"found"`
+	SeqNo       *int               `json:"_seq_no"`
+	PrimaryTerm *int               `json:"_primary_term"`
+	Source      *IdempotentRequest `json:"_source"`
+}
+
+func (request *IdempotentRequest) completed() bool {
+	return request.Status != 0
+}
+
+func (request *IdempotentRequest) isExpired(now time.Time) bool {
+	return !request.ExpiresAt.After(now)
+}
+
+// IdempotencyStore keeps requests with Idempotency-Key header and their responses in IdempotencyIndex for TTL,
+// so retries of these requests are answered with the original response instead of performing operation again.
+type IdempotencyStore struct {
+	client Client
+	ttl    time.Duration
+	// resetPassword generates a new password of the user, it is used to restore passwords of replayed responses
+	resetPassword PasswordReset
+}
+
+// PasswordReset sets a new password of the existing user and returns it
+type PasswordReset func(ctx context.Context, username string) (string, error)
+
+func NewIdempotencyStore(client Client, ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{client: client, ttl: ttl}
+}
+
+// EnablePasswordReset allows to replay responses with removed passwords, passwords of users of such responses
+// are reset and the new ones are returned. Replays of such responses are rejected if it is not enabled.
+func (is *IdempotencyStore) EnablePasswordReset(reset PasswordReset) {
+	is.resetPassword = reset
+}
+
+func (is IdempotencyStore) EnsureIndex(ctx context.Context) error {
+	return EnsureIndex(ctx, is.client, IdempotencyIndex)
+}
+
+// Guard performs request with Idempotency-Key header only once within TTL. Retry with the same key and the same
+// request is answered with the stored response, retry with the same key and another request is rejected as well
+// as retry of the request which is still in progress. Requests without the header are performed as is.
+// Responses with 5xx status codes are not stored, so such requests can be retried. Secret fields of responses
+// are not stored, so passwords of users are reset when such responses are replayed.
+func (is IdempotencyStore) Guard(h func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			h(w, r)
+			return
+		}
+		ctx := PrepareContext(r)
+		if len(key) > maxIdempotencyKeyLength {
+			WriteError(ctx, w, NewError(ErrValidation,
+				fmt.Sprintf("%s header must not be longer than %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			WriteError(ctx, w, DecodeError(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		id := idempotencyDocumentId(GetCtxStringValue(ctx, PrincipalKey), key)
+		request := &IdempotentRequest{Key: key, Fingerprint: requestFingerprint(r, body)}
+		stored, err := is.reserve(ctx, id, request)
+		if err != nil {
+			logger.ErrorContext(ctx, fmt.Sprintf("Failed to reserve idempotency key '%s'", key), slog.Any("error", err))
+			WriteError(ctx, w, err)
+			return
+		}
+		if stored != nil {
+			is.replay(ctx, w, request, stored)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		h(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		if recorder.status >= http.StatusInternalServerError {
+			if err = is.release(ctx, id); err != nil {
+				logger.WarnContext(ctx, fmt.Sprintf("Failed to release idempotency key '%s'", key), slog.Any("error", err))
+			}
+			return
+		}
+		request.Status = recorder.status
+		request.ContentType = w.Header().Get("Content-Type")
+		request.Body, request.Redacted = withoutSecrets(recorder.body.Bytes())
+		request.ExpiresAt = time.Now().UTC().Add(is.ttl)
+		if err = is.save(ctx, id, request, ""); err != nil {
+			logger.WarnContext(ctx, fmt.Sprintf("Failed to store response of request with idempotency key '%s'", key), slog.Any("error", err))
+		}
+	}
+}
+
+func (is IdempotencyStore) replay(ctx context.Context, w http.ResponseWriter, request *IdempotentRequest, stored *IdempotentRequest) {
+	switch {
+	case stored.Fingerprint != request.Fingerprint:
+		WriteError(ctx, w, NewError(ErrConflict,
+			fmt.Sprintf("%s '%s' is already used for another request", IdempotencyKeyHeader, request.Key)))
+	case !stored.completed():
+		w.Header().Set("Retry-After", "5")
+		WriteError(ctx, w, NewError(ErrConflict,
+			fmt.Sprintf("request with %s '%s' is still in progress", IdempotencyKeyHeader, request.Key)))
+	default:
+		logger.InfoContext(ctx, fmt.Sprintf("Replaying response of request with %s '%s'", IdempotencyKeyHeader, request.Key))
+		body := []byte(stored.Body)
+		if stored.Redacted {
+			var err error
+			if body, err = is.restorePasswords(ctx, body); err != nil {
+				logger.ErrorContext(ctx, fmt.Sprintf("Failed to restore passwords of response of request with %s '%s'",
+					IdempotencyKeyHeader, request.Key), slog.Any("error", err))
+				WriteError(ctx, w, err)
+				return
+			}
+		}
+		if stored.ContentType != "" {
+			w.Header().Set("Content-Type", stored.ContentType)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		ProcessResponseBody(ctx, w, body, stored.Status)
+	}
+}
+
+// restorePasswords resets passwords of users which connection properties are stored without passwords and puts
+// the new passwords into body, so the replayed response is as usable as the original one
+func (is IdempotencyStore) restorePasswords(ctx context.Context, body []byte) ([]byte, error) {
+	if is.resetPassword == nil {
+		return nil, NewError(ErrConflict, "response is stored without passwords and they can not be restored, "+
+			"use another "+IdempotencyKeyHeader)
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if err := is.resetPasswords(ctx, value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// resetPasswords sets passwords of objects with "username" field and without "password" field at any depth
+func (is IdempotencyStore) resetPasswords(ctx context.Context, value interface{}) error {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		username, ok := value["username"].(string)
+		if _, found := value["password"]; ok && username != "" && !found {
+			password, err := is.resetPassword(ctx, username)
+			if err != nil {
+				return err
+			}
+			value["password"] = password
+		}
+		for _, field := range value {
+			if err := is.resetPasswords(ctx, field); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, element := range value {
+			if err := is.resetPasswords(ctx, element); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reserve stores request which is not completed yet, so concurrent retries do not perform it. Stored request
+// is returned if the key is already used and is not expired, nil is returned if the key is reserved.
+func (is IdempotencyStore) reserve(ctx context.Context, id string, request *IdempotentRequest) (*IdempotentRequest, error) {
+	now := time.Now().UTC()
+	request.CreatedAt = now
+	request.ExpiresAt = now.Add(idempotencyReservationTimeout)
+	// the second attempt is made if expired request is removed or the key is released concurrently
+	for attempt := 0; attempt < 2; attempt++ {
+		err := is.save(ctx, id, request, "create")
+		if err == nil {
+			return nil, nil
+		}
+		if ErrorKindOf(err) != ErrConflict {
+			return nil, err
+		}
+		stored, err := is.get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if stored == nil {
+			continue
+		}
+		if !stored.Source.isExpired(now) {
+			return stored.Source, nil
+		}
+		if err = is.delete(ctx, id, stored.SeqNo, stored.PrimaryTerm); err != nil && ErrorKindOf(err) != ErrConflict {
+			return nil, err
+		}
+	}
+	return nil, NewError(ErrConflict, fmt.Sprintf("%s '%s' is used concurrently", IdempotencyKeyHeader, request.Key))
+}
+
+func (is IdempotencyStore) release(ctx context.Context, id string) error {
+	return is.delete(ctx, id, nil, nil)
+}
+
+func (is IdempotencyStore) get(ctx context.Context, id string) (*storedIdempotentRequest, error) {
+	getRequest := opensearchapi.GetRequest{
+		Index:      IdempotencyIndex,
+		DocumentID: id,
+	}
+	response, err := getRequest.Do(ctx, is.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive request with idempotency key: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to receive request with idempotency key, status code is %d", response.StatusCode)
+	}
+	var stored storedIdempotentRequest
+	if err = ProcessBody(response.Body, &stored); err != nil {
+		return nil, err
+	}
+	if !stored.Found || stored.Source == nil {
+		return nil, nil
+	}
+	return &stored, nil
+}
+
+// save stores request, ErrConflict error is returned if opType is "create" and the key is already used
+func (is IdempotencyStore) save(ctx context.Context, id string, request *IdempotentRequest, opType string) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	indexRequest := opensearchapi.IndexRequest{
+		Index:      IdempotencyIndex,
+		DocumentID: id,
+		Body:       bytes.NewReader(body),
+		OpType:     opType,
+	}
+	response, err := indexRequest.Do(ctx, is.client)
+	if err != nil {
+		return fmt.Errorf("failed to store request with idempotency key: %w", err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return NewError(ErrConflict, fmt.Sprintf("%s '%s' is already used", IdempotencyKeyHeader, request.Key))
+	default:
+		return fmt.Errorf("failed to store request with idempotency key, status code is %d", response.StatusCode)
+	}
+}
+
+// delete removes stored request, it is removed only if it is not changed since it was received when seqNo is specified
+func (is IdempotencyStore) delete(ctx context.Context, id string, seqNo *int, primaryTerm *int) error {
+	deleteRequest := opensearchapi.DeleteRequest{
+		Index:         IdempotencyIndex,
+		DocumentID:    id,
+		IfSeqNo:       seqNo,
+		IfPrimaryTerm: primaryTerm,
+	}
+	response, err := deleteRequest.Do(ctx, is.client)
+	if err != nil {
+		return fmt.Errorf("failed to remove request with idempotency key: %w", err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		return nil
+	case http.StatusConflict:
+		return NewError(ErrConflict, "request with idempotency key is changed concurrently")
+	default:
+		return fmt.Errorf("failed to remove request with idempotency key, status code is %d", response.StatusCode)
+	}
+}
+
+// ExpirePeriodically removes expired requests with the given interval until context is done
+func (is IdempotencyStore) ExpirePeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := is.Expire(ctx, time.Now()); err != nil {
+			logger.ErrorContext(ctx, "Failed to remove expired requests with idempotency keys", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Expire removes requests which expiration time has come before the given time. Expired requests are ignored
+// even if they are not removed yet, so removal only keeps the index small.
+func (is IdempotencyStore) Expire(ctx context.Context, before time.Time) error {
+	query := fmt.Sprintf(`{"query":{"range":{"expiresAt":{"lte":"%s"}}}}`, before.UTC().Format(time.RFC3339))
+	deleteRequest := opensearchapi.DeleteByQueryRequest{
+		Index:     []string{IdempotencyIndex},
+		Body:      strings.NewReader(query),
+		Conflicts: "proceed",
+	}
+	response, err := deleteRequest.Do(ctx, is.client)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.IsError() {
+		return fmt.Errorf("failed to remove expired requests with idempotency keys, status code is %d", response.StatusCode)
+	}
+	return nil
+}
+
+// idempotencyDocumentId scopes keys by the principal, so clients cannot receive responses of each other
+func idempotencyDocumentId(principal string, key string) string {
+	hash := sha256.Sum256([]byte(principal + "\n" + key))
+	return hex.EncodeToString(hash[:])
+}
+
+// requestFingerprint identifies request by its method, path, query and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// secretFields are fields of JSON responses which are not stored, e.g. passwords of connection properties
+var secretFields = []string{"password"}
+
+// withoutSecrets removes secretFields from JSON body at any depth and reports if any of them is removed,
+// body which is not JSON is returned as is
+func withoutSecrets(body []byte) (string, bool) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || !removeSecrets(value) {
+		return string(body), false
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return string(body), false
+	}
+	return string(redacted), true
+}
+
+// removeSecrets removes secretFields from value and returns true if any of them is found
+func removeSecrets(value interface{}) bool {
+	removed := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if slices.Contains(secretFields, key) {
+				delete(value, key)
+				removed = true
+			} else if removeSecrets(field) {
+				removed = true
+			}
+		}
+	case []interface{}:
+		for _, element := range value {
+			if removeSecrets(element) {
+				removed = true
+			}
+		}
+	}
+	return removed
+}
+
+// responseRecorder copies response body, so it can be stored after the response is sent
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(body []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	rr.body.Write(body)
+	return rr.ResponseWriter.Write(body)
+}
+
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
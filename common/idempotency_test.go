@@ -0,0 +1,215 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func idempotentRequest(key string, body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/api/v2/dbaas/adapter/opensearch/databases", strings.NewReader(body))
+	request = request.WithContext(WithPrincipal(request.Context(), "dbaas-aggregator"))
+	if key != "" {
+		request.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return request
+}
+
+func countingHandler(calls *int, status int) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"call":%d,"request":%s}`, *calls, body)))
+	}
+}
+
+func TestIdempotencyGuardReplaysResponse(t *testing.T) {
+	store := NewIdempotencyStore(NewClient(), time.Hour)
+	calls := 0
+	handler := store.Guard(countingHandler(&calls, http.StatusCreated))
+
+	first := httptest.NewRecorder()
+	handler(first, idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	retry := httptest.NewRecorder()
+	handler(retry, idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+
+	another := httptest.NewRecorder()
+	handler(another, idempotentRequest("key-1", `{"namePrefix":"other"}`))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusConflict, another.Code)
+
+	withoutKey := httptest.NewRecorder()
+	handler(withoutKey, idempotentRequest("", `{"namePrefix":"test"}`))
+	handler(withoutKey, idempotentRequest("", `{"namePrefix":"test"}`))
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyGuardDoesNotStorePasswords(t *testing.T) {
+	client := NewClient()
+	store := NewIdempotencyStore(client, time.Hour)
+	handler := store.Guard(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"name":"test","connectionProperties":[{"username":"test_admin","password":"secret","role":"admin"}]}`))
+	})
+
+	first := httptest.NewRecorder()
+	handler(first, idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	assert.Contains(t, first.Body.String(), "secret")
+
+	ctx := WithPrincipal(context.Background(), "dbaas-aggregator")
+	stored, err := store.get(ctx, idempotencyDocumentId("dbaas-aggregator", "key-1"))
+	assert.Nil(t, err)
+	assert.NotNil(t, stored)
+	assert.NotContains(t, stored.Source.Body, "password")
+	assert.NotContains(t, stored.Source.Body, "secret")
+
+	assert.True(t, stored.Source.Redacted)
+
+	retry := httptest.NewRecorder()
+	handler(retry, idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	assert.Equal(t, http.StatusConflict, retry.Code)
+	assert.NotContains(t, retry.Body.String(), "test_admin")
+}
+
+func TestIdempotencyGuardResetsPasswordsOfReplayedResponse(t *testing.T) {
+	store := NewIdempotencyStore(NewClient(), time.Hour)
+	var reset []string
+	store.EnablePasswordReset(func(ctx context.Context, username string) (string, error) {
+		reset = append(reset, username)
+		return "new-secret", nil
+	})
+	handler := store.Guard(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"name":"test","connectionProperties":[{"username":"test_admin","password":"secret","role":"admin"}]}`))
+	})
+
+	first := httptest.NewRecorder()
+	handler(first, idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	assert.Empty(t, reset)
+
+	retry := httptest.NewRecorder()
+	handler(retry, idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, []string{"test_admin"}, reset)
+	assert.JSONEq(t, `{"name":"test","connectionProperties":[{"username":"test_admin","password":"new-secret","role":"admin"}]}`, retry.Body.String())
+}
+
+func TestIdempotencyGuardRejectsReplayIfPasswordIsNotReset(t *testing.T) {
+	store := NewIdempotencyStore(NewClient(), time.Hour)
+	store.EnablePasswordReset(func(ctx context.Context, username string) (string, error) {
+		return "", NewError(ErrNotFound, fmt.Sprintf("user '%s' is not found", username))
+	})
+	handler := store.Guard(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"connectionProperties":{"username":"test_admin","password":"secret"}}`))
+	})
+
+	handler(httptest.NewRecorder(), idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	retry := httptest.NewRecorder()
+	handler(retry, idempotentRequest("key-1", `{"namePrefix":"test"}`))
+	assert.Equal(t, http.StatusNotFound, retry.Code)
+	assert.Empty(t, retry.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotencyGuardDoesNotStoreServerErrors(t *testing.T) {
+	store := NewIdempotencyStore(NewClient(), time.Hour)
+	calls := 0
+	handler := store.Guard(countingHandler(&calls, http.StatusBadGateway))
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		handler(recorder, idempotentRequest("key-1", `["db1"]`))
+		assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyGuardRejectsRequestInProgress(t *testing.T) {
+	client := NewClient()
+	store := NewIdempotencyStore(client, time.Hour)
+	calls := 0
+	handler := store.Guard(countingHandler(&calls, http.StatusOK))
+
+	request := idempotentRequest("key-1", `["db1"]`)
+	inProgress := &IdempotentRequest{Key: "key-1", Fingerprint: requestFingerprint(request, []byte(`["db1"]`))}
+	stored, err := store.reserve(context.Background(), idempotencyDocumentId("dbaas-aggregator", "key-1"), inProgress)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, "5", recorder.Header().Get("Retry-After"))
+	var response ErrorResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, ErrConflict.Code, response.Code)
+
+	// keys are scoped by client
+	recorder = httptest.NewRecorder()
+	request = idempotentRequest("key-1", `["db1"]`)
+	request = request.WithContext(WithPrincipal(request.Context(), "backup-operator"))
+	handler(recorder, request)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestIdempotencyGuardReusesExpiredKey(t *testing.T) {
+	store := NewIdempotencyStore(NewClient(), -time.Second)
+	calls := 0
+	handler := store.Guard(countingHandler(&calls, http.StatusOK))
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		handler(recorder, idempotentRequest("key-1", `["db1"]`))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get(IdempotentReplayedHeader))
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyGuardRejectsLongKey(t *testing.T) {
+	store := NewIdempotencyStore(NewClient(), time.Hour)
+	calls := 0
+	handler := store.Guard(countingHandler(&calls, http.StatusOK))
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, idempotentRequest(strings.Repeat("k", maxIdempotencyKeyLength+1), `["db1"]`))
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
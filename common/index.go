@@ -0,0 +1,86 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+// IndexExists checks that index with the given name exists
+func IndexExists(ctx context.Context, client Client, name string) (bool, error) {
+	existsRequest := opensearchapi.IndicesExistsRequest{
+		Index: []string{name},
+	}
+	response, err := existsRequest.Do(ctx, client)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if '%s' index exists %w", name, err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check if '%s' index exists, status code is %d", name, response.StatusCode)
+	}
+}
+
+// EnsureIndex creates index with the given name if it does not exist yet. It is used for adapter's own
+// system indices, so the index is created with default settings. Index which is created concurrently, e.g. by
+// another adapter replica, is considered as created.
+func EnsureIndex(ctx context.Context, client Client, name string) error {
+	exists, err := IndexExists(ctx, client, name)
+	if err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("Failed to check if '%s' index exists", name), slog.Any("error", err))
+		return err
+	}
+	if exists {
+		logger.DebugContext(ctx, fmt.Sprintf("'%s' index already exists", name))
+		return nil
+	}
+	createRequest := opensearchapi.IndicesCreateRequest{
+		Index: name,
+	}
+	createResponse, err := createRequest.Do(ctx, client)
+	if err != nil {
+		logger.ErrorContext(ctx, fmt.Sprintf("Failed to create '%s' index", name), slog.Any("error", err))
+		return fmt.Errorf("failed to create '%s' index: %w", name, err)
+	}
+	defer createResponse.Body.Close()
+	if createResponse.StatusCode == http.StatusCreated || createResponse.StatusCode == http.StatusOK {
+		logger.DebugContext(ctx, fmt.Sprintf("'%s' index is created", name))
+		return nil
+	}
+	body, err := io.ReadAll(createResponse.Body)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to read from http response body", slog.String("error", err.Error()))
+		return err
+	}
+	if createResponse.StatusCode == http.StatusBadRequest && strings.Contains(string(body), "resource_already_exists_exception") {
+		logger.DebugContext(ctx, fmt.Sprintf("'%s' index is created concurrently", name))
+		return nil
+	}
+	logger.ErrorContext(ctx, fmt.Sprintf("%s index cannot be created because of error: [%d] %s", name,
+		createResponse.StatusCode, string(body)))
+	return fmt.Errorf("%s index cannot be created because of error: [%d]", name, createResponse.StatusCode)
+}
@@ -0,0 +1,87 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/opensearch-project/opensearch-go/opensearchtransport"
+	"github.com/stretchr/testify/assert"
+)
+
+// trackedBody records that response body is closed
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+// indexClient responds to index existence check and index creation with the given statuses and bodies
+type indexClient struct {
+	existsStatus int
+	createStatus int
+	createBody   string
+	bodies       []*trackedBody
+}
+
+func (c *indexClient) Perform(req *http.Request) (*http.Response, error) {
+	status, body := c.existsStatus, ""
+	if req.Method == http.MethodPut {
+		status, body = c.createStatus, c.createBody
+	}
+	responseBody := &trackedBody{Reader: strings.NewReader(body)}
+	c.bodies = append(c.bodies, responseBody)
+	return &http.Response{StatusCode: status, Body: responseBody}, nil
+}
+
+func (c *indexClient) Metrics() (opensearchtransport.Metrics, error) {
+	return opensearchtransport.Metrics{}, nil
+}
+
+func (c *indexClient) DiscoverNodes() error {
+	return nil
+}
+
+func TestEnsureIndex(t *testing.T) {
+	cases := []struct {
+		client   *indexClient
+		requests int
+		failed   bool
+	}{
+		{&indexClient{existsStatus: http.StatusOK}, 1, false},
+		{&indexClient{existsStatus: http.StatusNotFound, createStatus: http.StatusOK}, 2, false},
+		{&indexClient{existsStatus: http.StatusNotFound, createStatus: http.StatusBadRequest,
+			createBody: `{"error":{"type":"resource_already_exists_exception","reason":"index [jobs/uuid] already exists"},"status":400}`}, 2, false},
+		{&indexClient{existsStatus: http.StatusNotFound, createStatus: http.StatusBadRequest,
+			createBody: `{"error":{"type":"invalid_index_name_exception"},"status":400}`}, 2, true},
+		{&indexClient{existsStatus: http.StatusForbidden}, 1, true},
+	}
+	for _, c := range cases {
+		err := EnsureIndex(context.Background(), c.client, "jobs")
+		assert.Equal(t, c.failed, err != nil, c.client.createBody)
+		assert.Len(t, c.client.bodies, c.requests)
+		for _, body := range c.client.bodies {
+			assert.True(t, body.closed)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// TenantKey is the header of incoming request and the context key of the tenant which request is performed for
+	TenantKey = "Tenant"
+	// DbPrefixKey is the context key of the resource prefix of the database being operated on
+	DbPrefixKey = "dbPrefix"
+	// PrincipalKey is the context key of the authenticated client of adapter API
+	PrincipalKey = "principal"
+
+	LogFormatText = "text"
+	LogFormatJson = "json"
+
+	requestIdField = "request_id"
+	tenantField    = "tenant_id"
+	dbPrefixField  = "db_prefix"
+	principalField = "principal"
+)
+
+// WithDbPrefix returns context of the operation on the database with the given resource prefix,
+// so the prefix is added to all messages logged with the context
+func WithDbPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, DbPrefixKey, prefix)
+}
+
+// WithPrincipal returns context of the request performed by the authenticated principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, PrincipalKey, principal)
+}
+
+// GetLogger creates logger configured with LOG_FORMAT and LOG_LEVEL environment variables and makes it default
+func GetLogger() *slog.Logger {
+	logger := slog.New(NewLogHandler(os.Stdout, GetEnv("LOG_FORMAT", LogFormatText), GetLogLevel()))
+	slog.SetDefault(logger)
+	return logger
+}
+
+// GetLogLevel returns level configured with LOG_LEVEL environment variable (DEBUG, INFO, WARN or ERROR).
+// If it is not specified, presence of DEBUG environment variable enables DEBUG level, INFO level is used otherwise.
+func GetLogLevel() slog.Level {
+	if value, ok := os.LookupEnv("LOG_LEVEL"); ok {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err == nil {
+			return level
+		}
+	}
+	if _, ok := os.LookupEnv("DEBUG"); ok {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// NewLogHandler creates handler writing messages of the given level and above in the format configured
+// with LOG_FORMAT environment variable: "text" (default) or "json"
+func NewLogHandler(out io.Writer, format string, level slog.Leveler) slog.Handler {
+	if strings.EqualFold(format, LogFormatJson) {
+		return &contextHandler{Handler: slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})}
+	}
+	return &CustomLogHandler{out: out, level: level, mutex: &sync.Mutex{}}
+}
+
+// contextHandler adds request ID, tenant and database prefix from the context to attributes of each message
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.AddAttrs(contextAttrs(ctx, true)...)
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// contextAttrs returns request ID, tenant, principal and database prefix stored in the context, fields missing
+// in the context are skipped
+func contextAttrs(ctx context.Context, withRequestFields bool) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	var attrs []slog.Attr
+	if withRequestFields {
+		if requestId := GetCtxStringValue(ctx, RequestIdKey); requestId != "" {
+			attrs = append(attrs, slog.String(requestIdField, requestId))
+		}
+		if tenant := GetCtxStringValue(ctx, TenantKey); tenant != "" {
+			attrs = append(attrs, slog.String(tenantField, tenant))
+		}
+	}
+	if principal := GetCtxStringValue(ctx, PrincipalKey); principal != "" {
+		attrs = append(attrs, slog.String(principalField, principal))
+	}
+	if prefix := GetCtxStringValue(ctx, DbPrefixKey); prefix != "" {
+		attrs = append(attrs, slog.String(dbPrefixField, prefix))
+	}
+	return attrs
+}
+
+// CustomLogHandler writes messages in the fixed text format
+// "[time] [level] [request_id=...] [tenant_id=...] [thread= ] [class= ] message key=value ...",
+// attributes of the message and database prefix from the context follow the message.
+type CustomLogHandler struct {
+	out   io.Writer
+	level slog.Leveler
+	mutex *sync.Mutex
+	// attrs are attributes added by WithAttrs already formatted as " key=value" pairs
+	attrs string
+	// group is the prefix of keys of attributes added after WithGroup
+	group string
+}
+
+func (h *CustomLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *CustomLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(record.Time.Format("[2006-01-02T15:04:05.999]"))
+	buffer.WriteString(" [" + record.Level.String() + "]")
+	buffer.WriteString(" [request_id=" + valueOrSpace(GetCtxStringValue(ctx, RequestIdKey)) + "]")
+	buffer.WriteString(" [tenant_id=" + valueOrSpace(GetCtxStringValue(ctx, TenantKey)) + "]")
+	buffer.WriteString(" [thread= ] [class= ] ")
+	buffer.WriteString(record.Message)
+	buffer.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		appendAttr(buffer, h.group, attr)
+		return true
+	})
+	for _, attr := range contextAttrs(ctx, false) {
+		appendAttr(buffer, "", attr)
+	}
+	buffer.WriteByte('\n')
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	_, err := h.out.Write(buffer.Bytes())
+	return err
+}
+
+func (h *CustomLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	buffer := bytes.NewBufferString(h.attrs)
+	for _, attr := range attrs {
+		appendAttr(buffer, h.group, attr)
+	}
+	handler := *h
+	handler.attrs = buffer.String()
+	return &handler
+}
+
+func (h *CustomLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.group = h.group + name + "."
+	return &handler
+}
+
+func appendAttr(buffer *bytes.Buffer, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		prefix := group
+		if attr.Key != "" {
+			prefix = group + attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			appendAttr(buffer, prefix, groupAttr)
+		}
+		return
+	}
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " =\"\n\t") {
+		value = strconv.Quote(value)
+	}
+	buffer.WriteString(" " + group + attr.Key + "=" + value)
+}
+
+func valueOrSpace(value string) string {
+	if value == "" {
+		return " "
+	}
+	return value
+}
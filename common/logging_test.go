@@ -0,0 +1,69 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextLogKeepsAttributes(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set(RequestIdKey, "42")
+	request.Header.Set(TenantKey, "tenant-a")
+	ctx := WithDbPrefix(PrepareContext(request), "dbaas_test")
+	out := &bytes.Buffer{}
+	logger := slog.New(NewLogHandler(out, LogFormatText, slog.LevelInfo))
+
+	logger.With(slog.String("component", "backup")).WithGroup("curator").
+		ErrorContext(ctx, "Failed to collect backup", slog.Any("error", errors.New("connection refused")), slog.Int("attempt", 3))
+	logger.DebugContext(ctx, "Debug message is skipped")
+
+	assert.Regexp(t, `^\[\S+\] \[ERROR\] \[request_id=42\] \[tenant_id=tenant-a\] \[thread= \] \[class= \] `+
+		`Failed to collect backup component=backup curator.error="connection refused" curator.attempt=3 db_prefix=dbaas_test\n$`, out.String())
+}
+
+func TestJsonLogContainsContextFields(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set(RequestIdKey, "42")
+	ctx := WithDbPrefix(PrepareContext(request), "dbaas_test")
+	out := &bytes.Buffer{}
+	logger := slog.New(NewLogHandler(out, LogFormatJson, slog.LevelDebug))
+
+	logger.DebugContext(ctx, "Creating user", slog.String("error", "timeout"))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "DEBUG", entry["level"])
+	assert.Equal(t, "Creating user", entry["msg"])
+	assert.Equal(t, "timeout", entry["error"])
+	assert.Equal(t, "42", entry[requestIdField])
+	assert.Equal(t, "dbaas_test", entry[dbPrefixField])
+	assert.NotContains(t, entry, tenantField)
+}
+
+func TestGetLogLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+	assert.Equal(t, slog.LevelWarn, GetLogLevel())
+	t.Setenv("LOG_LEVEL", "unknown")
+	t.Setenv("DEBUG", "")
+	assert.Equal(t, slog.LevelDebug, GetLogLevel())
+}
@@ -20,7 +20,10 @@ import (
 	"github.com/opensearch-project/opensearch-go/opensearchtransport"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 type Client interface {
@@ -30,12 +33,18 @@ type Client interface {
 }
 
 type ClientStub struct {
+	// documents keeps documents of adapter system indices, so they can be read after they are written,
+	// seqNos keeps sequence numbers of their last changes
+	documents map[string]string
+	seqNos    map[string]int
+	seqNo     int
+	mutex     sync.Mutex
 }
 
 type TransportStub struct{}
 
 func NewClient() *ClientStub {
-	return &ClientStub{}
+	return &ClientStub{documents: make(map[string]string), seqNos: make(map[string]int)}
 }
 
 func (cs *ClientStub) Perform(req *http.Request) (*http.Response, error) {
@@ -47,6 +56,10 @@ func (cs *ClientStub) Perform(req *http.Request) (*http.Response, error) {
 	case strings.HasPrefix(path, "/dbaas_opensearch_metadata/_doc"):
 		index := strings.ReplaceAll(path, "/dbaas_opensearch_metadata/_doc", "")
 		body = cs.metadataManipulations(index, method)
+	case strings.HasPrefix(path, "/dbaas_opensearch_") && strings.Contains(path, "/_doc/"):
+		body, statusCode = cs.documentManipulations(path, method, req.URL.Query(), req.Body)
+	case strings.HasPrefix(path, "/dbaas_opensearch_") && strings.HasSuffix(path, "/_search"):
+		body = cs.searchDocuments(strings.TrimSuffix(path, "_search"))
 	case strings.HasPrefix(path, "/_plugins/_security/api/roles/"):
 		role := strings.ReplaceAll(path, "/_plugins/_security/api/roles/", "")
 		body = cs.roleManipulations(role, method)
@@ -69,6 +82,21 @@ func (cs *ClientStub) Perform(req *http.Request) (*http.Response, error) {
 		body = cs.aliasManipulations(alias, method)
 	case strings.Contains(path, "/_snapshot/snapshots/_verify"):
 		body = "{\"status\": 200}"
+	case strings.HasPrefix(path, "/_snapshot/") && strings.HasSuffix(path, "/_verify"):
+		body = `{"nodes":{"ddfIN7-sT3avYl4DFZfKeg":{"name":"opensearch-1"}}}`
+	case strings.HasPrefix(path, "/_snapshot/") && strings.HasSuffix(path, "/_status"):
+		snapshot := strings.Split(path, "/")[3]
+		body = fmt.Sprintf(`{"snapshots":[{"snapshot":"%s","repository":"snapshots","state":"SUCCESS","shards_stats":{"done":2,"failed":0,"total":2},"stats":{"incremental":{"file_count":4,"size_in_bytes":2048},"processed":{"file_count":4,"size_in_bytes":2048},"total":{"file_count":6,"size_in_bytes":4096}},"indices":{"db1test":{"shards_stats":{"done":2,"failed":0,"total":2},"stats":{"incremental":{"file_count":4,"size_in_bytes":2048},"processed":{"file_count":4,"size_in_bytes":2048},"total":{"file_count":6,"size_in_bytes":4096}}}}}]}`, snapshot)
+	case strings.HasPrefix(path, "/_snapshot/") && strings.HasSuffix(path, "/_restore"):
+		body = `{"snapshot":{"shards":{"total":1,"failed":0,"successful":1}}}`
+	case path == "/_snapshot" || strings.HasPrefix(path, "/_snapshot/"):
+		body, statusCode = cs.snapshotManipulations(strings.Trim(strings.TrimPrefix(path, "/_snapshot"), "/"), method)
+	case strings.HasSuffix(path, "/_close"):
+		body = `{"acknowledged":true,"shards_acknowledged":true,"indices":{}}`
+	case strings.HasSuffix(path, "/_stats/docs"):
+		body = cs.indicesStats(strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/_stats/docs"))
+	case strings.HasSuffix(path, "/_mapping"):
+		body = cs.indicesMappings(strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/_mapping"))
 	case strings.HasPrefix(path, "/_cat/indices"):
 		body = `dbaas_metadata
 dbaas_opensearch_metadata
@@ -107,6 +135,107 @@ func (cs *ClientStub) metadataManipulations(index string, method string) string
 	}
 }
 
+// documentManipulations stores documents with sequence numbers, changes with if_seq_no which does not match
+// the sequence number of the stored document are rejected with conflict as well as creation of existing document
+func (cs *ClientStub) documentManipulations(path string, method string, query url.Values, requestBody io.Reader) (string, int) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	if cs.documents == nil {
+		cs.documents = make(map[string]string)
+		cs.seqNos = make(map[string]int)
+	}
+	id := path[strings.LastIndex(path, "/")+1:]
+	conflict := fmt.Sprintf(`{"error":{"type":"version_conflict_engine_exception","reason":"[%s]: version conflict"},"status":409}`, id)
+	_, exists := cs.documents[path]
+	if ifSeqNo := query.Get("if_seq_no"); ifSeqNo != "" && (!exists || ifSeqNo != strconv.Itoa(cs.seqNos[path])) {
+		return conflict, http.StatusConflict
+	}
+	switch method {
+	case http.MethodGet:
+		if !exists {
+			return fmt.Sprintf(`{"_id":"%s","found":false}`, id), http.StatusNotFound
+		}
+		return fmt.Sprintf(`{"_id":"%s","found":true,"_seq_no":%d,"_primary_term":1,"_source":%s}`,
+			id, cs.seqNos[path], cs.documents[path]), http.StatusOK
+	case http.MethodPut, http.MethodPost:
+		document, err := io.ReadAll(requestBody)
+		if err != nil {
+			return err.Error(), http.StatusBadRequest
+		}
+		if exists && query.Get("op_type") == "create" {
+			return conflict, http.StatusConflict
+		}
+		cs.seqNo++
+		cs.documents[path] = string(document)
+		cs.seqNos[path] = cs.seqNo
+		return fmt.Sprintf(`{"_id":"%s","result":"created","_seq_no":%d,"_primary_term":1}`, id, cs.seqNo), http.StatusCreated
+	case http.MethodDelete:
+		delete(cs.documents, path)
+		delete(cs.seqNos, path)
+		return fmt.Sprintf(`{"_id":"%s","result":"deleted"}`, id), http.StatusOK
+	default:
+		logger.Error(fmt.Sprintf("Document operations do not include '%s' method", method))
+		return "", http.StatusMethodNotAllowed
+	}
+}
+
+// snapshotManipulations processes requests to snapshot repositories and snapshots, 'missing' repository does not exist
+func (cs *ClientStub) snapshotManipulations(name string, method string) (string, int) {
+	repository := strings.Split(name, "/")[0]
+	if repository == "missing" {
+		return fmt.Sprintf(`{"error":{"type":"repository_missing_exception","reason":"[%s] missing"},"status":404}`, repository),
+			http.StatusNotFound
+	}
+	switch method {
+	case http.MethodGet:
+		return `{"snapshots":{"type":"fs","settings":{"location":"/usr/share/opensearch/snapshots"}},"tenant":{"type":"s3","settings":{"bucket":"tenant-bucket"}}}`, http.StatusOK
+	case http.MethodPut, http.MethodPost:
+		if strings.Contains(name, "/") {
+			return `{"accepted":true}`, http.StatusOK
+		}
+		return `{"acknowledged":true}`, http.StatusOK
+	case http.MethodDelete:
+		return `{"acknowledged":true}`, http.StatusOK
+	default:
+		logger.Error(fmt.Sprintf("Snapshot operations do not include '%s' method", method))
+		return "", http.StatusMethodNotAllowed
+	}
+}
+
+// searchDocuments returns all stored documents of the index, search query is not applied
+func (cs *ClientStub) searchDocuments(indexPath string) string {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	var hits []string
+	for path, document := range cs.documents {
+		if strings.HasPrefix(path, indexPath+"_doc/") {
+			id := path[strings.LastIndex(path, "/")+1:]
+			hits = append(hits, fmt.Sprintf(`{"_id":"%s","_seq_no":%d,"_primary_term":1,"_source":%s}`, id, cs.seqNos[path], document))
+		}
+	}
+	return fmt.Sprintf(`{"hits":{"total":{"value":%d},"hits":[%s]}}`, len(hits), strings.Join(hits, ","))
+}
+
+// indicesStats returns the same documents count for each requested index, wildcards are resolved to 'test' suffix
+func (cs *ClientStub) indicesStats(indices string) string {
+	var stats []string
+	for _, index := range strings.Split(indices, ",") {
+		stats = append(stats, fmt.Sprintf(`"%s":{"primaries":{"docs":{"count":10,"deleted":0}}}`,
+			strings.ReplaceAll(index, "*", "test")))
+	}
+	return fmt.Sprintf(`{"indices":{%s}}`, strings.Join(stats, ","))
+}
+
+// indicesMappings returns the same mapping for each requested index, wildcards are resolved to 'test' suffix
+func (cs *ClientStub) indicesMappings(indices string) string {
+	var mappings []string
+	for _, index := range strings.Split(indices, ",") {
+		mappings = append(mappings, fmt.Sprintf(`"%s":{"mappings":{"properties":{"name":{"type":"keyword"}}}}`,
+			strings.ReplaceAll(index, "*", "test")))
+	}
+	return fmt.Sprintf(`{%s}`, strings.Join(mappings, ","))
+}
+
 func (cs *ClientStub) roleManipulations(name string, method string) string {
 	switch method {
 	case http.MethodGet:
@@ -229,6 +358,9 @@ func (c *TransportStub) RoundTrip(req *http.Request) (*http.Response, error) {
 		body = "20240322T091826"
 	case strings.HasSuffix(path, "restore"):
 		body = "200"
+	case strings.Contains(path, "/jobstatus/"):
+		vault := path[strings.LastIndex(path, "/")+1:]
+		body = fmt.Sprintf(`{"status":"Successful","vault":"%s","type":"restore","trackPath":"%s"}`, vault, vault)
 	default:
 		return nil, fmt.Errorf("there is no option for '%s' path", path)
 	}
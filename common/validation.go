@@ -0,0 +1,190 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxIndexNameLength is the maximum length of OpenSearch index name in bytes
+	MaxIndexNameLength = 255
+	// MaxPrefixLength is the maximum length of resource prefix, the same limit is used for generated prefixes
+	MaxPrefixLength = 64
+
+	// IndexNameForbiddenChars are characters which OpenSearch does not allow in index names
+	IndexNameForbiddenChars = `\/*?"<>| ,#:`
+
+	// SystemIndexPrefix is the prefix of names of adapter's own system indices, resource prefixes which would
+	// grant access to them are not allowed
+	SystemIndexPrefix = "dbaas_opensearch_"
+)
+
+// ResourceKinds are kinds of resources which are created by adapter and can be dropped
+var ResourceKinds = []string{AliasKind, IndexKind, MetadataKind, ResourcePrefixKind, TemplateKind, IndexTemplateKind, UserKind}
+
+// rejectUnknownFields makes requests with unknown fields invalid, by default they are ignored, so fields added
+// to requests by new versions of DBaaS aggregator do not break the adapter
+var rejectUnknownFields = GetEnv("REJECT_UNKNOWN_REQUEST_FIELDS", "false") == "true"
+
+// FieldError describes invalid field of the request, Field is the path to the field, e.g. "settings.createOnly[1]",
+// empty Field refers to the whole request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validator collects errors of request fields, so all of them are reported to client at once
+type Validator struct {
+	errors []FieldError
+}
+
+// Add reports error of the field
+func (v *Validator) Add(field string, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: message})
+}
+
+// Check reports error of the field if err is not nil
+func (v *Validator) Check(field string, err error) {
+	if err != nil {
+		v.Add(field, err.Error())
+	}
+}
+
+// Required reports error if value of the field is empty
+func (v *Validator) Required(field string, value string) {
+	if value == "" {
+		v.Add(field, "must be specified")
+	}
+}
+
+// OneOf reports error if value of the field is not one of allowed values
+func (v *Validator) OneOf(field string, value string, allowed []string) {
+	if !slices.Contains(allowed, value) {
+		v.Add(field, fmt.Sprintf("must be one of %s, but '%s' is specified", strings.Join(allowed, ", "), value))
+	}
+}
+
+// Err returns VALIDATION_FAILED error with reported field errors in details, nil is returned if there are no errors
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	messages := make([]string, len(v.errors))
+	for i, fieldError := range v.errors {
+		messages[i] = fieldError.Message
+		if fieldError.Field != "" {
+			messages[i] = fmt.Sprintf("'%s' %s", fieldError.Field, fieldError.Message)
+		}
+	}
+	return &Error{
+		Kind:    ErrValidation,
+		Message: "request is invalid: " + strings.Join(messages, "; "),
+		Details: v.errors,
+	}
+}
+
+// DecodeJson decodes request body into v. Values of wrong types are reported as VALIDATION_FAILED errors for the
+// corresponding fields. Unknown fields are logged and ignored, they are reported as VALIDATION_FAILED errors
+// when REJECT_UNKNOWN_REQUEST_FIELDS is "true".
+func DecodeJson(body io.Reader, v interface{}) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return DecodeError(err)
+	}
+	err = decodeJson(data, v, rejectUnknownFields)
+	if err == nil && !rejectUnknownFields {
+		logUnknownField(data, v)
+	}
+	if err == nil {
+		return nil
+	}
+	var validator Validator
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeError) && typeError.Field != "":
+		validator.Add(typeError.Field, fmt.Sprintf("must be %s, but %s is specified", typeError.Type, typeError.Value))
+	case unknownFieldOf(err) != "":
+		validator.Add(unknownFieldOf(err), "unknown field")
+	default:
+		return DecodeError(err)
+	}
+	return validator.Err()
+}
+
+func decodeJson(data []byte, v interface{}, strict bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
+
+// logUnknownField decodes data into a new value of the same type as v to find the first unknown field
+func logUnknownField(data []byte, v interface{}) {
+	valueType := reflect.TypeOf(v)
+	if valueType == nil || valueType.Kind() != reflect.Pointer {
+		return
+	}
+	if field := unknownFieldOf(decodeJson(data, reflect.New(valueType.Elem()).Interface(), true)); field != "" {
+		logger.Warn(fmt.Sprintf("Request contains unknown field '%s', unknown fields are ignored", field))
+	}
+}
+
+// unknownFieldOf returns the name of unknown field reported by decoder, empty string is returned for other errors
+func unknownFieldOf(err error) string {
+	if err == nil || !strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return ""
+	}
+	return strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+}
+
+// ValidateIndexName checks that name satisfies naming rules of OpenSearch indices and is not longer than maxLength
+func ValidateIndexName(name string, maxLength int) error {
+	switch {
+	case name == "":
+		return errors.New("must not be empty")
+	case len(name) > maxLength:
+		return fmt.Errorf("must not be longer than %d bytes", maxLength)
+	case name == "." || name == "..":
+		return fmt.Errorf("must not be '%s'", name)
+	case strings.ToLower(name) != name:
+		return errors.New("must be lowercase")
+	case strings.ContainsAny(name[:1], "-_+."):
+		return errors.New("must not start with '-', '_', '+' or '.'")
+	case strings.ContainsAny(name, IndexNameForbiddenChars):
+		return fmt.Errorf("must not contain any of '%s' characters", IndexNameForbiddenChars)
+	}
+	return nil
+}
+
+// ValidatePrefix checks that resource prefix can be used as the prefix of OpenSearch indices and does not match
+// system indices of adapter, because users are granted access to all indices starting with their prefix
+func ValidatePrefix(prefix string) error {
+	if err := ValidateIndexName(prefix, MaxPrefixLength); err != nil {
+		return err
+	}
+	if strings.HasPrefix(SystemIndexPrefix, prefix) || strings.HasPrefix(prefix, SystemIndexPrefix) {
+		return fmt.Errorf("must not match system indices starting with '%s'", SystemIndexPrefix)
+	}
+	return nil
+}
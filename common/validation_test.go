@@ -0,0 +1,87 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateIndexName(t *testing.T) {
+	assert.Nil(t, ValidateIndexName("dbaas_test-1", MaxIndexNameLength))
+	for _, name := range []string{"", ".", "Dbaas", "_dbaas", "-dbaas", "db aas", "db*", "db:1", `db\1`, strings.Repeat("a", 256)} {
+		assert.NotNil(t, ValidateIndexName(name, MaxIndexNameLength), name)
+	}
+	assert.NotNil(t, ValidatePrefix(strings.Repeat("a", MaxPrefixLength+1)))
+}
+
+func TestValidatePrefixRejectsSystemIndices(t *testing.T) {
+	for _, prefix := range []string{"d", "dbaas", "dbaas_", "dbaas_opensearch", "dbaas_opensearch_jobs", IdempotencyIndex} {
+		assert.NotNil(t, ValidatePrefix(prefix), prefix)
+	}
+	for _, prefix := range []string{"dbaas_orders", "dbaasorders", "orders"} {
+		assert.Nil(t, ValidatePrefix(prefix), prefix)
+	}
+}
+
+func TestValidatorReportsAllFields(t *testing.T) {
+	var validator Validator
+	assert.Nil(t, validator.Err())
+
+	validator.Required("username", "")
+	validator.OneOf("role", "owner", []string{"admin", "dml"})
+	validator.Check("namePrefix", ValidatePrefix("Test"))
+	err := validator.Err()
+
+	assert.ErrorIs(t, err, ErrValidation)
+	var apiError *Error
+	assert.True(t, errors.As(err, &apiError))
+	assert.Equal(t, []FieldError{
+		{Field: "username", Message: "must be specified"},
+		{Field: "role", Message: "must be one of admin, dml, but 'owner' is specified"},
+		{Field: "namePrefix", Message: "must be lowercase"},
+	}, apiError.Details)
+}
+
+func TestDecodeJson(t *testing.T) {
+	type request struct {
+		Name     string `json:"name"`
+		Settings struct {
+			ResourcePrefix bool `json:"resourcePrefix"`
+		} `json:"settings"`
+	}
+	var value request
+	assert.Nil(t, DecodeJson(strings.NewReader(`{"name":"test","settings":{"resourcePrefix":true}}`), &value))
+	assert.True(t, value.Settings.ResourcePrefix)
+
+	assert.Nil(t, DecodeJson(strings.NewReader(`{"name":"owned","owner":"test"}`), &value))
+	assert.Equal(t, "owned", value.Name)
+
+	rejectUnknownFields = true
+	err := DecodeJson(strings.NewReader(`{"name":"test","owner":"test"}`), &value)
+	rejectUnknownFields = false
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Equal(t, []FieldError{{Field: "owner", Message: "unknown field"}}, err.(*Error).Details)
+
+	err = DecodeJson(strings.NewReader(`{"settings":{"resourcePrefix":"yes"}}`), &value)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Equal(t, "settings.resourcePrefix", err.(*Error).Details.([]FieldError)[0].Field)
+
+	err = DecodeJson(strings.NewReader(`{"name":`), &value)
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
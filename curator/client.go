@@ -0,0 +1,313 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/metrics"
+	"github.com/Netcracker/dbaas-opensearch-adapter/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var logger = common.GetLogger()
+
+const (
+	defaultAttempts = 3
+	defaultBackoff  = 500 * time.Millisecond
+
+	// Job states returned by Curator
+	QueuedState     = "Queued"
+	ProcessingState = "Processing"
+	SuccessfulState = "Successful"
+	FailedState     = "Failed"
+)
+
+var (
+	ErrNotFound    = common.NewError(common.ErrNotFound, "backup not found")
+	ErrUnavailable = common.NewError(common.ErrUpstreamUnavailable, "curator return internal server error")
+)
+
+// Error is returned when Curator responds with unexpected status code. It matches ErrNotFound for 404 status
+// and ErrUnavailable for 5xx statuses with errors.Is.
+type Error struct {
+	Operation  string
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("curator failed to %s, status code is %d: %s", e.Operation, e.StatusCode, e.Body)
+}
+
+func (e *Error) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+type BackupRequest struct {
+	AllowEviction string   `json:"allow_eviction"`
+	Dbs           []string `json:"dbs,omitempty"`
+}
+
+// RestoreRequest describes restoration of databases from the vault (backup). Databases can be renamed either by
+// RenamePattern and RenameReplacement applied to all indices or by ChangeDbNames mapping of old names to new ones.
+type RestoreRequest struct {
+	Vault             string            `json:"vault"`
+	SkipUsersRecovery string            `json:"skip_users_recovery"`
+	Dbs               []string          `json:"dbs"`
+	RenamePattern     string            `json:"rename_pattern,omitempty"`
+	RenameReplacement string            `json:"rename_replacement,omitempty"`
+	ChangeDbNames     map[string]string `json:"changeDbNames,omitempty"`
+}
+
+type JobStatus struct {
+	State   string `json:"status"`
+	Message string `json:"details,omitempty"`
+	Vault   string `json:"vault"`
+	Type    string `json:"type"`
+	Error   string `json:"err,omitempty"`
+	TaskId  string `json:"trackPath"`
+}
+
+type EvictResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Client performs requests to Curator. GET requests failed with 5xx status code or because of connection errors
+// are retried with exponential backoff. Other requests start backups, restorations and evictions, so they are
+// retried only if connection is not established and Curator has not received the request.
+type Client struct {
+	url        string
+	username   string
+	password   string
+	httpClient *http.Client
+	attempts   int
+	backoff    time.Duration
+}
+
+func NewClient(url string, username string, password string, httpClient *http.Client) *Client {
+	return &Client{
+		url:        strings.TrimSuffix(url, "/"),
+		username:   username,
+		password:   password,
+		httpClient: httpClient,
+		attempts:   defaultAttempts,
+		backoff:    defaultBackoff,
+	}
+}
+
+// SetRetryPolicy changes the number of attempts to perform request and the delay before the first retry,
+// the delay is doubled for each next retry.
+func (c *Client) SetRetryPolicy(attempts int, backoff time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	c.attempts = attempts
+	c.backoff = backoff
+}
+
+// Backup requests Curator to collect backup of the databases, all databases are backed up if none is specified.
+// Identifier of the backup is returned.
+func (c *Client) Backup(ctx context.Context, request BackupRequest) (string, error) {
+	body, err := c.do(ctx, "collect backup", http.MethodPost, "backup", request)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// Evict requests Curator to remove the backup.
+func (c *Client) Evict(ctx context.Context, backupID string) (EvictResponse, error) {
+	response, err := c.perform(ctx, "evict backup", http.MethodPost, "evict/"+url.PathEscape(backupID), nil)
+	if err != nil {
+		return EvictResponse{}, err
+	}
+	return EvictResponse{StatusCode: response.statusCode, Body: response.body}, nil
+}
+
+// Restore requests Curator to restore databases from the backup and returns identifier to track restoration.
+func (c *Client) Restore(ctx context.Context, request RestoreRequest) (string, error) {
+	body, err := c.do(ctx, "restore backup", http.MethodPost, "restore", request)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// JobStatus returns status of backup or restoration job.
+func (c *Client) JobStatus(ctx context.Context, jobID string) (JobStatus, error) {
+	body, err := c.do(ctx, "receive job status", http.MethodGet, "jobstatus/"+url.PathEscape(jobID), nil)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	var status JobStatus
+	if err = json.Unmarshal(body, &status); err != nil {
+		return JobStatus{}, fmt.Errorf("failed to decode response from JSON: %w", err)
+	}
+	return status, nil
+}
+
+// Health checks that Curator is available by its health endpoint, request is not retried
+func (c *Client) Health(ctx context.Context) error {
+	result, err := c.send(ctx, http.MethodGet, "health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to check curator health: %w", err)
+	}
+	if result.statusCode >= http.StatusBadRequest {
+		return &Error{Operation: "check health", StatusCode: result.statusCode, Body: string(result.body)}
+	}
+	return nil
+}
+
+// Configured returns true if Curator address is specified
+func (c *Client) Configured() bool {
+	return c.url != ""
+}
+
+type response struct {
+	statusCode int
+	body       []byte
+}
+
+// do performs request and returns response body if response status code is successful
+func (c *Client) do(ctx context.Context, operation string, method string, path string, request interface{}) ([]byte, error) {
+	response, err := c.perform(ctx, operation, method, path, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.body, nil
+}
+
+func (c *Client) perform(ctx context.Context, operation string, method string, path string, request interface{}) (response, error) {
+	ctx, span := tracing.Start(ctx, "curator "+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("http.request.method", method), attribute.String("url.path", path)))
+	result, err := c.performWithRetries(ctx, operation, method, path, request)
+	tracing.End(span, err)
+	var curatorErr *Error
+	switch {
+	case err == nil:
+		metrics.ObserveCuratorRequest(operation, metrics.OutcomeSuccess)
+	case errors.As(err, &curatorErr) && curatorErr.StatusCode < http.StatusInternalServerError:
+		metrics.ObserveCuratorRequest(operation, metrics.OutcomeRejected)
+	default:
+		metrics.ObserveCuratorRequest(operation, metrics.OutcomeFailed)
+	}
+	return result, err
+}
+
+func (c *Client) performWithRetries(ctx context.Context, operation string, method string, path string, request interface{}) (response, error) {
+	var body []byte
+	if request != nil {
+		var err error
+		if body, err = json.Marshal(request); err != nil {
+			return response{}, fmt.Errorf("failed to prepare request to %s: %w", operation, err)
+		}
+	}
+	backoff := c.backoff
+	var lastErr error
+	for attempt := 1; attempt <= c.attempts; attempt++ {
+		if attempt > 1 {
+			logger.WarnContext(ctx, fmt.Sprintf("Retrying request to %s in %s, attempt %d/%d", operation, backoff, attempt, c.attempts),
+				slog.Any("error", lastErr))
+			select {
+			case <-ctx.Done():
+				return response{}, fmt.Errorf("failed to %s: %w", operation, ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		var result response
+		result, lastErr = c.send(ctx, method, path, body)
+		if lastErr != nil {
+			if ctx.Err() != nil || method != http.MethodGet && !isDialError(lastErr) {
+				break
+			}
+			continue
+		}
+		if result.statusCode >= http.StatusInternalServerError {
+			lastErr = &Error{Operation: operation, StatusCode: result.statusCode, Body: string(result.body)}
+			if method != http.MethodGet {
+				return response{}, lastErr
+			}
+			continue
+		}
+		if result.statusCode >= http.StatusBadRequest {
+			return response{}, &Error{Operation: operation, StatusCode: result.statusCode, Body: string(result.body)}
+		}
+		return result, nil
+	}
+	if ctx.Err() != nil {
+		return response{}, fmt.Errorf("failed to %s: %w", operation, lastErr)
+	}
+	if !errors.As(lastErr, new(*Error)) {
+		lastErr = &common.Error{Kind: common.ErrUpstreamUnavailable, Message: "failed to " + operation, Cause: lastErr}
+	}
+	return response{}, lastErr
+}
+
+// isDialError checks that connection to Curator is not established, so the request is not sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, body []byte) (response, error) {
+	var requestBody io.Reader
+	if body != nil {
+		requestBody = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s", c.url, path), requestBody)
+	if err != nil {
+		return response{}, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set(common.RequestIdKey, common.GetCtxStringValue(ctx, common.RequestIdKey))
+	tracing.Inject(ctx, request.Header)
+	request.SetBasicAuth(c.username, c.password)
+	logger.DebugContext(ctx, fmt.Sprintf("Sending %s request to Curator '%s' path: %s", method, path, body))
+	httpResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return response{}, err
+	}
+	defer httpResponse.Body.Close()
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return response{}, err
+	}
+	return response{statusCode: httpResponse.StatusCode, body: responseBody}, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(server *FakeServer) *Client {
+	client := NewClient(server.URL, "curator", "password", server.Client())
+	client.SetRetryPolicy(3, time.Millisecond)
+	return client
+}
+
+func TestBackupEscapesDatabaseNames(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	client := newTestClient(server)
+
+	backupID, err := client.Backup(context.Background(), BackupRequest{AllowEviction: "False", Dbs: []string{`db"1`, "db2"}})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, backupID)
+	assert.Equal(t, []string{`db"1`, "db2"}, server.Backups[0].Dbs)
+
+	status, err := client.JobStatus(context.Background(), backupID)
+	assert.Nil(t, err)
+	assert.Equal(t, SuccessfulState, status.State)
+}
+
+func TestRetryOnUnavailableCurator(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	client := newTestClient(server)
+	backupID, err := client.Backup(context.Background(), BackupRequest{AllowEviction: "False"})
+	assert.Nil(t, err)
+
+	server.FailNext(2)
+	_, err = client.JobStatus(context.Background(), backupID)
+	assert.Nil(t, err)
+
+	server.FailNext(3)
+	_, err = client.JobStatus(context.Background(), backupID)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	var curatorErr *Error
+	assert.True(t, errors.As(err, &curatorErr))
+	assert.Equal(t, http.StatusServiceUnavailable, curatorErr.StatusCode)
+}
+
+func TestUnavailableCuratorIsNotRetriedOnPost(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	client := newTestClient(server)
+	backupID, err := client.Backup(context.Background(), BackupRequest{AllowEviction: "False", Dbs: []string{"db1"}})
+	assert.Nil(t, err)
+
+	server.FailNext(2)
+	_, err = client.Restore(context.Background(), RestoreRequest{Vault: backupID, Dbs: []string{"db1"}})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Empty(t, server.Restores)
+	// the second failure is not consumed by retry of restoration
+	_, err = client.Backup(context.Background(), BackupRequest{AllowEviction: "False"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+// roundTripFunc fails requests without sending them
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestPostIsRetriedOnDialError(t *testing.T) {
+	attempts := 0
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	})}
+	client := NewClient("http://curator:8080", "curator", "password", httpClient)
+	client.SetRetryPolicy(3, time.Millisecond)
+
+	_, err := client.Restore(context.Background(), RestoreRequest{Vault: "20240322T091826", Dbs: []string{"db1"}})
+	assert.ErrorIs(t, err, common.ErrUpstreamUnavailable)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	})
+	_, err = client.Restore(context.Background(), RestoreRequest{Vault: "20240322T091826", Dbs: []string{"db1"}})
+	assert.ErrorIs(t, err, common.ErrUpstreamUnavailable)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestNotFoundIsNotRetried(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	client := newTestClient(server)
+
+	_, err := client.Restore(context.Background(), RestoreRequest{Vault: "unknown", Dbs: []string{"db1"}})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.Evict(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRestoreAndEvict(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	client := newTestClient(server)
+	ctx := context.Background()
+
+	backupID, err := client.Backup(ctx, BackupRequest{AllowEviction: "False", Dbs: []string{"db1"}})
+	assert.Nil(t, err)
+	trackID, err := client.Restore(ctx, RestoreRequest{
+		Vault:         backupID,
+		Dbs:           []string{"db1"},
+		ChangeDbNames: map[string]string{"db1": "db1_restored"},
+	})
+	assert.Nil(t, err)
+	assert.NotEqual(t, backupID, trackID)
+	assert.Equal(t, map[string]string{"db1": "db1_restored"}, server.Restores[0].ChangeDbNames)
+
+	response, err := client.Evict(ctx, backupID)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []string{backupID}, server.Evicted)
+}
+
+func TestRetryStopsOnContextCancel(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	client := NewClient(server.URL, "curator", "password", server.Client())
+	client.SetRetryPolicy(5, time.Hour)
+
+	server.FailNext(5)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.JobStatus(ctx, "20240322T091826")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
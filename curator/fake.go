@@ -0,0 +1,125 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package curator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// FakeServer imitates Curator API for tests. Backups and restorations are completed immediately with
+// SuccessfulState unless another state is set by SetState.
+type FakeServer struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	counter  int
+	failures int
+	jobs     map[string]JobStatus
+	Backups  []BackupRequest
+	Restores []RestoreRequest
+	Evicted  []string
+}
+
+func NewFakeServer() *FakeServer {
+	fake := &FakeServer{jobs: make(map[string]JobStatus)}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	return fake
+}
+
+// FailNext makes the server respond with 503 status to the next count requests.
+func (f *FakeServer) FailNext(count int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failures = count
+}
+
+// SetState changes state of the job, message and error are returned in job status as is.
+func (f *FakeServer) SetState(jobID string, state string, message string, jobError string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	job := f.jobs[jobID]
+	job.State = state
+	job.Message = message
+	job.Error = jobError
+	job.Vault = jobID
+	f.jobs[jobID] = job
+}
+
+func (f *FakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		http.Error(w, "curator is not available", http.StatusServiceUnavailable)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodPost && path == "backup":
+		var request BackupRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.Backups = append(f.Backups, request)
+		f.writeJob(w, "backup")
+	case r.Method == http.MethodPost && path == "restore":
+		var request RestoreRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := f.jobs[request.Vault]; !ok {
+			http.Error(w, fmt.Sprintf("vault '%s' is not found", request.Vault), http.StatusNotFound)
+			return
+		}
+		f.Restores = append(f.Restores, request)
+		f.writeJob(w, "restore")
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "evict/"):
+		backupID := strings.TrimPrefix(path, "evict/")
+		if _, ok := f.jobs[backupID]; !ok {
+			http.Error(w, fmt.Sprintf("vault '%s' is not found", backupID), http.StatusNotFound)
+			return
+		}
+		delete(f.jobs, backupID)
+		f.Evicted = append(f.Evicted, backupID)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && path == "health":
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"UP"}`))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "jobstatus/"):
+		job, ok := f.jobs[strings.TrimPrefix(path, "jobstatus/")]
+		if !ok {
+			http.Error(w, "job is not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(job)
+	default:
+		http.Error(w, fmt.Sprintf("%s %s is not supported", r.Method, r.URL.Path), http.StatusNotFound)
+	}
+}
+
+func (f *FakeServer) writeJob(w http.ResponseWriter, jobType string) {
+	f.counter++
+	jobID := fmt.Sprintf("20240322T0918%02d", f.counter%100)
+	f.jobs[jobID] = JobStatus{State: SuccessfulState, Vault: jobID, Type: jobType, TaskId: jobID}
+	_, _ = w.Write([]byte(jobID))
+}
@@ -0,0 +1,314 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
+	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/curator"
+)
+
+const (
+	// checkTimeout limits the duration of each dependency check
+	checkTimeout = 10 * time.Second
+	// CertificateExpirationWarning is the period before certificate expiration when WARNING status is reported
+	CertificateExpirationWarning = 30 * 24 * time.Hour
+	// repositoryVerificationInterval is the period during which the result of repository verification is reused,
+	// because verification writes test files into the repository from every OpenSearch node
+	repositoryVerificationInterval = 5 * time.Minute
+)
+
+// CheckResult is the result of one dependency check, Name and LatencyMs are filled when the check is performed
+type CheckResult struct {
+	Name      string                 `json:"name"`
+	Status    string                 `json:"status"`
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	LatencyMs int64                  `json:"latencyMs"`
+}
+
+// Check verifies one dependency of adapter
+type Check func(ctx context.Context) CheckResult
+
+// DetailedHealth is the health of adapter with results of all dependency checks, Status is the worst status of checks
+type DetailedHealth struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// RepositoryVerifier verifies snapshot repository on all OpenSearch nodes
+type RepositoryVerifier interface {
+	VerifyRepository(ctx context.Context, name string) ([]byte, error)
+}
+
+// AddCheck adds dependency check which is performed for detailed health
+func (h *Health) AddCheck(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// CheckDependencies performs all dependency checks concurrently and measures latency of each check
+func (h *Health) CheckDependencies(ctx context.Context) DetailedHealth {
+	return performChecks(ctx, h.checks)
+}
+
+func performChecks(ctx context.Context, checks []namedCheck) DetailedHealth {
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = performCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+	statuses := make([]string, 0, len(results))
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	return DetailedHealth{Status: worstStatus(statuses...), Checks: results}
+}
+
+func performCheck(ctx context.Context, check namedCheck) CheckResult {
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	start := time.Now()
+	result := check.check(checkCtx)
+	result.Name = check.name
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if result.Status != common.Up {
+		logger.WarnContext(ctx, fmt.Sprintf("'%s' health check returns %s status: %s", check.name, result.Status, result.Message))
+	}
+	return result
+}
+
+// worstStatus returns the most severe status, UP is returned if there are no statuses
+func worstStatus(statuses ...string) string {
+	for _, healthStatus := range healthStatuses {
+		for _, status := range statuses {
+			if status == healthStatus {
+				return healthStatus
+			}
+		}
+	}
+	return common.Up
+}
+
+func (h *Health) DetailedHealthHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		responseBody, err := json.Marshal(h.CheckDependencies(ctx))
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal detailed health to json", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, 0)
+	}
+}
+
+func problem(err error) CheckResult {
+	return CheckResult{Status: common.Problem, Message: err.Error()}
+}
+
+// OpensearchCheck checks OpenSearch cluster health
+func OpensearchCheck(opensearch *cluster.Opensearch) Check {
+	return func(ctx context.Context) CheckResult {
+		return CheckResult{Status: opensearch.GetHealth(ctx)}
+	}
+}
+
+// RegistrationCheck reports the result of the last physical database registration in DBaaS aggregator
+func RegistrationCheck(registrationHealth func() common.ComponentHealth) Check {
+	return func(ctx context.Context) CheckResult {
+		switch registrationHealth().Status {
+		case "OK":
+			return CheckResult{Status: common.Up}
+		case common.Problem:
+			return CheckResult{Status: common.Problem, Message: "physical database is not registered in DBaaS aggregator"}
+		default:
+			return CheckResult{Status: common.Unknown, Message: "physical database registration is not performed yet"}
+		}
+	}
+}
+
+// CuratorCheck checks that Curator is available
+func CuratorCheck(client *curator.Client) Check {
+	return func(ctx context.Context) CheckResult {
+		if !client.Configured() {
+			return CheckResult{Status: common.Up, Message: "curator is not configured"}
+		}
+		if err := client.Health(ctx); err != nil {
+			return problem(err)
+		}
+		return CheckResult{Status: common.Up}
+	}
+}
+
+// IndexCheck checks that adapter system index exists
+func IndexCheck(client common.Client, index string) Check {
+	return func(ctx context.Context) CheckResult {
+		exists, err := common.IndexExists(ctx, client, index)
+		if err != nil {
+			return problem(err)
+		}
+		if !exists {
+			return CheckResult{Status: common.Problem, Message: fmt.Sprintf("'%s' index does not exist", index)}
+		}
+		return CheckResult{Status: common.Up}
+	}
+}
+
+// RolesCheck checks that roles and role mappings of all supported role types are configured by adapter
+func RolesCheck(baseProvider *basic.BaseProvider, enhancedSecurityPluginEnabled bool) Check {
+	return func(ctx context.Context) CheckResult {
+		result := CheckResult{Status: common.Up, Details: make(map[string]interface{})}
+		var problems []string
+		for _, roleType := range baseProvider.GetSupportedRoleTypes() {
+			if err := baseProvider.VerifyRole(roleType, enhancedSecurityPluginEnabled); err != nil {
+				result.Details[roleType] = err.Error()
+				problems = append(problems, roleType)
+				continue
+			}
+			result.Details[roleType] = "OK"
+		}
+		if len(problems) > 0 {
+			result.Status = common.Problem
+			result.Message = fmt.Sprintf("roles are not configured properly for %s role types", strings.Join(problems, ", "))
+		}
+		return result
+	}
+}
+
+// RepositoryCheck verifies snapshot repository which is used for backups. The repository is verified at most
+// once per repositoryVerificationInterval, so health requests do not produce writes into the repository.
+func RepositoryCheck(verifier RepositoryVerifier, repository string) Check {
+	return cachedCheck(repositoryVerificationInterval, func(ctx context.Context) CheckResult {
+		if _, err := verifier.VerifyRepository(ctx, repository); err != nil {
+			return problem(err)
+		}
+		return CheckResult{Status: common.Up, Details: map[string]interface{}{"repository": repository}}
+	})
+}
+
+// cachedCheck performs check at most once per ttl and returns its last result in between. Concurrent callers
+// wait for the check in progress instead of performing it again.
+func cachedCheck(ttl time.Duration, check Check) Check {
+	var mutex sync.Mutex
+	var result CheckResult
+	var checkedAt time.Time
+	return func(ctx context.Context) CheckResult {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if checkedAt.IsZero() || time.Since(checkedAt) >= ttl {
+			result = check(ctx)
+			checkedAt = time.Now()
+		}
+		return result
+	}
+}
+
+// CertificatesCheck checks expiration of PEM certificates in the given folders. WARNING status is returned if
+// any certificate expires in less than warningPeriod and PROBLEM status is returned if it is already expired.
+// Folders which do not exist are skipped.
+func CertificatesCheck(warningPeriod time.Duration, folders ...string) Check {
+	return func(ctx context.Context) CheckResult {
+		result := CheckResult{Status: common.Up, Details: make(map[string]interface{})}
+		now := time.Now()
+		var expired, expiring []string
+		for _, folder := range folders {
+			certificates, err := readCertificates(folder)
+			if err != nil {
+				return problem(err)
+			}
+			for file, certificate := range certificates {
+				result.Details[file] = certificate.NotAfter.UTC()
+				switch {
+				case now.After(certificate.NotAfter):
+					expired = append(expired, file)
+				case now.Add(warningPeriod).After(certificate.NotAfter):
+					expiring = append(expiring, file)
+				}
+			}
+		}
+		switch {
+		case len(expired) > 0:
+			result.Status = common.Problem
+			result.Message = fmt.Sprintf("certificates are expired: %s", strings.Join(expired, ", "))
+		case len(expiring) > 0:
+			result.Status = common.Warning
+			result.Message = fmt.Sprintf("certificates expire in less than %s: %s", warningPeriod, strings.Join(expiring, ", "))
+		case len(result.Details) == 0:
+			result.Message = "certificates are not found"
+		}
+		return result
+	}
+}
+
+// readCertificates returns the earliest expiring certificate of each file in the folder, files without
+// certificates (e.g. private keys) are skipped
+func readCertificates(folder string) (map[string]*x509.Certificate, error) {
+	entries, err := os.ReadDir(folder)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificates from '%s': %w", folder, err)
+	}
+	certificates := make(map[string]*x509.Certificate)
+	for _, entry := range entries {
+		if !common.IsNotDir(entry) {
+			continue
+		}
+		path := filepath.Join(folder, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate '%s': %w", path, err)
+		}
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate '%s': %w", path, err)
+			}
+			if earliest, ok := certificates[path]; !ok || certificate.NotAfter.Before(earliest.NotAfter) {
+				certificates[path] = certificate
+			}
+		}
+	}
+	return certificates, nil
+}
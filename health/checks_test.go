@@ -0,0 +1,154 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
+	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/curator"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCertificate(t *testing.T, path string, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dbaas-opensearch-adapter"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	content := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})...)
+	assert.Nil(t, os.WriteFile(path, content, 0600))
+}
+
+func TestCertificatesCheck(t *testing.T) {
+	tlsFolder := t.TempDir()
+	trustedFolder := t.TempDir()
+	writeCertificate(t, filepath.Join(tlsFolder, "tls.crt"), time.Now().Add(365*24*time.Hour))
+	writeCertificate(t, filepath.Join(trustedFolder, "ca.crt"), time.Now().Add(365*24*time.Hour))
+	missingFolder := filepath.Join(t.TempDir(), "missing")
+
+	check := CertificatesCheck(CertificateExpirationWarning, tlsFolder, trustedFolder, missingFolder)
+	result := check(context.Background())
+	assert.Equal(t, common.Up, result.Status)
+	assert.Len(t, result.Details, 2)
+
+	writeCertificate(t, filepath.Join(trustedFolder, "ca.crt"), time.Now().Add(24*time.Hour))
+	result = check(context.Background())
+	assert.Equal(t, common.Warning, result.Status)
+	assert.Contains(t, result.Message, "ca.crt")
+
+	writeCertificate(t, filepath.Join(tlsFolder, "tls.crt"), time.Now().Add(-time.Hour))
+	result = check(context.Background())
+	assert.Equal(t, common.Problem, result.Status)
+	assert.Contains(t, result.Message, "tls.crt")
+
+	result = CertificatesCheck(CertificateExpirationWarning, missingFolder)(context.Background())
+	assert.Equal(t, common.Up, result.Status)
+	assert.Equal(t, "certificates are not found", result.Message)
+}
+
+func TestCuratorCheck(t *testing.T) {
+	fake := curator.NewFakeServer()
+	defer fake.Close()
+	client := curator.NewClient(fake.URL, "backup", "backup", fake.Client())
+	assert.Equal(t, common.Up, CuratorCheck(client)(context.Background()).Status)
+
+	fake.FailNext(1)
+	assert.Equal(t, common.Problem, CuratorCheck(client)(context.Background()).Status)
+
+	notConfigured := curator.NewClient("", "", "", nil)
+	assert.Equal(t, common.Up, CuratorCheck(notConfigured)(context.Background()).Status)
+}
+
+// countingVerifier counts repository verifications
+type countingVerifier struct {
+	calls int
+}
+
+func (v *countingVerifier) VerifyRepository(ctx context.Context, name string) ([]byte, error) {
+	v.calls++
+	return []byte(`{"nodes":{}}`), nil
+}
+
+func TestRepositoryCheckIsCached(t *testing.T) {
+	verifier := &countingVerifier{}
+	check := RepositoryCheck(verifier, "snapshots")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, common.Up, check(context.Background()).Status)
+	}
+	assert.Equal(t, 1, verifier.calls)
+
+	calls := 0
+	expiring := cachedCheck(time.Millisecond, func(ctx context.Context) CheckResult {
+		calls++
+		return CheckResult{Status: common.Up}
+	})
+	expiring(context.Background())
+	time.Sleep(2 * time.Millisecond)
+	expiring(context.Background())
+	assert.Equal(t, 2, calls)
+}
+
+func TestRolesCheck(t *testing.T) {
+	opensearch := &cluster.Opensearch{Client: common.NewClient()}
+	result := RolesCheck(basic.NewBaseProvider(opensearch), false)(context.Background())
+	// roles returned by OpenSearch stub do not contain all permissions configured by adapter
+	assert.Equal(t, common.Problem, result.Status)
+	assert.Len(t, result.Details, 4)
+	assert.Contains(t, result.Details[basic.DmlRoleType], "does not have cluster permissions")
+}
+
+func TestCheckDependencies(t *testing.T) {
+	healthService := Health{}
+	healthService.AddCheck("index", IndexCheck(common.NewClient(), basic.DbaasMetadata))
+	healthService.AddCheck("slow", func(ctx context.Context) CheckResult {
+		time.Sleep(20 * time.Millisecond)
+		return CheckResult{Status: common.Warning, Message: "cluster is yellow"}
+	})
+	healthService.AddCheck("failed", func(ctx context.Context) CheckResult {
+		return problem(errors.New("connection refused"))
+	})
+
+	detailed := healthService.CheckDependencies(context.Background())
+	assert.Equal(t, common.Problem, detailed.Status)
+	assert.Len(t, detailed.Checks, 3)
+	assert.Equal(t, "index", detailed.Checks[0].Name)
+	assert.Equal(t, common.Up, detailed.Checks[0].Status)
+	assert.Equal(t, "slow", detailed.Checks[1].Name)
+	assert.GreaterOrEqual(t, detailed.Checks[1].LatencyMs, int64(20))
+	assert.Equal(t, "connection refused", detailed.Checks[2].Message)
+}
@@ -25,19 +25,28 @@ import (
 )
 
 type Health struct {
-	Status                string                  `json:"status"`
-	OpensearchHealth      common.ComponentHealth  `json:"opensearchHealth"`
-	DbaasAggregatorHealth *common.ComponentHealth `json:"dbaasAggregatorHealth"`
-	Opensearch            *cluster.Opensearch     `json:"-"`
+	Status                string                 `json:"status"`
+	OpensearchHealth      common.ComponentHealth `json:"opensearchHealth"`
+	DbaasAggregatorHealth common.ComponentHealth `json:"dbaasAggregatorHealth"`
+	Opensearch            *cluster.Opensearch    `json:"-"`
+	// RegistrationHealth returns the current health of physical database registration
+	RegistrationHealth func() common.ComponentHealth `json:"-"`
+
+	// checks are performed for detailed health only
+	checks []namedCheck
+	// readinessChecks and startupChecks are performed for the corresponding Kubernetes probes
+	readinessChecks []namedCheck
+	startupChecks   []namedCheck
 }
 
+var logger = common.GetLogger()
+
 var healthStatuses = []string{common.Down, common.OutOfService, common.Problem, common.Warning, common.Unknown, common.Up}
 
 func (h *Health) HealthHandler() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := common.PrepareContext(r)
-		h.DetermineHealthStatus(ctx)
-		responseBody, err := json.Marshal(h)
+		responseBody, err := json.Marshal(h.DetermineHealthStatus(ctx))
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			errorMessage := fmt.Sprintf("Error occurred during health serialization: %s", err.Error())
@@ -54,12 +63,19 @@ func (h *Health) HealthHandler() func(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Health) DetermineHealthStatus(ctx context.Context) {
-	h.OpensearchHealth.Status = h.Opensearch.GetHealth(ctx)
+// DetermineHealthStatus returns the current health of adapter. It is determined for each request and h is not
+// changed, so concurrent health requests do not interfere.
+func (h *Health) DetermineHealthStatus(ctx context.Context) Health {
+	health := Health{OpensearchHealth: h.OpensearchHealth, DbaasAggregatorHealth: h.DbaasAggregatorHealth}
+	health.OpensearchHealth.Status = h.Opensearch.GetHealth(ctx)
+	if h.RegistrationHealth != nil {
+		health.DbaasAggregatorHealth = h.RegistrationHealth()
+	}
 	for _, status := range healthStatuses {
-		if status == h.OpensearchHealth.Status || status == h.DbaasAggregatorHealth.Status {
-			h.Status = status
-			return
+		if status == health.OpensearchHealth.Status || status == health.DbaasAggregatorHealth.Status {
+			health.Status = status
+			break
 		}
 	}
+	return health
 }
@@ -0,0 +1,169 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+)
+
+// AddReadinessCheck adds check which is performed for readiness probe
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.readinessChecks = append(h.readinessChecks, namedCheck{name: name, check: check})
+}
+
+// AddStartupCheck adds check which is performed for startup probe
+func (h *Health) AddStartupCheck(name string, check Check) {
+	h.startupChecks = append(h.startupChecks, namedCheck{name: name, check: check})
+}
+
+// LivenessHandler reports that adapter process is able to serve requests, it does not check dependencies,
+// so adapter is not restarted because of OpenSearch or DBaaS aggregator problems.
+func (h *Health) LivenessHandler() func(w http.ResponseWriter, r *http.Request) {
+	return probeHandler(nil)
+}
+
+// ReadinessHandler reports that adapter is able to process requests of DBaaS aggregator
+func (h *Health) ReadinessHandler() func(w http.ResponseWriter, r *http.Request) {
+	return probeHandler(h.readinessChecks)
+}
+
+// StartupHandler reports that adapter bootstrap is completed
+func (h *Health) StartupHandler() func(w http.ResponseWriter, r *http.Request) {
+	return probeHandler(h.startupChecks)
+}
+
+// probeHandler performs checks and responds with 200 status if adapter is UP or WARNING and 503 status otherwise
+func probeHandler(checks []namedCheck) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		result := performChecks(ctx, checks)
+		responseBody, err := json.Marshal(result)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal probe result to json", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		status := http.StatusOK
+		if result.Status != common.Up && result.Status != common.Warning {
+			status = http.StatusServiceUnavailable
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, status)
+	}
+}
+
+// ConnectionCheck checks that OpenSearch responds, the cluster status does not matter
+func ConnectionCheck(opensearch *cluster.Opensearch) Check {
+	return func(ctx context.Context) CheckResult {
+		clusterStatus := opensearch.GetHealth(ctx)
+		if clusterStatus == common.Problem {
+			return CheckResult{Status: common.Problem, Message: "OpenSearch is not available"}
+		}
+		return CheckResult{Status: common.Up, Details: map[string]interface{}{"clusterStatus": clusterStatus}}
+	}
+}
+
+// DrainCheck reports OUT_OF_SERVICE status when adapter is shutting down
+func DrainCheck(drainer *common.Drainer) Check {
+	return func(ctx context.Context) CheckResult {
+		if drainer.Draining() {
+			return CheckResult{Status: common.OutOfService, Message: "adapter is shutting down"}
+		}
+		return CheckResult{Status: common.Up}
+	}
+}
+
+// States of startup steps, failed step has the error message as its state
+const (
+	StepPending   = "PENDING"
+	StepCompleted = "COMPLETED"
+)
+
+// Startup tracks bootstrap steps of adapter, startup is completed when all steps are completed
+type Startup struct {
+	mutex  sync.RWMutex
+	steps  []string
+	states map[string]string
+}
+
+func NewStartup(steps ...string) *Startup {
+	states := make(map[string]string, len(steps))
+	for _, step := range steps {
+		states[step] = StepPending
+	}
+	return &Startup{steps: steps, states: states}
+}
+
+func (s *Startup) Complete(step string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[step] = StepCompleted
+}
+
+// Fail records the error of the step, the step can be completed later if it is retried
+func (s *Startup) Fail(step string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.states[step] = err.Error()
+}
+
+// Completed returns true if all steps are completed
+func (s *Startup) Completed() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, step := range s.steps {
+		if s.states[step] != StepCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports UP status when all steps are completed, UNKNOWN status when some steps are pending and
+// PROBLEM status when some steps are failed
+func (s *Startup) Check(ctx context.Context) CheckResult {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	result := CheckResult{Status: common.Up, Details: make(map[string]interface{}, len(s.steps))}
+	var pending, failed []string
+	for _, step := range s.steps {
+		state := s.states[step]
+		result.Details[step] = state
+		switch state {
+		case StepCompleted:
+		case StepPending:
+			pending = append(pending, step)
+		default:
+			failed = append(failed, step)
+		}
+	}
+	switch {
+	case len(failed) > 0:
+		result.Status = common.Problem
+		result.Message = fmt.Sprintf("startup steps are failed: %s", strings.Join(failed, ", "))
+	case len(pending) > 0:
+		result.Status = common.Unknown
+		result.Message = fmt.Sprintf("startup steps are not completed: %s", strings.Join(pending, ", "))
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
+	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func probe(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (int, DetailedHealth) {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/probe", nil))
+	var result DetailedHealth
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	return recorder.Code, result
+}
+
+func TestLivenessDoesNotDependOnChecks(t *testing.T) {
+	healthService := Health{}
+	healthService.AddReadinessCheck("failed", func(ctx context.Context) CheckResult {
+		return problem(errors.New("connection refused"))
+	})
+	code, result := probe(t, healthService.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, common.Up, result.Status)
+}
+
+func TestReadiness(t *testing.T) {
+	drainer := common.NewDrainer()
+	healthService := Health{}
+	healthService.AddReadinessCheck("metadataIndex", IndexCheck(common.NewClient(), basic.DbaasMetadata))
+	healthService.AddReadinessCheck("drain", DrainCheck(drainer))
+	code, result := probe(t, healthService.ReadinessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, common.Up, result.Status)
+
+	drainer.Drain(context.Background())
+	code, result = probe(t, healthService.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, common.OutOfService, result.Status)
+}
+
+func TestStartup(t *testing.T) {
+	startup := NewStartup("roles", "migration")
+	healthService := Health{}
+	healthService.AddStartupCheck("bootstrap", startup.Check)
+	code, result := probe(t, healthService.StartupHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, common.Unknown, result.Status)
+
+	startup.Complete("migration")
+	startup.Fail("roles", errors.New("security plugin is not available"))
+	code, result = probe(t, healthService.StartupHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, common.Problem, result.Status)
+	assert.Equal(t, "security plugin is not available", result.Checks[0].Details["roles"])
+	assert.False(t, startup.Completed())
+
+	startup.Complete("roles")
+	code, result = probe(t, healthService.StartupHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, common.Up, result.Status)
+	assert.True(t, startup.Completed())
+}
+
+func TestConcurrentHealthRequestsDoNotChangeHealth(t *testing.T) {
+	healthService := Health{
+		Opensearch: &cluster.Opensearch{Host: "localhost", Port: 9200, Protocol: common.Http, Client: common.NewClient()},
+		RegistrationHealth: func() common.ComponentHealth {
+			return common.ComponentHealth{Status: common.Up}
+		},
+	}
+	handler := healthService.HealthHandler()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			handler(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+			var result Health
+			assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+			assert.NotEmpty(t, result.Status)
+			assert.Equal(t, common.Up, result.DbaasAggregatorHealth.Status)
+		}()
+	}
+	wg.Wait()
+	assert.Empty(t, healthService.Status)
+}
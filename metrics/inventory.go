@@ -0,0 +1,84 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InventoryCacheTTL is the period during which counted managed resources are reused between scrapes
+const InventoryCacheTTL = time.Minute
+
+const inventoryTimeout = 10 * time.Second
+
+// CountFunc returns the number of databases and users managed by adapter
+type CountFunc func(ctx context.Context) (databases int, users int, err error)
+
+var (
+	managedDatabasesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "managed_databases"),
+		"Number of databases managed by adapter.", nil, nil)
+	managedUsersDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "managed_users"),
+		"Number of users managed by adapter.", nil, nil)
+)
+
+// InventoryCollector exposes the number of managed databases and users. Resources are counted in OpenSearch
+// during scrape, the result is cached for cacheTTL. Nothing is exposed until resources are counted successfully.
+type InventoryCollector struct {
+	count     CountFunc
+	cacheTTL  time.Duration
+	mutex     sync.Mutex
+	databases int
+	users     int
+	countedAt time.Time
+}
+
+func NewInventoryCollector(count CountFunc, cacheTTL time.Duration) *InventoryCollector {
+	return &InventoryCollector{count: count, cacheTTL: cacheTTL}
+}
+
+// RegisterInventory adds collector of managed resources counted by the given function to Registry
+func RegisterInventory(count CountFunc) error {
+	return Registry.Register(NewInventoryCollector(count, InventoryCacheTTL))
+}
+
+func (ic *InventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- managedDatabasesDesc
+	ch <- managedUsersDesc
+}
+
+func (ic *InventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	ic.mutex.Lock()
+	defer ic.mutex.Unlock()
+	if ic.countedAt.IsZero() || time.Since(ic.countedAt) >= ic.cacheTTL {
+		ctx, cancel := context.WithTimeout(context.Background(), inventoryTimeout)
+		defer cancel()
+		databases, users, err := ic.count(ctx)
+		if err != nil {
+			logger.Error("Failed to count managed resources", slog.Any("error", err))
+		} else {
+			ic.databases, ic.users, ic.countedAt = databases, users, time.Now()
+		}
+	}
+	if ic.countedAt.IsZero() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(managedDatabasesDesc, prometheus.GaugeValue, float64(ic.databases))
+	ch <- prometheus.MustNewConstMetric(managedUsersDesc, prometheus.GaugeValue, float64(ic.users))
+}
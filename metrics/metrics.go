@@ -0,0 +1,157 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var logger = common.GetLogger()
+
+const namespace = "dbaas_opensearch_adapter"
+
+// Outcomes of requests to Curator
+const (
+	OutcomeSuccess  = "success"
+	OutcomeRejected = "rejected"
+	OutcomeFailed   = "failed"
+)
+
+// Registry contains all adapter metrics, it is exposed by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests processed by adapter by route and status code.",
+	}, []string{"route", "method", "status"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests processed by adapter by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	opensearchRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "opensearch_request_duration_seconds",
+		Help:      "Duration of requests to OpenSearch by API and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"api", "status"})
+	curatorRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "curator_requests_total",
+		Help:      "Number of requests to Curator by operation and outcome.",
+	}, []string{"operation", "outcome"})
+	registrationAttempts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registration_attempts_total",
+		Help:      "Number of attempts to register physical database in DBaaS aggregator.",
+	})
+	registrationFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registration_failures_total",
+		Help:      "Number of failed attempts to register physical database in DBaaS aggregator.",
+	})
+	recoveryState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users_recovery_state",
+		Help:      "Current state of users recovery, the gauge of the current state is 1.",
+	}, []string{"state"})
+	recoveryTotalUsers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users_recovery_total_users",
+		Help:      "Number of users to recover by the current or the last users recovery.",
+	})
+	recoveryProcessedUsers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "users_recovery_processed_users",
+		Help:      "Number of users recovered by the current or the last users recovery.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		opensearchRequestDuration,
+		curatorRequests,
+		registrationAttempts,
+		registrationFailures,
+		recoveryState,
+		recoveryTotalUsers,
+		recoveryProcessedUsers,
+	)
+}
+
+// Handler serves metrics of Registry in Prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorLog: promErrorLog{}})
+}
+
+// Middleware records number and duration of HTTP requests. It is expected to be used as mux middleware,
+// so requests are labeled by the path template of the matched route instead of the actual path.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := common.RouteTemplate(r)
+		recorder := common.NewStatusRecorder(w)
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+		httpRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.Status)).Inc()
+	})
+}
+
+// ObserveCuratorRequest records the final outcome of request to Curator
+func ObserveCuratorRequest(operation string, outcome string) {
+	curatorRequests.WithLabelValues(operation, outcome).Inc()
+}
+
+// ObserveRegistrationAttempt records attempt to register physical database, attempt is failed if err is not nil
+func ObserveRegistrationAttempt(err error) {
+	registrationAttempts.Inc()
+	if err != nil {
+		registrationFailures.Inc()
+	}
+}
+
+// SetRecoveryState marks the given state of users recovery as the current one
+func SetRecoveryState(state string) {
+	recoveryState.Reset()
+	recoveryState.WithLabelValues(state).Set(1)
+}
+
+// SetRecoveryProgress records the number of processed users from the total number of users to recover
+func SetRecoveryProgress(processed int, total int) {
+	recoveryProcessedUsers.Set(float64(processed))
+	recoveryTotalUsers.Set(float64(total))
+}
+
+type promErrorLog struct{}
+
+func (promErrorLog) Println(v ...interface{}) {
+	logger.Error("Failed to serve metrics", slog.Any("error", fmt.Sprint(v...)))
+}
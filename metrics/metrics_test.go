@@ -0,0 +1,85 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareLabelsRequestsByRoute(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/databases/{dbName}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods(http.MethodGet)
+
+	for _, name := range []string{"first", "second"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/databases/"+name, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("/databases/{dbName}", http.MethodGet, "404")))
+}
+
+func TestInstrumentedClientLabelsRequestsByApi(t *testing.T) {
+	client := InstrumentClient(common.NewClient())
+	request := httptest.NewRequest(http.MethodGet, "/_plugins/_security/api/internalusers/dbaas_user", nil)
+	response, err := client.Perform(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	assert.Equal(t, "GET /_plugins/_security/api/internalusers/{name}", common.OpensearchApiName(request))
+	assert.Equal(t, 1, testutil.CollectAndCount(opensearchRequestDuration, namespace+"_opensearch_request_duration_seconds"))
+	assert.Equal(t, "PUT /{name}/_doc/{name}",
+		common.OpensearchApiName(httptest.NewRequest(http.MethodPut, "/dbaas_opensearch_metadata/_doc/test", nil)))
+}
+
+func TestInventoryCollectorCachesCount(t *testing.T) {
+	calls := 0
+	var countErr error
+	collector := NewInventoryCollector(func(ctx context.Context) (int, int, error) {
+		calls++
+		return 2, 3, countErr
+	}, time.Hour)
+	countErr = errors.New("opensearch is not available")
+	assert.Equal(t, 0, testutil.CollectAndCount(collector))
+
+	countErr = nil
+	expected := `
+# HELP dbaas_opensearch_adapter_managed_databases Number of databases managed by adapter.
+# TYPE dbaas_opensearch_adapter_managed_databases gauge
+dbaas_opensearch_adapter_managed_databases 2
+# HELP dbaas_opensearch_adapter_managed_users Number of users managed by adapter.
+# TYPE dbaas_opensearch_adapter_managed_users gauge
+dbaas_opensearch_adapter_managed_users 3
+`
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+	assert.Equal(t, 2, calls)
+
+	registry := prometheus.NewRegistry()
+	assert.Nil(t, registry.Register(collector))
+}
@@ -0,0 +1,54 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/opensearch-project/opensearch-go/opensearchtransport"
+)
+
+// instrumentedClient records duration of requests performed by the wrapped OpenSearch client
+type instrumentedClient struct {
+	common.Client
+}
+
+// InstrumentClient wraps OpenSearch client, so duration of all requests sent by opensearchapi and api packages
+// is recorded by API
+func InstrumentClient(client common.Client) common.Client {
+	return &instrumentedClient{Client: client}
+}
+
+func (ic *instrumentedClient) Perform(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	response, err := ic.Client.Perform(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(response.StatusCode)
+	}
+	opensearchRequestDuration.WithLabelValues(common.OpensearchApiName(req), status).Observe(time.Since(start).Seconds())
+	return response, err
+}
+
+func (ic *instrumentedClient) Metrics() (opensearchtransport.Metrics, error) {
+	return ic.Client.Metrics()
+}
+
+func (ic *instrumentedClient) DiscoverNodes() error {
+	return ic.Client.DiscoverNodes()
+}
@@ -0,0 +1,108 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// YamlPath is the path the specification is served at in YAML format
+	YamlPath = "/openapi.yaml"
+	// JsonPath is the path the specification is served at in JSON format
+	JsonPath = "/openapi.json"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// operationKeys are keys of path item which describe operations, other keys such as "parameters" are skipped
+var operationKeys = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var document = sync.OnceValues(func() (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI specification: %w", err)
+	}
+	return doc, nil
+})
+
+var jsonSpec = sync.OnceValues(func() ([]byte, error) {
+	doc, err := document()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+})
+
+// Paths returns methods of operations described in the specification by path templates, methods are upper-cased
+// and sorted, so they can be compared with methods of mux routes
+func Paths() (map[string][]string, error) {
+	doc, err := document()
+	if err != nil {
+		return nil, err
+	}
+	items, ok := doc["paths"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("OpenAPI specification does not contain paths")
+	}
+	paths := make(map[string][]string, len(items))
+	for path, item := range items {
+		operations, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("path item '%s' of OpenAPI specification is not an object", path)
+		}
+		var methods []string
+		for _, key := range operationKeys {
+			if _, ok := operations[key]; ok {
+				methods = append(methods, strings.ToUpper(key))
+			}
+		}
+		sort.Strings(methods)
+		paths[path] = methods
+	}
+	return paths, nil
+}
+
+// YamlHandler serves the specification as it is written
+func YamlHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(spec)
+	}
+}
+
+// JsonHandler serves the specification converted to JSON for tools which do not support YAML
+func JsonHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		body, err := jsonSpec()
+		if err != nil {
+			common.WriteError(ctx, w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}
+}
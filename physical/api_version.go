@@ -0,0 +1,132 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+)
+
+// aggregatorMultiUserMajor is the major version of DBaaS aggregator API which supports v2 adapter API
+const aggregatorMultiUserMajor = 3
+
+type ApiVersionInfo struct {
+	Major           int   `json:"major,omitempty"`
+	Minor           int   `json:"minor,omitempty"`
+	SupportedMajors []int `json:"supportedMajors,omitempty"`
+}
+
+// getAggregatorApiVersion receives API version of DBaaS aggregator, statusCode is returned if aggregator responds
+// with unexpected status.
+func getAggregatorApiVersion(ctx context.Context, aggregatorAddress string, client *http.Client) (*ApiVersionInfo, int, error) {
+	url := fmt.Sprintf("%s/api-version", aggregatorAddress)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to prepare request to get API version: %w", err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get API version: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, response.StatusCode, fmt.Errorf("failed to get API version, status code is %d", response.StatusCode)
+	}
+	var apiVersionInfo ApiVersionInfo
+	if err = common.ProcessBody(response.Body, &apiVersionInfo); err != nil {
+		return nil, response.StatusCode, fmt.Errorf("failed to parse api-version response body: %w", err)
+	}
+	return &apiVersionInfo, response.StatusCode, nil
+}
+
+// adapterApiVersion returns adapter API version which is used for registration in aggregator with given supported majors
+func adapterApiVersion(supportedMajors []int) string {
+	if slices.Contains(supportedMajors, aggregatorMultiUserMajor) {
+		return common.ApiV2
+	}
+	return common.ApiV1
+}
+
+// negotiateApiVersion returns adapter API version and majors supported by aggregator. API_VERSION environment
+// variable is used if aggregator is not available and v1 is used if aggregator does not provide API version.
+func negotiateApiVersion(ctx context.Context, aggregatorAddress string, client *http.Client) (string, []int) {
+	apiVersion := common.GetEnv("API_VERSION", common.ApiV2)
+	info, statusCode, err := getAggregatorApiVersion(ctx, aggregatorAddress, client)
+	if err != nil && (statusCode == 0 || statusCode == http.StatusOK) {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to negotiate API version, API %s from API_VERSION environment variable is used",
+			apiVersion), slog.Any("error", err))
+		return apiVersion, nil
+	}
+	var supportedMajors []int
+	if info != nil {
+		supportedMajors = info.SupportedMajors
+	}
+	if len(supportedMajors) == 0 {
+		logger.WarnContext(ctx, fmt.Sprintf("DBaaS aggregator does not provide supported majors, API %s is used",
+			common.ApiV1), slog.Any("error", err))
+		return common.ApiV1, supportedMajors
+	}
+	apiVersion = adapterApiVersion(supportedMajors)
+	logger.InfoContext(ctx, fmt.Sprintf("Adapter API %s is enabled.", apiVersion))
+	return apiVersion, supportedMajors
+}
+
+// negotiate receives API version of DBaaS aggregator before the first registration, so adapter start does not
+// depend on aggregator availability.
+func (rs *RegistrationProvider) negotiate(ctx context.Context) {
+	apiVersion, supportedMajors := negotiateApiVersion(ctx, rs.dbaasAggregator.Address, rs.client)
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.statusMutex.Lock()
+	defer rs.statusMutex.Unlock()
+	rs.supportedMajors = supportedMajors
+	rs.apiVersion.Set(apiVersion)
+}
+
+// checkApiVersion receives API version of DBaaS aggregator again and switches registration to the corresponding
+// adapter API version if it is changed, so aggregator upgrade does not require adapter restart. The current
+// version is kept if aggregator does not respond with valid version.
+func (rs *RegistrationProvider) checkApiVersion(ctx context.Context) {
+	info, _, err := getAggregatorApiVersion(ctx, rs.dbaasAggregator.Address, rs.client)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to check API version of DBaaS aggregator, the current one is kept", slog.Any("error", err))
+		return
+	}
+	if len(info.SupportedMajors) == 0 {
+		logger.WarnContext(ctx, "DBaaS aggregator does not provide supported majors, the current API version is kept")
+		return
+	}
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.statusMutex.Lock()
+	defer rs.statusMutex.Unlock()
+	if slices.Equal(rs.supportedMajors, info.SupportedMajors) {
+		return
+	}
+	logger.InfoContext(ctx, fmt.Sprintf("Supported majors of DBaaS aggregator are changed from %v to %v",
+		rs.supportedMajors, info.SupportedMajors))
+	rs.supportedMajors = info.SupportedMajors
+	rs.registrationStatus.SupportedMajorsChanges++
+	if apiVersion := adapterApiVersion(info.SupportedMajors); apiVersion != rs.apiVersion.Get() {
+		logger.InfoContext(ctx, fmt.Sprintf("Physical database registration is switched from API %s to API %s",
+			rs.apiVersion.Get(), apiVersion))
+		rs.apiVersion.Set(apiVersion)
+	}
+}
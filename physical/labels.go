@@ -0,0 +1,110 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"time"
+)
+
+// ReadLabelsFile returns labels of physical database. Labels file is re-read if it is changed, invalid file is
+// rejected and the previous labels are returned.
+func (rs *RegistrationProvider) ReadLabelsFile(ctx context.Context) map[string]string {
+	if _, err := rs.reloadLabels(ctx); err != nil {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to reload labels file %s, previous labels are used", rs.labelsFileLocation),
+			slog.Any("error", err))
+	}
+	rs.labelsMutex.RLock()
+	defer rs.labelsMutex.RUnlock()
+	labels := maps.Clone(rs.labels)
+	logger.DebugContext(ctx, fmt.Sprintf("Read labels: %v", labels))
+	return labels
+}
+
+// WatchLabels checks labels file with the interval until ctx is cancelled. Physical database is registered again
+// as soon as valid labels are changed.
+func (rs *RegistrationProvider) WatchLabels(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := rs.reloadLabels(ctx)
+		if err != nil {
+			logger.WarnContext(ctx, fmt.Sprintf("Labels file %s is rejected, previous labels are kept", rs.labelsFileLocation),
+				slog.Any("error", err))
+			continue
+		}
+		if changed {
+			logger.InfoContext(ctx, "Labels are changed, physical database is being registered again")
+			rs.executor.Submit(func() {
+				rs.RegisterWithRetry(ctx)
+			})
+		}
+	}
+}
+
+// reloadLabels reads labels file and replaces the current labels if the file content is changed. It returns true
+// if labels are changed after they have been loaded once. Missing file means that there are no labels.
+func (rs *RegistrationProvider) reloadLabels(ctx context.Context) (bool, error) {
+	content, err := os.ReadFile(rs.labelsFileLocation)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	hash := sha256.Sum256(content)
+
+	rs.labelsMutex.Lock()
+	defer rs.labelsMutex.Unlock()
+	if rs.labelsLoaded && hash == rs.labelsHash || hash == rs.rejectedLabelsHash {
+		return false, nil
+	}
+	var labels map[string]string
+	if err != nil {
+		logger.DebugContext(ctx, fmt.Sprintf("Skipping labels file, it does not exist: %s", rs.labelsFileLocation))
+	} else if labels, err = parseLabels(content); err != nil {
+		// the same invalid content is rejected only once to not repeat the warning
+		rs.rejectedLabelsHash = hash
+		return false, err
+	}
+	changed := rs.labelsLoaded && !maps.Equal(labels, rs.labels)
+	rs.labels = labels
+	rs.labelsHash = hash
+	rs.labelsLoaded = true
+	return changed, nil
+}
+
+// parseLabels validates labels file content, it must be a JSON object with string values and non-empty keys
+func parseLabels(content []byte) (map[string]string, error) {
+	var labels map[string]string
+	if err := json.Unmarshal(content, &labels); err != nil {
+		return nil, fmt.Errorf("labels must be JSON object with string values: %w", err)
+	}
+	for name := range labels {
+		if name == "" {
+			return nil, fmt.Errorf("label name must not be empty")
+		}
+	}
+	return labels, nil
+}
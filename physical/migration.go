@@ -0,0 +1,244 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/gorilla/mux"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+const (
+	MigrationsIndex = common.SystemIndexPrefix + "migrations"
+
+	MigrationInProgress = "IN_PROGRESS"
+	MigrationCompleted  = "COMPLETED"
+	MigrationFailed     = "FAILED"
+
+	migrationsBatchSize = 100
+)
+
+// MigrationProgress is the progress of additional roles migration requested by aggregator instruction. Users are
+// stored as soon as they are created, so they are reported instead of creating new ones if migration is resumed
+// after adapter restart.
+type MigrationProgress struct {
+	InstructionId string         `json:"instructionId"`
+	Status        string         `json:"status"`
+	Users         []MigratedUser `json:"users,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	StartedAt     time.Time      `json:"startedAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	CompletedAt   *time.Time     `json:"completedAt,omitempty"`
+}
+
+// MigratedUser is the user created for additional role, Reported is set when aggregator accepts its connection properties
+type MigratedUser struct {
+	AdditionalRoleId string `json:"additionalRoleId"`
+	RoleType         string `json:"roleType"`
+	Username         string `json:"username"`
+	ResourcePrefix   string `json:"resourcePrefix"`
+	Reported         bool   `json:"reported"`
+}
+
+type storedMigration struct {
+	Found  bool               `json:"found"`
+	Source *MigrationProgress `json:"_source"`
+}
+
+type foundMigrations struct {
+	Hits struct {
+		Hits []storedMigration `json:"hits"`
+	} `json:"hits"`
+}
+
+func (progress *MigrationProgress) findUser(additionalRoleId string, roleType string) *MigratedUser {
+	for i := range progress.Users {
+		if progress.Users[i].AdditionalRoleId == additionalRoleId && progress.Users[i].RoleType == roleType {
+			return &progress.Users[i]
+		}
+	}
+	return nil
+}
+
+func (progress *MigrationProgress) markReported(additionalRoleIds []string) {
+	for i := range progress.Users {
+		for _, id := range additionalRoleIds {
+			if progress.Users[i].AdditionalRoleId == id {
+				progress.Users[i].Reported = true
+			}
+		}
+	}
+}
+
+// MigrationStore keeps progress of additional roles migrations in MigrationsIndex.
+type MigrationStore struct {
+	client common.Client
+}
+
+func NewMigrationStore(client common.Client) *MigrationStore {
+	return &MigrationStore{client: client}
+}
+
+func (ms MigrationStore) EnsureIndex(ctx context.Context) error {
+	return common.EnsureIndex(ctx, ms.client, MigrationsIndex)
+}
+
+// Get returns progress of migration by instruction identifier, nil is returned if migration is not started.
+func (ms MigrationStore) Get(ctx context.Context, instructionId string) (*MigrationProgress, error) {
+	getRequest := opensearchapi.GetRequest{
+		Index:      MigrationsIndex,
+		DocumentID: instructionId,
+	}
+	response, err := getRequest.Do(ctx, ms.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive progress of '%s' migration: %w", instructionId, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to receive progress of '%s' migration, status code is %d", instructionId, response.StatusCode)
+	}
+	var stored storedMigration
+	if err = common.ProcessBody(response.Body, &stored); err != nil {
+		return nil, err
+	}
+	if !stored.Found {
+		return nil, nil
+	}
+	return stored.Source, nil
+}
+
+// List returns the latest migrations starting from the most recent one.
+func (ms MigrationStore) List(ctx context.Context) ([]MigrationProgress, error) {
+	searchRequest := opensearchapi.SearchRequest{
+		Index: []string{MigrationsIndex},
+		Body:  strings.NewReader(fmt.Sprintf(`{"size":%d,"sort":[{"startedAt":"desc"}]}`, migrationsBatchSize)),
+	}
+	var found foundMigrations
+	if err := common.DoRequest(searchRequest, ms.client, &found, ctx); err != nil {
+		return nil, fmt.Errorf("failed to search migrations: %w", err)
+	}
+	migrations := make([]MigrationProgress, 0, len(found.Hits.Hits))
+	for _, hit := range found.Hits.Hits {
+		if hit.Source != nil {
+			migrations = append(migrations, *hit.Source)
+		}
+	}
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].StartedAt.After(migrations[j].StartedAt)
+	})
+	return migrations, nil
+}
+
+func (ms MigrationStore) Save(ctx context.Context, progress *MigrationProgress) error {
+	progress.UpdatedAt = time.Now().UTC()
+	body, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	indexRequest := opensearchapi.IndexRequest{
+		Index:      MigrationsIndex,
+		DocumentID: progress.InstructionId,
+		Body:       strings.NewReader(string(body)),
+	}
+	response, err := indexRequest.Do(ctx, ms.client)
+	if err != nil {
+		return fmt.Errorf("failed to store progress of '%s' migration: %w", progress.InstructionId, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to store progress of '%s' migration, status code is %d", progress.InstructionId, response.StatusCode)
+	}
+	return nil
+}
+
+// loadMigrationProgress returns stored progress of migration or starts the new one. Migration is performed
+// without persistence if the store is not configured or not available.
+func (rs *RegistrationProvider) loadMigrationProgress(ctx context.Context, instructionId string) *MigrationProgress {
+	if rs.Migrations != nil {
+		progress, err := rs.Migrations.Get(ctx, instructionId)
+		if err != nil {
+			logger.WarnContext(ctx, fmt.Sprintf("Failed to receive progress of '%s' migration, it is started from scratch", instructionId),
+				slog.Any("error", err))
+		} else if progress != nil {
+			logger.InfoContext(ctx, fmt.Sprintf("Resuming '%s' migration, %d users are already created", instructionId, len(progress.Users)))
+			progress.Status = MigrationInProgress
+			progress.Error = ""
+			return progress
+		}
+	}
+	return &MigrationProgress{
+		InstructionId: instructionId,
+		Status:        MigrationInProgress,
+		StartedAt:     time.Now().UTC(),
+	}
+}
+
+func (rs *RegistrationProvider) saveMigrationProgress(ctx context.Context, progress *MigrationProgress) {
+	if rs.Migrations == nil {
+		return
+	}
+	if err := rs.Migrations.Save(ctx, progress); err != nil {
+		logger.WarnContext(ctx, fmt.Sprintf("Failed to store progress of '%s' migration", progress.InstructionId), slog.Any("error", err))
+	}
+}
+
+// MigrationProgressHandler returns progress of migration by instruction identifier or the list of latest migrations
+// if identifier is not specified.
+func (rs *RegistrationProvider) MigrationProgressHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		logger.InfoContext(ctx, "Received request to get progress of additional roles migration")
+		if rs.Migrations == nil {
+			common.WriteError(ctx, w, common.NewError(common.ErrNotFound, "migration progress is not stored"))
+			return
+		}
+		var result interface{}
+		var err error
+		if instructionId, ok := mux.Vars(r)["instructionId"]; ok {
+			var progress *MigrationProgress
+			if progress, err = rs.Migrations.Get(ctx, instructionId); err == nil && progress == nil {
+				common.WriteError(ctx, w, common.NewError(common.ErrNotFound, fmt.Sprintf("'%s' migration is not found", instructionId)))
+				return
+			}
+			result = progress
+		} else {
+			result, err = rs.Migrations.List(ctx)
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to receive progress of migration", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		responseBody, err := json.Marshal(result)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal migration progress to json", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, 0)
+	}
+}
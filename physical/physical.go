@@ -17,36 +17,37 @@ package physical
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
 	cl "github.com/Netcracker/dbaas-opensearch-adapter/client"
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/tracing"
 	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 var logger = common.GetLogger()
 
+// StatusDraining is reported to DBaaS aggregator when adapter is shutting down and does not accept new databases
+const StatusDraining dao.Status = "draining"
+
 type Database struct {
 	Id     string            `json:"id"`
 	Labels map[string]string `json:"labels"`
 }
 
-type ApiVersionInfo struct {
-	Major           int   `json:"major,omitempty"`
-	Minor           int   `json:"minor,omitempty"`
-	SupportedMajors []int `json:"supportedMajors,omitempty"`
-}
-
 type RegistrationProvider struct {
-	ApiVersion             string
+	// apiVersion is API version negotiated with DBaaS aggregator, it is shared with baseProvider
+	apiVersion             *common.NegotiatedApiVersion
 	dbaasAdapter           *common.Component
 	dbaasAggregator        *common.Component
 	physicalDatabaseId     string
@@ -55,15 +56,30 @@ type RegistrationProvider struct {
 	registrationRetryTime  int
 	registrationRetryDelay int
 	client                 *http.Client
-	Health                 common.ComponentHealth
 	status                 dao.Status
 
 	// mutex is used to synchronize concurrent registrations.
 	mutex    sync.Mutex
-	executor common.BackgroundExecutor
+	executor *common.BackgroundExecutor
+
+	// labelsMutex guards labels which are read from labelsFileLocation, labelsHash is the checksum of the file content
+	labelsMutex        sync.RWMutex
+	labels             map[string]string
+	labelsHash         [sha256.Size]byte
+	rejectedLabelsHash [sha256.Size]byte
+	labelsLoaded       bool
+
+	// statusMutex guards health and registrationStatus which are changed by registration attempts
+	statusMutex        sync.RWMutex
+	health             common.ComponentHealth
+	registrationStatus RegistrationStatus
+	// supportedMajors are major versions of DBaaS aggregator API received on the last successful version check
+	supportedMajors []int
 
 	// baseProvider is used for migration on multi-user approach
 	baseProvider *basic.BaseProvider
+	// Migrations stores progress of migration, migration is not resumable after restart if it is not specified
+	Migrations *MigrationStore
 }
 
 func NewRegistrationProvider(aggregatorAddress string, aggregatorCredentials dao.BasicAuth,
@@ -73,8 +89,6 @@ func NewRegistrationProvider(aggregatorAddress string, aggregatorCredentials dao
 	if client == nil {
 		client = cl.ConfigureClient()
 	}
-	apiVersion := getApiVersion(aggregatorAddress, client)
-	baseProvider.ApiVersion = apiVersion
 	dbaasAggregator := &common.Component{
 		Address:     aggregatorAddress,
 		Credentials: aggregatorCredentials,
@@ -84,7 +98,7 @@ func NewRegistrationProvider(aggregatorAddress string, aggregatorCredentials dao
 		Credentials: adapterCredentials,
 	}
 	return &RegistrationProvider{
-		ApiVersion:             apiVersion,
+		apiVersion:             baseProvider.NegotiatedApiVersion(),
 		dbaasAdapter:           dbaasAdapter,
 		dbaasAggregator:        dbaasAggregator,
 		physicalDatabaseId:     physicalDatabaseId,
@@ -93,68 +107,29 @@ func NewRegistrationProvider(aggregatorAddress string, aggregatorCredentials dao
 		registrationRetryTime:  registrationRetryTime,
 		registrationRetryDelay: registrationRetryDelay,
 		client:                 client,
-		Health:                 common.ComponentHealth{Status: "UNKNOWN"},
-		executor:               common.BackgroundExecutor{},
+		health:                 common.ComponentHealth{Status: "UNKNOWN"},
+		executor:               common.NewBackgroundExecutor(),
 		status:                 dao.StatusRunning,
+		registrationStatus:     RegistrationStatus{State: StateStopped},
 		baseProvider:           baseProvider,
 	}
 }
 
-func getApiVersion(aggregatorAddress string, client *http.Client) string {
-	apiVersion := common.GetEnv("API_VERSION", common.ApiV2)
-	url := fmt.Sprintf("%s/api-version", aggregatorAddress)
-	request, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to prepare request to get API version. API %s is enabled by default.", apiVersion), slog.Any("error", err))
-		return apiVersion
-	}
-	response, err := client.Do(request)
-	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to get API version. API %s is enabled by default.", apiVersion), slog.Any("error", err))
-		return apiVersion
-	}
-	defer response.Body.Close()
-	if response.StatusCode == http.StatusOK {
-		var apiVersionInfo ApiVersionInfo
-		err = common.ProcessBody(response.Body, &apiVersionInfo)
-		if err != nil {
-			logger.Error(fmt.Sprintf("Failed to parse api-version response body. API %s is enabled by default.", apiVersion), slog.Any("error", err))
-			return apiVersion
-		}
-
-		for _, supportedMajor := range apiVersionInfo.SupportedMajors {
-			if supportedMajor == 3 {
-				logger.Info("Adapter API v2 is enabled.")
-				return common.ApiV2
-			}
-		}
-	}
-	logger.Info("Adapter API v1 is enabled.")
-	return common.ApiV1
-}
-
-func (rs *RegistrationProvider) StartRegistration() {
-	go rs.registerPeriodically()
-}
-
-func (rs *RegistrationProvider) registerPeriodically() {
-	for {
-		rs.register()
-		time.Sleep(time.Duration(rs.registrationFixedDelay) * time.Millisecond)
-	}
+// StartRegistration starts periodic registration of physical database which is stopped when ctx is cancelled
+func (rs *RegistrationProvider) StartRegistration(ctx context.Context) {
+	go rs.registerPeriodically(ctx)
 }
 
 // register performs one physical database registration attempt and sets the corresponding health status
 // depending on the result.
-func (rs *RegistrationProvider) register() {
+func (rs *RegistrationProvider) register(ctx context.Context) error {
 	defer rs.mutex.Unlock()
 	rs.mutex.Lock()
-	rs.doRegistrationRequest()
+	return rs.doRegistrationRequest(ctx)
 }
 
 // prepareRequestParameters returns method, URL and body for the registration HTTP request.
-// It can cause panic in case of JSON marshalling errors.
-func (rs *RegistrationProvider) prepareRequestParameters(ctx context.Context) (string, string, []byte) {
+func (rs *RegistrationProvider) prepareRequestParameters(ctx context.Context) (string, string, []byte, error) {
 	url := fmt.Sprintf("%s/api/%s/dbaas/opensearch/physical_databases/%s",
 		rs.dbaasAggregator.Address, rs.getAggregatorVersion(), rs.physicalDatabaseId)
 	registrationRequestBody := dao.PhysicalDatabaseRegistrationRequest{
@@ -167,15 +142,16 @@ func (rs *RegistrationProvider) prepareRequestParameters(ctx context.Context) (s
 	body, err := json.Marshal(registrationRequestBody)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to marshal physical database registration body", slog.Any("error", err))
-		panic(err)
+		return "", "", nil, err
 	}
-	return http.MethodPut, url, body
+	return http.MethodPut, url, body, nil
 }
 
 func (rs *RegistrationProvider) modifyReqParams(request *dao.PhysicalDatabaseRegistrationRequest) {
-	if rs.ApiVersion == common.ApiV2 {
+	apiVersion := rs.apiVersion.Get()
+	if apiVersion == common.ApiV2 {
 		request.Metadata = dao.Metadata{
-			ApiVersion: dao.ApiVersion(rs.ApiVersion),
+			ApiVersion: dao.ApiVersion(apiVersion),
 			ApiVersions: dao.ApiVersions{Specs: []dao.ApiVersionsSpec{
 				{
 					SpecRootUrl:     dao.RootUrl,
@@ -187,73 +163,108 @@ func (rs *RegistrationProvider) modifyReqParams(request *dao.PhysicalDatabaseReg
 			SupportedRoles: rs.baseProvider.GetSupportedRoleTypes(),
 			Features: map[string]bool{
 				"multiusers": true,
-				"tls":        rs.baseProvider.IsOpenSearchTlsEnabled(),
+				"tls":        rs.baseProvider.WithApiVersion(apiVersion).IsOpenSearchTlsEnabled(),
 			},
 		}
 		request.Status = rs.status
 	}
 }
 
-// doRegistrationRequest sends an HTTP request to register physical database in DBaaS.
-// It causes panic in case of the registration errors.
-func (rs *RegistrationProvider) doRegistrationRequest() {
+// doRegistrationRequest sends an HTTP request to register physical database in DBaaS, records the result
+// of the attempt and sets the corresponding health status.
+func (rs *RegistrationProvider) doRegistrationRequest(ctx context.Context) error {
 	requestId := common.GenerateUUID()
-	ctx := context.WithValue(context.Background(), common.RequestIdKey, requestId)
-	defer func() {
-		if r := recover(); r != nil {
-			message := fmt.Sprintf("%v", r)
-			// Uncomment this code when server start doesn't depend on registration and migration processes
-			//if strings.Contains(message, "aggregator is available") {
-			//	panic(message)
-			//}
-			logger.InfoContext(ctx, fmt.Sprintf("Recovered from physical database registration panic, set health PROBLEM: %s", message))
-			rs.Health = common.ComponentHealth{Status: "PROBLEM"}
-		} else {
-			logger.InfoContext(ctx, "Successfully registered physical database, set health OK")
-			rs.Health = common.ComponentHealth{Status: "OK"}
-		}
-	}()
-	method, url, body := rs.prepareRequestParameters(ctx)
+	ctx = context.WithValue(ctx, common.RequestIdKey, requestId)
+	ctx, span := tracing.Start(ctx, "register physical database", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("request.id", requestId)))
+	statusCode, err := rs.sendRegistrationRequest(ctx, requestId)
+	span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	tracing.End(span, err)
+	rs.recordAttempt(statusCode, err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to register physical database, set health PROBLEM", slog.Any("error", err))
+		rs.setHealth(common.ComponentHealth{Status: "PROBLEM"})
+		return err
+	}
+	logger.InfoContext(ctx, "Successfully registered physical database, set health OK")
+	rs.setHealth(common.ComponentHealth{Status: "OK"})
+	return nil
+}
+
+// Health returns health of physical database registration depending on the result of the last attempt
+func (rs *RegistrationProvider) Health() common.ComponentHealth {
+	rs.statusMutex.RLock()
+	defer rs.statusMutex.RUnlock()
+	return rs.health
+}
+
+func (rs *RegistrationProvider) setHealth(health common.ComponentHealth) {
+	rs.statusMutex.Lock()
+	defer rs.statusMutex.Unlock()
+	rs.health = health
+}
+
+func (rs *RegistrationProvider) sendRegistrationRequest(ctx context.Context, requestId string) (int, error) {
+	var registrationStatusCode int
+	method, url, body, err := rs.prepareRequestParameters(ctx)
+	if err != nil {
+		return registrationStatusCode, err
+	}
 	logger.DebugContext(ctx, fmt.Sprintf("Request parameters are [%s, %s, %s]", method, url, body))
 	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to prepare request to register physical database", slog.Any("error", err))
-		panic(err)
+		return registrationStatusCode, err
 	}
 	request.SetBasicAuth(rs.dbaasAggregator.Credentials.Username, rs.dbaasAggregator.Credentials.Password)
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set(common.RequestIdKey, requestId)
+	tracing.Inject(ctx, request.Header)
 	response, err := rs.client.Do(request)
 	if err != nil || response.StatusCode >= http.StatusBadRequest {
-		statusCode, healthError := rs.doHealthRequest()
+		if response != nil {
+			registrationStatusCode = response.StatusCode
+			response.Body.Close()
+		}
+		statusCode, healthError := rs.doHealthRequest(ctx)
 		if healthError != nil {
-			panic(healthError)
+			return registrationStatusCode, healthError
 		}
 		if statusCode >= http.StatusBadRequest {
-			panic(fmt.Errorf("aggregator is not available and returns '%d' status code", statusCode))
+			return registrationStatusCode, fmt.Errorf("aggregator is not available and returns '%d' status code", statusCode)
 		}
-		panic(fmt.Errorf("the aggregator is available, but the adapter fails to register with '%d' code and error: %v",
-			response.StatusCode, err))
+		return registrationStatusCode, fmt.Errorf("the aggregator is available, but the adapter fails to register with '%d' code and error: %v",
+			registrationStatusCode, err)
 	}
 	defer response.Body.Close()
-	if rs.ApiVersion == common.ApiV2 {
+	registrationStatusCode = response.StatusCode
+	if rs.apiVersion.Get() == common.ApiV2 {
 		var physicalDatabaseRegistrationResponse dao.PhysicalDatabaseRegistrationResponse
 		if err = common.ProcessBody(response.Body, &physicalDatabaseRegistrationResponse); err != nil {
-			panic(err)
+			return registrationStatusCode, err
+		}
+		if response.StatusCode != http.StatusOK && rs.status == StatusDraining {
+			logger.InfoContext(ctx, "Adapter is draining, migration is postponed until the next start")
+			return registrationStatusCode, nil
 		}
 		if response.StatusCode != http.StatusOK {
+			rs.setPendingInstruction(physicalDatabaseRegistrationResponse.Instruction.Id)
 			if err = rs.performMigration(response.StatusCode, physicalDatabaseRegistrationResponse, ctx); err != nil {
-				panic(err)
+				return registrationStatusCode, err
 			}
 		}
-		rs.status = dao.StatusRun
+		rs.setPendingInstruction("")
+		if rs.status != StatusDraining {
+			rs.status = dao.StatusRun
+		}
 	}
 	logger.InfoContext(ctx, "Checked success code for physical database registration")
+	return registrationStatusCode, nil
 }
 
-func (rs *RegistrationProvider) doHealthRequest() (int, error) {
+func (rs *RegistrationProvider) doHealthRequest(ctx context.Context) (int, error) {
 	url := fmt.Sprintf("%s/health", rs.dbaasAggregator.Address)
-	request, err := http.NewRequest(http.MethodGet, url, nil)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return http.StatusInternalServerError, fmt.Errorf("failed to prepare request to get aggregator's health: %v", err)
 	}
@@ -265,23 +276,26 @@ func (rs *RegistrationProvider) doHealthRequest() (int, error) {
 	return response.StatusCode, nil
 }
 
+// performMigration creates users for absent role types of additional roles received from DBaaS aggregator and
+// reports them. Progress is stored per instruction, so users created before adapter restart are reported
+// instead of creating new ones.
 func (rs *RegistrationProvider) performMigration(statusCode int,
 	physicalDatabaseRegistrationResponse dao.PhysicalDatabaseRegistrationResponse,
 	ctx context.Context) error {
 	logger.InfoContext(ctx, "Starting migration to v3 version of DBaaS aggregator")
-	var physicalDatabaseRoleResponse *http.Response
+	instructionId := physicalDatabaseRegistrationResponse.Instruction.Id
+	progress := rs.loadMigrationProgress(ctx, instructionId)
+	rs.saveMigrationProgress(ctx, progress)
+	additionalRoles := physicalDatabaseRegistrationResponse.Instruction.AdditionalRoles
 	for statusCode == http.StatusAccepted {
 		physicalDatabaseRoleRequest := dao.PhysicalDatabaseRoleRequest{}
-		additionalRoles, err := rs.getAdditionalRoles(physicalDatabaseRegistrationResponse, physicalDatabaseRoleResponse)
-		if err != nil {
-			return err
-		}
+		var reportedRoles []string
 		for _, additionalRole := range additionalRoles {
 			var databaseConnectionProperties []dao.ConnectionProperties
 			var databaseResources []dao.DbResource
 			receivedProperties := additionalRole.ConnectionProperties
 			for _, roleType := range rs.getAbsentRoleTypes(receivedProperties, ctx) {
-				connectionProperties, resources, err := rs.createAdditionalResources(additionalRole, roleType, ctx)
+				connectionProperties, resources, err := rs.createAdditionalResources(additionalRole, roleType, progress, ctx)
 				if err != nil {
 					logger.ErrorContext(ctx, fmt.Sprintf("Unable to create additional resources because of error: %+v", err))
 					physicalDatabaseRoleRequest.Failure = &dao.Failure{
@@ -302,24 +316,45 @@ func (rs *RegistrationProvider) performMigration(statusCode int,
 				Resources:            databaseResources,
 				DbName:               "",
 			})
+			reportedRoles = append(reportedRoles, additionalRole.Id)
 		}
 
-		statusCode, err = rs.performMigrationRequest(ctx, physicalDatabaseRegistrationResponse.Instruction.Id, physicalDatabaseRoleRequest)
+		var err error
+		statusCode, additionalRoles, err = rs.performMigrationRequest(ctx, instructionId, physicalDatabaseRoleRequest)
 		if err != nil {
+			rs.failMigration(ctx, progress, err)
 			return err
 		}
+		if statusCode < http.StatusBadRequest {
+			progress.markReported(reportedRoles)
+			rs.saveMigrationProgress(ctx, progress)
+		}
 	}
 	if statusCode == http.StatusInternalServerError {
-		return fmt.Errorf("migration is not performed")
-	}
+		err := fmt.Errorf("migration is not performed")
+		rs.failMigration(ctx, progress, err)
+		return err
+	}
+	completedAt := time.Now().UTC()
+	progress.Status = MigrationCompleted
+	progress.CompletedAt = &completedAt
+	rs.saveMigrationProgress(ctx, progress)
 	logger.InfoContext(ctx, "Migration to v3 version of DBaaS aggregator is completed successfully")
 	return nil
 }
 
-func (rs *RegistrationProvider) performMigrationRequest(ctx context.Context, id string, obj dao.PhysicalDatabaseRoleRequest) (int, error) {
+func (rs *RegistrationProvider) failMigration(ctx context.Context, progress *MigrationProgress, err error) {
+	progress.Status = MigrationFailed
+	progress.Error = err.Error()
+	rs.saveMigrationProgress(ctx, progress)
+}
+
+// performMigrationRequest reports created resources and returns the next additional roles if aggregator
+// responds with 202 status.
+func (rs *RegistrationProvider) performMigrationRequest(ctx context.Context, id string, obj dao.PhysicalDatabaseRoleRequest) (int, []dao.AdditionalRole, error) {
 	physicalDatabaseRoleResponse, err := rs.doMigrationRequest(id, obj, ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 	defer func() {
 		err = physicalDatabaseRoleResponse.Body.Close()
@@ -329,21 +364,20 @@ func (rs *RegistrationProvider) performMigrationRequest(ctx context.Context, id
 	}()
 
 	statusCode := physicalDatabaseRoleResponse.StatusCode
-	return statusCode, nil
-}
-
-func (rs *RegistrationProvider) getAdditionalRoles(physicalDatabaseRegistrationResponse dao.PhysicalDatabaseRegistrationResponse,
-	response *http.Response) ([]dao.AdditionalRole, error) {
-	if response == nil {
-		return physicalDatabaseRegistrationResponse.Instruction.AdditionalRoles, nil
+	if statusCode != http.StatusAccepted {
+		return statusCode, nil, nil
 	}
-	defer response.Body.Close()
 	var additionalRoles []dao.AdditionalRole
-	err := common.ProcessBody(response.Body, &additionalRoles)
-	return additionalRoles, err
+	if err = common.ProcessBody(physicalDatabaseRoleResponse.Body, &additionalRoles); err != nil {
+		return statusCode, nil, err
+	}
+	return statusCode, additionalRoles, nil
 }
 
-func (rs *RegistrationProvider) createAdditionalResources(additionalRole dao.AdditionalRole, roleType string, ctx context.Context) (dao.ConnectionProperties, []dao.DbResource, error) {
+// createAdditionalResources creates user for the role type of additional role. If the user is already created
+// during the previous attempt of migration, its password is regenerated and the same user is reported.
+func (rs *RegistrationProvider) createAdditionalResources(additionalRole dao.AdditionalRole, roleType string,
+	progress *MigrationProgress, ctx context.Context) (dao.ConnectionProperties, []dao.DbResource, error) {
 	logger.InfoContext(ctx, fmt.Sprintf("Creating additional resources for %s role type", roleType))
 	var connectionProperties dao.ConnectionProperties
 	resourcePrefixProperty := additionalRole.ConnectionProperties[0]["resourcePrefix"]
@@ -356,7 +390,24 @@ func (rs *RegistrationProvider) createAdditionalResources(additionalRole dao.Add
 		}
 	}
 	resourcePrefix := common.ConvertAnyToString(resourcePrefixProperty)
-	username, password, resources, err := rs.baseProvider.CreateUserByPrefix(resourcePrefix, "", resourcePrefix, roleType, ctx)
+	var username, password string
+	var resources []dao.DbResource
+	var err error
+	if migratedUser := progress.findUser(additionalRole.Id, roleType); migratedUser != nil {
+		logger.InfoContext(ctx, fmt.Sprintf("User '%s' is already created for %s role type", migratedUser.Username, roleType))
+		username, password, resources, err = rs.baseProvider.CreateOrUpdateUser(migratedUser.Username, "", resourcePrefix, roleType, ctx)
+	} else {
+		username, password, resources, err = rs.baseProvider.CreateUserByPrefix(resourcePrefix, "", resourcePrefix, roleType, ctx)
+		if err == nil {
+			progress.Users = append(progress.Users, MigratedUser{
+				AdditionalRoleId: additionalRole.Id,
+				RoleType:         roleType,
+				Username:         username,
+				ResourcePrefix:   resourcePrefix,
+			})
+			rs.saveMigrationProgress(ctx, progress)
+		}
+	}
 	if err != nil {
 		return connectionProperties, nil, err
 	}
@@ -411,55 +462,34 @@ func (rs *RegistrationProvider) doMigrationRequest(instructionID string, request
 	return response, nil
 }
 
-func (rs *RegistrationProvider) ReadLabelsFile(ctx context.Context) map[string]string {
-	var labels map[string]string
-	file, err := os.ReadFile(rs.labelsFileLocation)
-	if err != nil {
-		logger.WarnContext(ctx, fmt.Sprintf("Skipping labels file, cannot read it: %s", rs.labelsFileLocation))
-		return labels
-	}
-	err = json.Unmarshal(file, &labels)
-	if err != nil {
-		logger.WarnContext(ctx, fmt.Sprintf("Failed to parse labels file %s", rs.labelsFileLocation), slog.Any("error", err))
-	}
-	logger.DebugContext(ctx, fmt.Sprintf("Read labels: %v", labels))
-	return labels
-}
-
-func (rs *RegistrationProvider) ForceRegistrationHandler() func(w http.ResponseWriter, r *http.Request) {
+// ForceRegistrationHandler registers physical database in background, registration is stopped when serverCtx
+// is cancelled.
+func (rs *RegistrationProvider) ForceRegistrationHandler(serverCtx context.Context) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := common.PrepareContext(r)
 		logger.InfoContext(ctx, "Received request to force register physical database")
-		rs.executor.Submit(rs.RegisterWithRetry)
+		rs.executor.Submit(func() {
+			rs.RegisterWithRetry(serverCtx)
+		})
 		w.WriteHeader(http.StatusAccepted)
 	}
 }
 
 // RegisterWithRetry performs attempts to register physical database in DBaaS during the retryTimeSec.
-// If one attempt fails, next attempt is being performed after the retryDelaySec seconds.
-// Health status is being updated after each registration attempt.
-func (rs *RegistrationProvider) RegisterWithRetry() {
-	defer func() {
-		if r := recover(); r != nil {
-			logger.Info(fmt.Sprintf("Recovered from force physical database registration panic, set health PROBLEM: %v", r))
-			rs.Health = common.ComponentHealth{Status: "PROBLEM"}
-		}
-	}()
-	defer rs.mutex.Unlock()
-
-	rs.mutex.Lock()
-
+// If one attempt fails, next attempt is being performed after the retryDelaySec seconds, attempts are stopped
+// when ctx is cancelled. Registration mutex is held during attempts only, so periodic registration and draining
+// are not blocked between them. Health status is being updated after each registration attempt.
+func (rs *RegistrationProvider) RegisterWithRetry(ctx context.Context) {
 	interval := time.Duration(rs.registrationRetryDelay) * time.Millisecond
 	timeout := time.Duration(rs.registrationRetryTime) * time.Millisecond
-	err := wait.PollImmediate(interval, timeout, func() (done bool, err error) {
-		rs.doRegistrationRequest()
-		return true, nil
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+		return rs.register(ctx) == nil, nil
 	})
 	if err != nil {
-		logger.Error("Force physical db registration has failed with error", slog.Any("error", err))
+		logger.ErrorContext(ctx, "Force physical db registration has failed with error", slog.Any("error", err))
 		return
 	}
-	logger.Debug("Force physical db registration finished successfully")
+	logger.DebugContext(ctx, "Force physical db registration finished successfully")
 }
 
 func (rs *RegistrationProvider) GetPhysicalDatabaseHandler() func(w http.ResponseWriter, r *http.Request) {
@@ -473,7 +503,7 @@ func (rs *RegistrationProvider) GetPhysicalDatabaseHandler() func(w http.Respons
 		responseBody, err := json.Marshal(physicalDatabase)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to marshal physical database response to json", slog.Any("error", err))
-			common.ProcessResponseBody(ctx, w, []byte(err.Error()), http.StatusInternalServerError)
+			common.WriteError(ctx, w, err)
 			return
 		}
 		common.ProcessResponseBody(ctx, w, responseBody, 0)
@@ -481,7 +511,7 @@ func (rs *RegistrationProvider) GetPhysicalDatabaseHandler() func(w http.Respons
 }
 
 func (rs *RegistrationProvider) getAggregatorVersion() string {
-	dbaasApiVersion := rs.ApiVersion
+	dbaasApiVersion := rs.apiVersion.Get()
 	dbaasAggregatorVersion := "v1"
 
 	if dbaasApiVersion == common.ApiV2 {
@@ -15,14 +15,22 @@
 package physical
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
 	cl "github.com/Netcracker/dbaas-opensearch-adapter/client"
+	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
 	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,8 +64,49 @@ func TestRegistration(t *testing.T) {
 		basic.NewBaseProvider(nil),
 	)
 
-	registrationService.doRegistrationRequest()
-	assert.Equal(t, registrationService.Health, common.ComponentHealth{Status: "OK"})
+	err := registrationService.doRegistrationRequest(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, registrationService.Health(), common.ComponentHealth{Status: "OK"})
+}
+
+func TestRegisterWithRetryStopsOnCancel(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer testServer.Close()
+	registrationService := NewRegistrationProvider(testServer.URL, dao.BasicAuth{Username: "cluster-dba", Password: "test"},
+		"", nil, 150000, 60000, 5000, "tmp-test", "http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+		dao.BasicAuth{Username: "dbaas-aggregator", Password: "dbaas-aggregator"}, basic.NewBaseProvider(nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		registrationService.RegisterWithRetry(ctx)
+		close(stopped)
+	}()
+	assert.Eventually(t, func() bool {
+		return registrationService.Status().Attempts > 0
+	}, time.Second, 10*time.Millisecond)
+
+	// registration mutex is not held while waiting for the next attempt
+	registered := make(chan error)
+	go func() {
+		registered <- registrationService.register(context.Background())
+	}()
+	select {
+	case err := <-registered:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "registration is blocked by retries of forced registration")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		assert.Fail(t, "forced registration is not stopped when context is cancelled")
+	}
+	assert.Equal(t, common.ComponentHealth{Status: "PROBLEM"}, registrationService.Health())
 }
 
 func TestFailedRegistration(t *testing.T) {
@@ -90,8 +139,13 @@ func TestFailedRegistration(t *testing.T) {
 		basic.NewBaseProvider(nil),
 	)
 
-	registrationService.doRegistrationRequest()
-	assert.Equal(t, registrationService.Health, common.ComponentHealth{Status: "PROBLEM"})
+	err := registrationService.doRegistrationRequest(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, registrationService.Health(), common.ComponentHealth{Status: "PROBLEM"})
+	status := registrationService.Status()
+	assert.Equal(t, 1, status.ConsecutiveFailures)
+	assert.Contains(t, status.LastError, "aggregator is not available")
+	assert.Nil(t, status.LastSuccess)
 }
 
 func TestApiVersion(t *testing.T) {
@@ -104,7 +158,7 @@ func TestApiVersion(t *testing.T) {
 	}))
 	defer func() { testServer.Close() }()
 
-	apiVersion := getApiVersion(testServer.URL, cl.ConfigureClient())
+	apiVersion, _ := negotiateApiVersion(context.Background(), testServer.URL, cl.ConfigureClient())
 	assert.Equal(t, apiVersion, common.ApiV2)
 }
 
@@ -118,7 +172,7 @@ func TestApiVersionWhenV3NotSupported(t *testing.T) {
 	}))
 	defer func() { testServer.Close() }()
 
-	apiVersion := getApiVersion(testServer.URL, cl.ConfigureClient())
+	apiVersion, _ := negotiateApiVersion(context.Background(), testServer.URL, cl.ConfigureClient())
 	assert.Equal(t, apiVersion, common.ApiV1)
 }
 
@@ -132,7 +186,7 @@ func TestApiVersionWithIncorrectData(t *testing.T) {
 	}))
 	defer func() { testServer.Close() }()
 
-	apiVersion := getApiVersion(testServer.URL, cl.ConfigureClient())
+	apiVersion, _ := negotiateApiVersion(context.Background(), testServer.URL, cl.ConfigureClient())
 	assert.Equal(t, apiVersion, common.ApiV2)
 }
 
@@ -146,6 +200,440 @@ func TestApiVersionWhenApiVersionNotFound(t *testing.T) {
 	}))
 	defer func() { testServer.Close() }()
 
-	apiVersion := getApiVersion(testServer.URL, cl.ConfigureClient())
+	apiVersion, _ := negotiateApiVersion(context.Background(), testServer.URL, cl.ConfigureClient())
 	assert.Equal(t, apiVersion, common.ApiV1)
 }
+
+func TestPeriodicRegistrationRetriesAndStops(t *testing.T) {
+	var requests atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut && requests.Add(1) <= 2 {
+			res.WriteHeader(500)
+			return
+		}
+		res.WriteHeader(200)
+	}))
+	defer func() { testServer.Close() }()
+
+	registrationService := NewRegistrationProvider(
+		testServer.URL,
+		dao.BasicAuth{
+			Username: "cluster-dba",
+			Password: "test",
+		},
+		"",
+		nil,
+		20,
+		60000,
+		5000,
+		"tmp-test",
+		"http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+		dao.BasicAuth{
+			Username: "dbaas-aggregator",
+			Password: "dbaas-aggregator",
+		},
+		basic.NewBaseProvider(nil),
+	)
+	registrationService.apiVersion.Set(common.ApiV1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		registrationService.registerPeriodically(ctx)
+		close(stopped)
+	}()
+	assert.Eventually(t, func() bool {
+		return registrationService.Status().LastSuccess != nil
+	}, 5*time.Second, 5*time.Millisecond)
+	status := registrationService.Status()
+	assert.GreaterOrEqual(t, status.Attempts, 3)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Empty(t, status.LastError)
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "registration is not stopped after context cancellation")
+	}
+	assert.Equal(t, StateStopped, registrationService.Status().State)
+}
+
+func TestRegistrationBackoff(t *testing.T) {
+	backoff := registrationBackoff{initial: time.Second, max: 4 * time.Second}
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		delay := backoff.next()
+		assert.InDelta(t, float64(expected), float64(delay), float64(expected)*registrationJitter)
+	}
+	backoff.reset()
+	assert.InDelta(t, float64(time.Second), float64(backoff.next()), float64(time.Second)*registrationJitter)
+}
+
+func TestRegistrationStatus(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut {
+			res.WriteHeader(403)
+			return
+		}
+		res.WriteHeader(200)
+	}))
+	defer func() { testServer.Close() }()
+
+	registrationService := NewRegistrationProvider(
+		testServer.URL,
+		dao.BasicAuth{
+			Username: "cluster-dba",
+			Password: "test",
+		},
+		"",
+		nil,
+		150000,
+		60000,
+		5000,
+		"tmp-test",
+		"http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+		dao.BasicAuth{
+			Username: "dbaas-aggregator",
+			Password: "dbaas-aggregator",
+		},
+		basic.NewBaseProvider(nil),
+	)
+	registrationService.apiVersion.Set(common.ApiV1)
+	for i := 0; i < registrationHistorySize+5; i++ {
+		assert.NotNil(t, registrationService.doRegistrationRequest(context.Background()))
+	}
+
+	recorder := httptest.NewRecorder()
+	registrationService.RegistrationStatusHandler()(recorder, httptest.NewRequest(http.MethodGet, "/registration", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var status RegistrationStatus
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, "tmp-test", status.PhysicalDatabaseId)
+	assert.Equal(t, common.ApiV1, status.ApiVersion)
+	assert.Equal(t, testServer.URL, status.AggregatorUrl)
+	assert.Equal(t, registrationHistorySize+5, status.Attempts)
+	assert.Equal(t, 403, status.LastStatusCode)
+	assert.Len(t, status.History, registrationHistorySize)
+	assert.Equal(t, 403, status.History[0].StatusCode)
+	assert.Contains(t, status.History[0].Error, "fails to register with '403' code")
+	assert.False(t, status.History[0].Time.Before(status.History[1].Time))
+}
+
+func TestPendingMigrationInstruction(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPut:
+			res.WriteHeader(202)
+			_, _ = res.Write([]byte(`{"instruction":{"id":"instruction-1"}}`))
+		case http.MethodPost:
+			res.WriteHeader(500)
+		default:
+			res.WriteHeader(200)
+		}
+	}))
+	defer func() { testServer.Close() }()
+
+	registrationService := NewRegistrationProvider(
+		testServer.URL,
+		dao.BasicAuth{
+			Username: "cluster-dba",
+			Password: "test",
+		},
+		"",
+		nil,
+		150000,
+		60000,
+		5000,
+		"tmp-test",
+		"http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+		dao.BasicAuth{
+			Username: "dbaas-aggregator",
+			Password: "dbaas-aggregator",
+		},
+		basic.NewBaseProvider(nil),
+	)
+	registrationService.apiVersion.Set(common.ApiV2)
+
+	err := registrationService.doRegistrationRequest(context.Background())
+	assert.NotNil(t, err)
+	status := registrationService.Status()
+	assert.Equal(t, "instruction-1", status.PendingInstructionId)
+	assert.Equal(t, 202, status.LastStatusCode)
+}
+
+func TestReloadLabels(t *testing.T) {
+	labelsFile := filepath.Join(t.TempDir(), "labels.json")
+	registrationService := &RegistrationProvider{labelsFileLocation: labelsFile}
+	ctx := context.Background()
+
+	assert.Nil(t, registrationService.ReadLabelsFile(ctx))
+	assert.Nil(t, os.WriteFile(labelsFile, []byte(`{"clusterName":"opensearch"}`), 0644))
+	changed, err := registrationService.reloadLabels(ctx)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	assert.Nil(t, os.WriteFile(labelsFile, []byte(`{"clusterName":`), 0644))
+	_, err = registrationService.reloadLabels(ctx)
+	assert.NotNil(t, err)
+	changed, err = registrationService.reloadLabels(ctx)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, map[string]string{"clusterName": "opensearch"}, registrationService.ReadLabelsFile(ctx))
+
+	assert.Nil(t, os.WriteFile(labelsFile, []byte(`{"clusterName":"opensearch", "region": "eu"}`), 0644))
+	assert.Equal(t, map[string]string{"clusterName": "opensearch", "region": "eu"}, registrationService.ReadLabelsFile(ctx))
+	changed, err = registrationService.reloadLabels(ctx)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+}
+
+func TestLabelsChangeTriggersRegistration(t *testing.T) {
+	var labels atomic.Value
+	labels.Store("")
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut {
+			var request dao.PhysicalDatabaseRegistrationRequest
+			assert.Nil(t, json.NewDecoder(req.Body).Decode(&request))
+			labels.Store(request.Labels["region"])
+		}
+		res.WriteHeader(200)
+	}))
+	defer func() { testServer.Close() }()
+	labelsFile := filepath.Join(t.TempDir(), "labels.json")
+	assert.Nil(t, os.WriteFile(labelsFile, []byte(`{"region":"eu"}`), 0644))
+
+	registrationService := NewRegistrationProvider(
+		testServer.URL,
+		dao.BasicAuth{
+			Username: "cluster-dba",
+			Password: "test",
+		},
+		labelsFile,
+		nil,
+		150000,
+		60000,
+		5000,
+		"tmp-test",
+		"http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+		dao.BasicAuth{
+			Username: "dbaas-aggregator",
+			Password: "dbaas-aggregator",
+		},
+		basic.NewBaseProvider(nil),
+	)
+	registrationService.apiVersion.Set(common.ApiV1)
+	assert.Nil(t, registrationService.register(context.Background()))
+	assert.Equal(t, "eu", labels.Load())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registrationService.WatchLabels(ctx, 5*time.Millisecond)
+	assert.Nil(t, os.WriteFile(labelsFile, []byte(`{"region":"us"}`), 0644))
+	assert.Eventually(t, func() bool {
+		return labels.Load() == "us"
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestMigrationIsResumedAfterRestart(t *testing.T) {
+	var failReport atomic.Bool
+	failReport.Store(true)
+	var reported []dao.PhysicalDatabaseRoleRequest
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPut:
+			res.WriteHeader(202)
+			_, _ = res.Write([]byte(`{"instruction":{"id":"instruction-2","additionalRoles":[{"id":"role-1","connectionProperties":[{"resourcePrefix":"test","role":"admin"}]}]}}`))
+		case http.MethodPost:
+			var request dao.PhysicalDatabaseRoleRequest
+			assert.Nil(t, json.NewDecoder(req.Body).Decode(&request))
+			reported = append(reported, request)
+			if failReport.Load() {
+				res.WriteHeader(500)
+				return
+			}
+			res.WriteHeader(200)
+		default:
+			res.WriteHeader(200)
+		}
+	}))
+	defer func() { testServer.Close() }()
+
+	opensearch := &cluster.Opensearch{Host: "localhost", Port: 9200, Protocol: common.Http, Client: common.NewClient()}
+	newRegistrationService := func() *RegistrationProvider {
+		registrationService := NewRegistrationProvider(
+			testServer.URL,
+			dao.BasicAuth{
+				Username: "cluster-dba",
+				Password: "test",
+			},
+			"",
+			nil,
+			150000,
+			60000,
+			5000,
+			"tmp-test",
+			"http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+			dao.BasicAuth{
+				Username: "dbaas-aggregator",
+				Password: "dbaas-aggregator",
+			},
+			basic.NewBaseProvider(opensearch),
+		)
+		registrationService.apiVersion.Set(common.ApiV2)
+		registrationService.Migrations = NewMigrationStore(opensearch.Client)
+		return registrationService
+	}
+	ctx := context.Background()
+
+	assert.NotNil(t, newRegistrationService().doRegistrationRequest(ctx))
+	progress, err := NewMigrationStore(opensearch.Client).Get(ctx, "instruction-2")
+	assert.Nil(t, err)
+	assert.Equal(t, MigrationFailed, progress.Status)
+	assert.Len(t, progress.Users, 3)
+	assert.False(t, progress.Users[0].Reported)
+
+	failReport.Store(false)
+	assert.Nil(t, newRegistrationService().doRegistrationRequest(ctx))
+	assert.Len(t, reported, 2)
+	usernames := func(request dao.PhysicalDatabaseRoleRequest) []interface{} {
+		var result []interface{}
+		for _, properties := range request.Success[0].ConnectionProperties {
+			result = append(result, properties["username"])
+		}
+		return result
+	}
+	assert.Equal(t, usernames(reported[0]), usernames(reported[1]))
+
+	progress, err = NewMigrationStore(opensearch.Client).Get(ctx, "instruction-2")
+	assert.Nil(t, err)
+	assert.Equal(t, MigrationCompleted, progress.Status)
+	assert.NotNil(t, progress.CompletedAt)
+	for _, user := range progress.Users {
+		assert.True(t, user.Reported)
+		assert.Equal(t, "test", user.ResourcePrefix)
+	}
+}
+
+func TestMigrationProgressHandler(t *testing.T) {
+	client := common.NewClient()
+	registrationService := &RegistrationProvider{Migrations: NewMigrationStore(client)}
+	ctx := context.Background()
+	assert.Nil(t, registrationService.Migrations.Save(ctx, &MigrationProgress{InstructionId: "instruction-3", Status: MigrationInProgress}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/migrations", registrationService.MigrationProgressHandler())
+	router.HandleFunc("/migrations/{instructionId}", registrationService.MigrationProgressHandler())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/migrations/instruction-3", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var progress MigrationProgress
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &progress))
+	assert.Equal(t, MigrationInProgress, progress.Status)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/migrations/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/migrations", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var migrations []MigrationProgress
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &migrations))
+	assert.Len(t, migrations, 1)
+}
+
+func TestDrainReportsStatus(t *testing.T) {
+	var status atomic.Value
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPut {
+			var request dao.PhysicalDatabaseRegistrationRequest
+			assert.Nil(t, json.NewDecoder(req.Body).Decode(&request))
+			status.Store(request.Status)
+			res.WriteHeader(202)
+			_, _ = res.Write([]byte(`{"instruction":{"id":"instruction-4"}}`))
+			return
+		}
+		res.WriteHeader(200)
+	}))
+	defer func() { testServer.Close() }()
+
+	registrationService := NewRegistrationProvider(
+		testServer.URL,
+		dao.BasicAuth{
+			Username: "cluster-dba",
+			Password: "test",
+		},
+		"",
+		nil,
+		150000,
+		60000,
+		5000,
+		"tmp-test",
+		"http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+		dao.BasicAuth{
+			Username: "dbaas-aggregator",
+			Password: "dbaas-aggregator",
+		},
+		basic.NewBaseProvider(nil),
+	)
+	registrationService.apiVersion.Set(common.ApiV2)
+
+	assert.Nil(t, registrationService.Drain(context.Background()))
+	assert.Equal(t, StatusDraining, status.Load())
+	assert.Empty(t, registrationService.Status().PendingInstructionId)
+}
+
+func TestApiVersionIsCheckedAgain(t *testing.T) {
+	var apiVersionResponse atomic.Value
+	apiVersionResponse.Store(`{"major":2,"minor":4,"supportedMajors":[1,2]}`)
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api-version" {
+			res.WriteHeader(200)
+			_, _ = res.Write([]byte(apiVersionResponse.Load().(string)))
+			return
+		}
+		res.WriteHeader(200)
+	}))
+	defer func() { testServer.Close() }()
+
+	baseProvider := basic.NewBaseProvider(nil)
+	registrationService := NewRegistrationProvider(
+		testServer.URL,
+		dao.BasicAuth{
+			Username: "cluster-dba",
+			Password: "test",
+		},
+		"",
+		nil,
+		150000,
+		60000,
+		5000,
+		"tmp-test",
+		"http://dbaas-opensearch-adapter.elasticsearch-cluster:8080",
+		dao.BasicAuth{
+			Username: "dbaas-aggregator",
+			Password: "dbaas-aggregator",
+		},
+		baseProvider,
+	)
+	registrationService.negotiate(context.Background())
+	assert.Equal(t, common.ApiV1, registrationService.Status().ApiVersion)
+	assert.Equal(t, common.ApiV1, baseProvider.Version())
+	assert.Equal(t, []int{1, 2}, registrationService.Status().SupportedMajors)
+
+	apiVersionResponse.Store(`{"major":3,"minor":1,"supportedMajors":[2,3]}`)
+	registrationService.checkApiVersion(context.Background())
+	status := registrationService.Status()
+	assert.Equal(t, common.ApiV2, status.ApiVersion)
+	assert.Equal(t, common.ApiV2, baseProvider.Version())
+	assert.Equal(t, common.ApiV1, baseProvider.WithApiVersion(common.ApiV1).Version())
+	assert.Equal(t, []int{2, 3}, status.SupportedMajors)
+	assert.Equal(t, 1, status.SupportedMajorsChanges)
+
+	apiVersionResponse.Store(`{"major":3,"minor":1}`)
+	registrationService.checkApiVersion(context.Background())
+	status = registrationService.Status()
+	assert.Equal(t, common.ApiV2, status.ApiVersion)
+	assert.Equal(t, 1, status.SupportedMajorsChanges)
+}
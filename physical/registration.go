@@ -0,0 +1,233 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/metrics"
+)
+
+// RegistrationState is the state of periodic physical database registration
+type RegistrationState string
+
+const (
+	// StateRegistering means that registration request is being performed
+	StateRegistering RegistrationState = "REGISTERING"
+	// StateRegistered means that the last registration succeeded and the next one is performed after fixed delay
+	StateRegistered RegistrationState = "REGISTERED"
+	// StateBackingOff means that the last registration failed and the next one is performed after growing delay
+	StateBackingOff RegistrationState = "BACKING_OFF"
+	// StateStopped means that periodic registration is stopped because the server is shut down
+	StateStopped RegistrationState = "STOPPED"
+)
+
+const (
+	// initialRegistrationBackoff is the delay before the first retry of failed registration, so adapter is registered
+	// quickly after restart if aggregator is not available for a short time
+	initialRegistrationBackoff = time.Second
+	// registrationJitter is the part of delay which is randomized to spread registrations of several adapters
+	registrationJitter = 0.2
+	// registrationHistorySize is the number of the last registration attempts kept in status
+	registrationHistorySize = 20
+)
+
+// RegistrationStatus describes the state of periodic registration and results of registration attempts
+type RegistrationStatus struct {
+	PhysicalDatabaseId string `json:"physicalDatabaseId"`
+	ApiVersion         string `json:"apiVersion"`
+	AggregatorUrl      string `json:"aggregatorUrl"`
+	// SupportedMajors are major versions of DBaaS aggregator API, SupportedMajorsChanges is the number of their
+	// changes detected by periodic version check
+	SupportedMajors        []int                 `json:"supportedMajors,omitempty"`
+	SupportedMajorsChanges int                   `json:"supportedMajorsChanges"`
+	State                  RegistrationState     `json:"state"`
+	Attempts               int                   `json:"attempts"`
+	ConsecutiveFailures    int                   `json:"consecutiveFailures"`
+	LastAttempt            *time.Time            `json:"lastAttempt,omitempty"`
+	LastSuccess            *time.Time            `json:"lastSuccess,omitempty"`
+	LastStatusCode         int                   `json:"lastStatusCode,omitempty"`
+	LastError              string                `json:"lastError,omitempty"`
+	NextAttempt            *time.Time            `json:"nextAttempt,omitempty"`
+	PendingInstructionId   string                `json:"pendingInstructionId,omitempty"`
+	History                []RegistrationAttempt `json:"history"`
+}
+
+// RegistrationAttempt is the result of one registration attempt, StatusCode is not specified if aggregator
+// has not responded to registration request.
+type RegistrationAttempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// registrationBackoff calculates delays between failed registration attempts. Delay is doubled after each failure
+// up to the max value and randomized by jitter.
+type registrationBackoff struct {
+	initial time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+func (b *registrationBackoff) next() time.Duration {
+	if b.current == 0 {
+		b.current = b.initial
+	} else {
+		b.current = min(b.current*2, b.max)
+	}
+	return withJitter(b.current)
+}
+
+func (b *registrationBackoff) reset() {
+	b.current = 0
+}
+
+func withJitter(delay time.Duration) time.Duration {
+	jitter := time.Duration(float64(delay) * registrationJitter * (2*rand.Float64() - 1))
+	return delay + jitter
+}
+
+// Status returns the current state of periodic registration, the history starts from the latest attempt
+func (rs *RegistrationProvider) Status() RegistrationStatus {
+	rs.statusMutex.RLock()
+	defer rs.statusMutex.RUnlock()
+	status := rs.registrationStatus
+	status.PhysicalDatabaseId = rs.physicalDatabaseId
+	status.ApiVersion = rs.apiVersion.Get()
+	status.AggregatorUrl = rs.dbaasAggregator.Address
+	status.SupportedMajors = slices.Clone(rs.supportedMajors)
+	status.History = make([]RegistrationAttempt, len(rs.registrationStatus.History))
+	for i, attempt := range rs.registrationStatus.History {
+		status.History[len(status.History)-1-i] = attempt
+	}
+	return status
+}
+
+func (rs *RegistrationProvider) RegistrationStatusHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := common.PrepareContext(r)
+		logger.InfoContext(ctx, "Received request to get physical database registration status")
+		responseBody, err := json.Marshal(rs.Status())
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal registration status to json", slog.Any("error", err))
+			common.WriteError(ctx, w, err)
+			return
+		}
+		common.ProcessResponseBody(ctx, w, responseBody, 0)
+	}
+}
+
+// registerPeriodically registers physical database until ctx is cancelled. The first registration is performed
+// immediately, failed registrations are retried with exponential backoff and successful ones are repeated
+// after the fixed delay. API version of aggregator is checked before each registration.
+func (rs *RegistrationProvider) registerPeriodically(ctx context.Context) {
+	fixedDelay := time.Duration(rs.registrationFixedDelay) * time.Millisecond
+	backoff := registrationBackoff{initial: min(initialRegistrationBackoff, fixedDelay), max: fixedDelay}
+	state := StateRegistering
+	negotiated := false
+	var delay time.Duration
+	for {
+		switch state {
+		case StateRegistering:
+			rs.setState(state, time.Time{})
+			if negotiated {
+				rs.checkApiVersion(ctx)
+			} else {
+				rs.negotiate(ctx)
+				negotiated = true
+			}
+			if err := rs.register(ctx); err != nil {
+				state = StateBackingOff
+				delay = backoff.next()
+			} else {
+				state = StateRegistered
+				backoff.reset()
+				delay = withJitter(fixedDelay)
+			}
+		case StateRegistered, StateBackingOff:
+			rs.setState(state, time.Now().Add(delay))
+			logger.DebugContext(ctx, fmt.Sprintf("Next physical database registration is in %s", delay))
+			select {
+			case <-ctx.Done():
+				state = StateStopped
+			case <-time.After(delay):
+				state = StateRegistering
+			}
+		case StateStopped:
+			rs.setState(state, time.Time{})
+			logger.InfoContext(ctx, "Physical database registration is stopped", slog.Any("reason", ctx.Err()))
+			return
+		}
+	}
+}
+
+// Drain reports StatusDraining to DBaaS aggregator, so new databases are not placed on the adapter which
+// is shutting down
+func (rs *RegistrationProvider) Drain(ctx context.Context) error {
+	rs.mutex.Lock()
+	rs.status = StatusDraining
+	rs.mutex.Unlock()
+	return rs.register(ctx)
+}
+
+func (rs *RegistrationProvider) setState(state RegistrationState, nextAttempt time.Time) {
+	rs.statusMutex.Lock()
+	defer rs.statusMutex.Unlock()
+	rs.registrationStatus.State = state
+	rs.registrationStatus.NextAttempt = nil
+	if !nextAttempt.IsZero() {
+		rs.registrationStatus.NextAttempt = &nextAttempt
+	}
+}
+
+func (rs *RegistrationProvider) setPendingInstruction(instructionId string) {
+	rs.statusMutex.Lock()
+	defer rs.statusMutex.Unlock()
+	rs.registrationStatus.PendingInstructionId = instructionId
+}
+
+// recordAttempt stores the result of registration attempt, statusCode is the status of registration response
+// or 0 if aggregator has not responded.
+func (rs *RegistrationProvider) recordAttempt(statusCode int, attemptErr error) {
+	metrics.ObserveRegistrationAttempt(attemptErr)
+	rs.statusMutex.Lock()
+	defer rs.statusMutex.Unlock()
+	now := time.Now()
+	attempt := RegistrationAttempt{Time: now, StatusCode: statusCode}
+	if attemptErr != nil {
+		attempt.Error = attemptErr.Error()
+	}
+	history := append(rs.registrationStatus.History, attempt)
+	rs.registrationStatus.History = history[max(0, len(history)-registrationHistorySize):]
+	rs.registrationStatus.Attempts++
+	rs.registrationStatus.LastAttempt = &now
+	rs.registrationStatus.LastStatusCode = statusCode
+	if attemptErr != nil {
+		rs.registrationStatus.ConsecutiveFailures++
+		rs.registrationStatus.LastError = attemptErr.Error()
+		return
+	}
+	rs.registrationStatus.ConsecutiveFailures = 0
+	rs.registrationStatus.LastSuccess = &now
+	rs.registrationStatus.LastError = ""
+}
@@ -0,0 +1,194 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// BasicAuthMethod authenticates principals by username and password
+	BasicAuthMethod = "basic"
+	// MtlsAuthMethod authenticates principals by client certificate verified against trusted CA
+	MtlsAuthMethod = "mtls"
+)
+
+var authMethods = []string{BasicAuthMethod, MtlsAuthMethod}
+
+// Authorizer protects handler of the route, only principals with aggregator scope or one of the given scopes
+// are allowed to use it
+type Authorizer func(scopes []string, f func(w http.ResponseWriter, r *http.Request)) http.Handler
+
+// AuthMethods are authentication methods allowed for routes of adapter API. Routes are identified by their
+// path templates, routes which are not listed allow Default methods.
+type AuthMethods struct {
+	Default []string
+	Routes  map[string][]string
+}
+
+// ParseAuthMethods reads comma-separated list of default methods and JSON object with lists of methods by route
+// path templates
+func ParseAuthMethods(defaults string, routes string) (AuthMethods, error) {
+	methods := AuthMethods{Default: splitAuthMethods(defaults)}
+	if strings.TrimSpace(routes) != "" {
+		if err := json.Unmarshal([]byte(routes), &methods.Routes); err != nil {
+			return AuthMethods{}, fmt.Errorf("route authentication methods must be JSON object with lists of methods: %w", err)
+		}
+	}
+	var validator common.Validator
+	validateAuthMethods(&validator, "default", methods.Default)
+	for template, routeMethods := range methods.Routes {
+		validateAuthMethods(&validator, template, routeMethods)
+	}
+	return methods, validator.Err()
+}
+
+func splitAuthMethods(value string) []string {
+	var methods []string
+	for _, method := range strings.Split(value, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+func validateAuthMethods(validator *common.Validator, field string, methods []string) {
+	if len(methods) == 0 {
+		validator.Add(field, "must not be empty")
+	}
+	for i, method := range methods {
+		validator.OneOf(fmt.Sprintf("%s[%d]", field, i), method, authMethods)
+	}
+}
+
+// forRoute returns methods allowed for the route with the path template
+func (m AuthMethods) forRoute(template string) []string {
+	if methods, ok := m.Routes[template]; ok {
+		return methods
+	}
+	return m.Default
+}
+
+// Uses checks that the method is allowed for any route
+func (m AuthMethods) Uses(method string) bool {
+	if slices.Contains(m.Default, method) {
+		return true
+	}
+	for _, methods := range m.Routes {
+		if slices.Contains(methods, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownRoutes returns route path templates which are configured, but not registered in router
+func (m AuthMethods) unknownRoutes(r *mux.Router) []string {
+	registered := map[string]bool{}
+	_ = r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if template, err := route.GetPathTemplate(); err == nil {
+			registered[template] = true
+		}
+		return nil
+	})
+	var unknown []string
+	for template := range m.Routes {
+		if !registered[template] {
+			unknown = append(unknown, template)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+// BasicAuthorizer authenticates principals with basic authentication and checks their scopes
+func BasicAuthorizer(credentials *Credentials, realm string) Authorizer {
+	return NewAuthorizer(credentials, AuthMethods{Default: []string{BasicAuthMethod}}, realm)
+}
+
+// NewAuthorizer authenticates principals with methods allowed for the route and checks their scopes. When both
+// methods are allowed, verified client certificate is checked first and basic authentication is used if
+// certificate is not presented or does not match any principal.
+func NewAuthorizer(credentials *Credentials, methods AuthMethods, realm string) Authorizer {
+	return func(scopes []string, f func(w http.ResponseWriter, r *http.Request)) http.Handler {
+		h := http.HandlerFunc(f)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			allowed := methods.forRoute(common.RouteTemplate(r))
+			principal, err := authenticate(credentials, allowed, r)
+			if err != nil {
+				if slices.Contains(allowed, BasicAuthMethod) {
+					w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
+				}
+				common.WriteError(ctx, w, err)
+				return
+			}
+			if !principal.allows(scopes) {
+				common.WriteError(ctx, w, common.NewError(common.ErrForbidden,
+					fmt.Sprintf("'%s' is not allowed to use this API, it requires one of scopes: %s",
+						principal.username, strings.Join(append([]string{AggregatorScope}, scopes...), ", "))))
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(common.WithPrincipal(ctx, principal.username)))
+		})
+	}
+}
+
+func authenticate(credentials *Credentials, allowed []string, r *http.Request) (credential, error) {
+	if slices.Contains(allowed, MtlsAuthMethod) && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if principal, ok := credentials.authenticateCertificate(r.TLS.VerifiedChains[0][0]); ok {
+			return principal, nil
+		}
+	}
+	if slices.Contains(allowed, BasicAuthMethod) {
+		if user, pass, ok := r.BasicAuth(); ok {
+			if principal, ok := credentials.authenticate(user, pass); ok {
+				return principal, nil
+			}
+		}
+		return credential{}, common.NewError(common.ErrUnauthorized, "Not authorized to use this API")
+	}
+	return credential{}, common.NewError(common.ErrUnauthorized,
+		"Not authorized to use this API, trusted client certificate of known principal is required")
+}
+
+// clientTlsConfig requests client certificates and verifies them against CA certificates from the file, client
+// certificates are optional on TLS level, so routes with basic authentication are available without them
+func clientTlsConfig(caFile string) (*tls.Config, error) {
+	content, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no PEM certificates are found in %s", caFile)
+	}
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
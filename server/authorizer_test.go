@@ -0,0 +1,228 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const certificateCredentials = `[
+  {"username": "backup", "subject": "CN=backup-operator,O=dbaas", "scopes": ["backup-operator"]},
+  {"username": "observer", "san": "observer.dbaas.svc", "scopes": ["readonly-observer"]},
+  {"username": "monitoring", "password": "monitoring-password", "scopes": ["readonly-observer"]}
+]`
+
+func issueCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return certificate, key
+}
+
+func issueCa(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return issueCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "dbaas-ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+}
+
+func issueClientCertificate(t *testing.T, subject pkix.Name, dnsNames []string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return issueCertificate(t, &x509.Certificate{
+		Subject:     subject,
+		DNSNames:    dnsNames,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+}
+
+func certificateRequest(path string, certificate *x509.Certificate) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}}
+	return request
+}
+
+func loadCredentials(t *testing.T, content string) *Credentials {
+	location := filepath.Join(t.TempDir(), "credentials.json")
+	writeCredentials(t, location, content)
+	credentials := NewCredentials("dbaas-aggregator", "password", location)
+	_, err := credentials.Reload(context.Background())
+	assert.Nil(t, err)
+	return credentials
+}
+
+// principalRouter serves principal of the request on routes which allow only client certificates, only basic
+// authentication and both of them
+func principalRouter(credentials *Credentials) *mux.Router {
+	methods := AuthMethods{
+		Default: []string{BasicAuthMethod},
+		Routes: map[string][]string{
+			"/mtls": {MtlsAuthMethod},
+			"/any":  {MtlsAuthMethod, BasicAuthMethod},
+		},
+	}
+	authorizer := NewAuthorizer(credentials, methods, "adapter")
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(common.GetCtxStringValue(r.Context(), common.PrincipalKey)))
+	}
+	r := mux.NewRouter()
+	for _, path := range []string{"/mtls", "/any", "/basic"} {
+		r.Handle(path, authorizer(observerScopes, handler))
+	}
+	return r
+}
+
+func TestParseAuthMethods(t *testing.T) {
+	methods, err := ParseAuthMethods(" basic, mtls ", `{"/api/v2/dbaas/adapter/opensearch/backups/collect": ["mtls"]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{BasicAuthMethod, MtlsAuthMethod}, methods.Default)
+	assert.Equal(t, []string{MtlsAuthMethod}, methods.forRoute("/api/v2/dbaas/adapter/opensearch/backups/collect"))
+	assert.Equal(t, methods.Default, methods.forRoute("/api/v2/dbaas/adapter/opensearch/databases"))
+	assert.True(t, methods.Uses(MtlsAuthMethod))
+	assert.False(t, AuthMethods{Default: []string{BasicAuthMethod}}.Uses(MtlsAuthMethod))
+
+	_, err = ParseAuthMethods("basic,token", `{"/health": []}`)
+	assert.ErrorIs(t, err, common.ErrValidation)
+	assert.Contains(t, err.Error(), "'default[1]' must be one of")
+	assert.Contains(t, err.Error(), "'/health' must not be empty")
+
+	_, err = ParseAuthMethods("basic", `["mtls"]`)
+	assert.NotNil(t, err)
+
+	methods, err = ParseAuthMethods("basic", `{"/api/v2/dbaas/adapter/opensearch/unknown": ["mtls"], "/health": ["basic"]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/api/v2/dbaas/adapter/opensearch/unknown"}, methods.unknownRoutes(testRouter()))
+}
+
+func TestAuthorizerAuthenticatesClientCertificate(t *testing.T) {
+	ca, caKey := issueCa(t)
+	backup, _ := issueClientCertificate(t, pkix.Name{CommonName: "backup-operator", Organization: []string{"dbaas"}}, nil, ca, caKey)
+	observer, _ := issueClientCertificate(t, pkix.Name{CommonName: "some-client"}, []string{"observer.dbaas.svc"}, ca, caKey)
+	unknown, _ := issueClientCertificate(t, pkix.Name{CommonName: "unknown"}, []string{"unknown.dbaas.svc"}, ca, caKey)
+	r := principalRouter(loadCredentials(t, certificateCredentials))
+
+	cases := []struct {
+		request   *http.Request
+		status    int
+		principal string
+	}{
+		{certificateRequest("/mtls", observer), http.StatusOK, "observer"},
+		{certificateRequest("/any", observer), http.StatusOK, "observer"},
+		{certificateRequest("/mtls", backup), http.StatusForbidden, ""},
+		{certificateRequest("/mtls", unknown), http.StatusUnauthorized, ""},
+		{authorizedRequest(http.MethodGet, "/mtls", "dbaas-aggregator", "password"), http.StatusUnauthorized, ""},
+		{authorizedRequest(http.MethodGet, "/any", "monitoring", "monitoring-password"), http.StatusOK, "monitoring"},
+		{authorizedRequest(http.MethodGet, "/basic", "dbaas-aggregator", "password"), http.StatusOK, "dbaas-aggregator"},
+		{certificateRequest("/basic", observer), http.StatusUnauthorized, ""},
+	}
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, c.request)
+		assert.Equal(t, c.status, recorder.Code, c.request.URL.Path)
+		if c.status == http.StatusOK {
+			assert.Equal(t, c.principal, recorder.Body.String())
+		}
+	}
+
+	// certificate of principal takes precedence, unknown certificate falls back to basic authentication
+	request := certificateRequest("/any", unknown)
+	request.SetBasicAuth("monitoring", "monitoring-password")
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, request)
+	assert.Equal(t, "monitoring", recorder.Body.String())
+
+	// principals with client certificate only can not authenticate with empty password
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, authorizedRequest(http.MethodGet, "/any", "observer", ""))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, certificateRequest("/mtls", unknown))
+	assert.Empty(t, recorder.Header().Get("WWW-Authenticate"))
+}
+
+func TestClientTlsConfigVerifiesClientCertificates(t *testing.T) {
+	ca, caKey := issueCa(t)
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.Nil(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600))
+	config, err := clientTlsConfig(caFile)
+	assert.Nil(t, err)
+
+	server := httptest.NewUnstartedServer(principalRouter(loadCredentials(t, certificateCredentials)))
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(certificate *x509.Certificate, key *ecdsa.PrivateKey) (int, string) {
+		// every request uses its own connection, so client certificate is presented in new handshake
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		client := &http.Client{Transport: transport}
+		if certificate != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{{Certificate: [][]byte{certificate.Raw}, PrivateKey: key}}
+		}
+		response, err := client.Get(server.URL + "/any")
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		return response.StatusCode, string(body)
+	}
+
+	status, principal := get(issueClientCertificate(t, pkix.Name{CommonName: "client"}, []string{"observer.dbaas.svc"}, ca, caKey))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "observer", principal)
+
+	status, _ = get(nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	untrustedCa, untrustedKey := issueCa(t)
+	status, _ = get(issueClientCertificate(t, pkix.Name{CommonName: "client"}, []string{"observer.dbaas.svc"}, untrustedCa, untrustedKey))
+	assert.NotEqual(t, http.StatusOK, status, "certificate of untrusted CA must be rejected")
+
+	assert.Nil(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+	_, err = clientTlsConfig(caFile)
+	assert.NotNil(t, err)
+}
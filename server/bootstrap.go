@@ -0,0 +1,85 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/backup"
+	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/health"
+	"github.com/Netcracker/dbaas-opensearch-adapter/physical"
+)
+
+// bootstrapStep is a part of adapter initialization in OpenSearch which is retried until it succeeds
+type bootstrapStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func bootstrapSteps(baseProvider *basic.BaseProvider, backupProvider *backup.BackupProvider,
+	registrationProvider *physical.RegistrationProvider, idempotency *common.IdempotencyStore) []bootstrapStep {
+	return []bootstrapStep{
+		{name: "metadataIndex", run: baseProvider.EnsureAggregationIndex},
+		{name: "backupRegistryIndex", run: backupProvider.Registry.EnsureIndex},
+		{name: "migrationsIndex", run: registrationProvider.Migrations.EnsureIndex},
+		{name: "idempotencyIndex", run: idempotency.EnsureIndex},
+		{name: "roles", run: func(ctx context.Context) error { return createRoles(baseProvider) }},
+		{name: "migration", run: func(ctx context.Context) error { return migrateUsers(baseProvider) }},
+		{name: "roleMappings", run: func(ctx context.Context) error { return createRoleMappings(baseProvider) }},
+	}
+}
+
+func stepNames(steps []bootstrapStep) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.name)
+	}
+	return names
+}
+
+// bootstrap performs steps in order while HTTP server is already serving probes. Failed step is retried with
+// exponential backoff starting from retryDelay up to maxRetryDelay, its error is recorded in startup, so it is
+// reported by health and probes. onComplete is called when all steps succeed.
+func bootstrap(ctx context.Context, startup *health.Startup, steps []bootstrapStep, retryDelay time.Duration,
+	maxRetryDelay time.Duration, onComplete func()) {
+	logger := common.GetLogger()
+	for _, step := range steps {
+		delay := retryDelay
+		for attempt := 1; ; attempt++ {
+			err := step.run(ctx)
+			if err == nil {
+				startup.Complete(step.name)
+				break
+			}
+			startup.Fail(step.name, err)
+			logger.ErrorContext(ctx, fmt.Sprintf("Bootstrap step '%s' failed on attempt %d, retrying in %s", step.name, attempt, delay),
+				slog.Any("error", err))
+			select {
+			case <-ctx.Done():
+				logger.WarnContext(ctx, "Bootstrap is interrupted", slog.Any("reason", ctx.Err()))
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxRetryDelay)
+		}
+	}
+	logger.InfoContext(ctx, "Bootstrap is completed")
+	onComplete()
+}
@@ -0,0 +1,68 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrapRetriesFailedStep(t *testing.T) {
+	failures := 2
+	var order []string
+	steps := []bootstrapStep{
+		{name: "index", run: func(ctx context.Context) error {
+			order = append(order, "index")
+			if failures > 0 {
+				failures--
+				return errors.New("OpenSearch is not available")
+			}
+			return nil
+		}},
+		{name: "roles", run: func(ctx context.Context) error {
+			order = append(order, "roles")
+			return nil
+		}},
+	}
+	startup := health.NewStartup(stepNames(steps)...)
+	completed := false
+	bootstrap(context.Background(), startup, steps, time.Millisecond, 2*time.Millisecond, func() { completed = true })
+
+	assert.True(t, completed)
+	assert.True(t, startup.Completed())
+	assert.Equal(t, []string{"index", "index", "index", "roles"}, order)
+}
+
+func TestBootstrapIsInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	steps := []bootstrapStep{
+		{name: "index", run: func(ctx context.Context) error {
+			cancel()
+			return errors.New("OpenSearch is not available")
+		}},
+	}
+	startup := health.NewStartup(stepNames(steps)...)
+	completed := false
+	bootstrap(ctx, startup, steps, time.Hour, time.Hour, func() { completed = true })
+
+	assert.False(t, completed)
+	result := startup.Check(context.Background())
+	assert.Equal(t, "OpenSearch is not available", result.Details["index"])
+}
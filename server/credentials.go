@@ -0,0 +1,262 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+)
+
+const (
+	// AggregatorScope allows to use all routes of adapter API
+	AggregatorScope = "aggregator"
+	// BackupOperatorScope allows to use backup, restore and snapshot repository routes
+	BackupOperatorScope = "backup-operator"
+	// ReadonlyObserverScope allows to use routes which list and describe databases, backups and registration
+	ReadonlyObserverScope = "readonly-observer"
+)
+
+var credentialScopes = []string{AggregatorScope, BackupOperatorScope, ReadonlyObserverScope}
+
+// Principal is the client of adapter API with its credentials and scopes. Principal authenticates with password
+// or with client certificate which subject or one of subject alternative names matches Subject or San.
+type Principal struct {
+	Username string   `json:"username"`
+	Password string   `json:"password,omitempty"`
+	Subject  string   `json:"subject,omitempty"`
+	San      string   `json:"san,omitempty"`
+	Scopes   []string `json:"scopes"`
+}
+
+// credential keeps hashes of username and password, so all of them are compared in constant time
+// regardless of their length
+type credential struct {
+	username     string
+	usernameHash [sha256.Size]byte
+	passwordHash [sha256.Size]byte
+	// withPassword is false for principals which authenticate only with client certificate
+	withPassword bool
+	subject      string
+	san          string
+	scopes       []string
+}
+
+func newCredential(principal Principal) credential {
+	return credential{
+		username:     principal.Username,
+		usernameHash: sha256.Sum256([]byte(principal.Username)),
+		passwordHash: sha256.Sum256([]byte(principal.Password)),
+		withPassword: principal.Password != "",
+		subject:      principal.Subject,
+		san:          principal.San,
+		scopes:       principal.Scopes,
+	}
+}
+
+// matches checks that certificate subject or one of its subject alternative names is the one of credential
+func (c credential) matches(certificate *x509.Certificate) bool {
+	if c.subject != "" && c.subject == certificate.Subject.String() {
+		return true
+	}
+	if c.san == "" {
+		return false
+	}
+	names := slices.Concat(certificate.DNSNames, certificate.EmailAddresses)
+	for _, uri := range certificate.URIs {
+		names = append(names, uri.String())
+	}
+	for _, ip := range certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return slices.Contains(names, c.san)
+}
+
+// allows checks that credential has aggregator scope or one of the given scopes
+func (c credential) allows(scopes []string) bool {
+	for _, scope := range c.scopes {
+		if scope == AggregatorScope || slices.Contains(scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials are principals allowed to use adapter API. DBaaS aggregator credentials are always allowed with
+// aggregator scope, other principals are read from credentials file which is reloaded when it is changed.
+type Credentials struct {
+	aggregator   credential
+	fileLocation string
+
+	mutex        sync.RWMutex
+	principals   []credential
+	hash         [sha256.Size]byte
+	loaded       bool
+	rejectedHash [sha256.Size]byte
+}
+
+func NewCredentials(aggregatorUsername string, aggregatorPassword string, fileLocation string) *Credentials {
+	return &Credentials{
+		aggregator: newCredential(Principal{
+			Username: aggregatorUsername,
+			Password: aggregatorPassword,
+			Scopes:   []string{AggregatorScope},
+		}),
+		fileLocation: fileLocation,
+	}
+}
+
+// Watch checks credentials file with the interval until ctx is cancelled
+func (c *Credentials) Watch(ctx context.Context, interval time.Duration) {
+	if c.fileLocation == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := c.Reload(ctx); err != nil {
+			common.GetLogger().WarnContext(ctx, fmt.Sprintf("Credentials file %s is rejected, previous credentials are kept", c.fileLocation),
+				slog.Any("error", err))
+		}
+	}
+}
+
+// Reload reads credentials file and replaces principals if the file content is changed, it returns true
+// if principals are replaced. Missing file means that only DBaaS aggregator is allowed to use API.
+func (c *Credentials) Reload(ctx context.Context) (bool, error) {
+	if c.fileLocation == "" {
+		return false, nil
+	}
+	content, err := os.ReadFile(c.fileLocation)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	hash := sha256.Sum256(content)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.loaded && hash == c.hash || hash == c.rejectedHash {
+		return false, nil
+	}
+	var principals []Principal
+	if err == nil {
+		if principals, err = c.parsePrincipals(content); err != nil {
+			// the same invalid content is rejected only once to not repeat the warning
+			c.rejectedHash = hash
+			return false, err
+		}
+	}
+	c.principals = make([]credential, 0, len(principals))
+	usernames := make([]string, 0, len(principals))
+	for _, principal := range principals {
+		c.principals = append(c.principals, newCredential(principal))
+		usernames = append(usernames, principal.Username)
+	}
+	c.hash = hash
+	c.loaded = true
+	common.GetLogger().InfoContext(ctx, fmt.Sprintf("Credentials are loaded from %s for principals: %s",
+		c.fileLocation, strings.Join(usernames, ", ")))
+	return true, nil
+}
+
+// parsePrincipals reads JSON list of principals, usernames must be unique and must differ from DBaaS aggregator one
+func (c *Credentials) parsePrincipals(content []byte) ([]Principal, error) {
+	var principals []Principal
+	if err := json.Unmarshal(content, &principals); err != nil {
+		return nil, fmt.Errorf("credentials must be JSON list of principals: %w", err)
+	}
+	var validator common.Validator
+	usernames := map[string]bool{c.aggregator.username: true}
+	for i, principal := range principals {
+		field := fmt.Sprintf("[%d]", i)
+		validator.Required(field+".username", principal.Username)
+		if principal.Password == "" && principal.Subject == "" && principal.San == "" {
+			validator.Add(field, "one of 'password', 'subject' or 'san' must be specified")
+		}
+		if strings.Contains(principal.Username, ":") {
+			validator.Add(field+".username", "must not contain ':' character")
+		}
+		if usernames[principal.Username] {
+			validator.Add(field+".username", fmt.Sprintf("'%s' is already used", principal.Username))
+		}
+		usernames[principal.Username] = true
+		if len(principal.Scopes) == 0 {
+			validator.Add(field+".scopes", "must not be empty")
+		}
+		for j, scope := range principal.Scopes {
+			validator.OneOf(fmt.Sprintf("%s.scopes[%d]", field, j), scope, credentialScopes)
+		}
+	}
+	return principals, validator.Err()
+}
+
+// authenticate returns credential matching username and password, all credentials are compared, so the time
+// does not depend on which of them matches
+func (c *Credentials) authenticate(username string, password string) (credential, bool) {
+	usernameHash := sha256.Sum256([]byte(username))
+	passwordHash := sha256.Sum256([]byte(password))
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var found credential
+	matched := 0
+	for _, candidate := range append([]credential{c.aggregator}, c.principals...) {
+		match := subtle.ConstantTimeCompare(usernameHash[:], candidate.usernameHash[:]) &
+			subtle.ConstantTimeCompare(passwordHash[:], candidate.passwordHash[:]) &
+			subtle.ConstantTimeByteEq(boolToByte(candidate.withPassword), 1)
+		if match == 1 && matched == 0 {
+			found = candidate
+		}
+		matched |= match
+	}
+	return found, matched == 1
+}
+
+// authenticateCertificate returns credential of principal which subject or subject alternative name matches
+// verified client certificate
+func (c *Credentials) authenticateCertificate(certificate *x509.Certificate) (credential, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, candidate := range c.principals {
+		if candidate.matches(certificate) {
+			return candidate, true
+		}
+	}
+	return credential{}, false
+}
+
+func boolToByte(value bool) uint8 {
+	if value {
+		return 1
+	}
+	return 0
+}
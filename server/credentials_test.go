@@ -0,0 +1,128 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCredentials = `[
+  {"username": "backup", "password": "backup-password", "scopes": ["backup-operator"]},
+  {"username": "observer", "password": "observer-password", "scopes": ["readonly-observer"]}
+]`
+
+func writeCredentials(t *testing.T, location string, content string) {
+	assert.Nil(t, os.WriteFile(location, []byte(content), 0600))
+}
+
+func authorizedRequest(method string, path string, username string, password string) *http.Request {
+	request := httptest.NewRequest(method, path, nil)
+	request.SetBasicAuth(username, password)
+	return request
+}
+
+func TestCredentialsReload(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "credentials.json")
+	credentials := NewCredentials("dbaas-aggregator", "password", location)
+
+	changed, err := credentials.Reload(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	_, ok := credentials.authenticate("backup", "backup-password")
+	assert.False(t, ok)
+
+	writeCredentials(t, location, testCredentials)
+	changed, err = credentials.Reload(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	principal, ok := credentials.authenticate("backup", "backup-password")
+	assert.True(t, ok)
+	assert.Equal(t, "backup", principal.username)
+	_, ok = credentials.authenticate("backup", "observer-password")
+	assert.False(t, ok)
+	principal, ok = credentials.authenticate("dbaas-aggregator", "password")
+	assert.True(t, ok)
+	assert.Equal(t, []string{AggregatorScope}, principal.scopes)
+
+	changed, err = credentials.Reload(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	writeCredentials(t, location, `[{"username": "dbaas-aggregator", "password": "another", "scopes": ["superuser"]}]`)
+	_, err = credentials.Reload(context.Background())
+	assert.ErrorIs(t, err, common.ErrValidation)
+	assert.Contains(t, err.Error(), "'[0].username' 'dbaas-aggregator' is already used")
+	assert.Contains(t, err.Error(), "'[0].scopes[0]' must be one of")
+
+	writeCredentials(t, location, `[{"username": "backup", "scopes": ["backup-operator"]}]`)
+	_, err = credentials.Reload(context.Background())
+	assert.ErrorIs(t, err, common.ErrValidation)
+	assert.Contains(t, err.Error(), "one of 'password', 'subject' or 'san' must be specified")
+	_, ok = credentials.authenticate("backup", "backup-password")
+	assert.True(t, ok, "previous credentials must be kept")
+
+	assert.Nil(t, os.Remove(location))
+	changed, err = credentials.Reload(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	_, ok = credentials.authenticate("backup", "backup-password")
+	assert.False(t, ok)
+}
+
+func TestBasicAuthorizerChecksScopes(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "credentials.json")
+	writeCredentials(t, location, testCredentials)
+	credentials := NewCredentials("dbaas-aggregator", "password", location)
+	_, err := credentials.Reload(context.Background())
+	assert.Nil(t, err)
+	r := testRouterWithCredentials(credentials)
+
+	forbidden := []*http.Request{
+		authorizedRequest(http.MethodPost, "/api/v2/dbaas/adapter/opensearch/databases", "backup", "backup-password"),
+		authorizedRequest(http.MethodPost, "/api/v2/dbaas/adapter/opensearch/backups/collect", "observer", "observer-password"),
+		authorizedRequest(http.MethodGet, "/api/v2/dbaas/adapter/opensearch/users/restore-password/state", "backup", "backup-password"),
+	}
+	for _, request := range forbidden {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusForbidden, recorder.Code, request.URL.Path)
+		var response common.ErrorResponse
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, common.ErrForbidden.Code, response.Code)
+	}
+
+	allowed := []*http.Request{
+		authorizedRequest(http.MethodGet, "/api/v2/dbaas/adapter/opensearch/users/restore-password/state", "observer", "observer-password"),
+		authorizedRequest(http.MethodGet, "/api/v2/dbaas/adapter/opensearch/users/restore-password/state", "dbaas-aggregator", "password"),
+	}
+	for _, request := range allowed {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code, request.URL.Path)
+	}
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, authorizedRequest(http.MethodGet, "/api/v2/dbaas/adapter/opensearch/users/restore-password/state", "observer", "password"))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
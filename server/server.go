@@ -16,7 +16,7 @@ package server
 
 import (
 	"context"
-	"crypto/subtle"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/Netcracker/dbaas-opensearch-adapter/backup"
@@ -25,13 +25,17 @@ import (
 	"github.com/Netcracker/dbaas-opensearch-adapter/cluster"
 	"github.com/Netcracker/dbaas-opensearch-adapter/common"
 	"github.com/Netcracker/dbaas-opensearch-adapter/health"
+	"github.com/Netcracker/dbaas-opensearch-adapter/metrics"
+	"github.com/Netcracker/dbaas-opensearch-adapter/openapi"
 	"github.com/Netcracker/dbaas-opensearch-adapter/physical"
+	"github.com/Netcracker/dbaas-opensearch-adapter/tracing"
 	"github.com/Netcracker/qubership-dbaas-adapter-core/pkg/dao"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"log/slog"
 	"net/http"
 	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
@@ -56,15 +60,29 @@ var (
 	//nolint:errcheck
 	enhancedSecurityPluginEnabled, _ = strconv.ParseBool(common.GetEnv("ENHANCED_SECURITY_PLUGIN_ENABLED", "false"))
 
-	labelsFilename    = common.GetEnv("LABELS_FILE_LOCATION_NAME", "dbaas.physical_databases.registration.labels.json")
-	labelsLocationDir = common.GetEnv("LABELS_FILE_LOCATION_DIR", "/app/config/")
+	labelsFilename      = common.GetEnv("LABELS_FILE_LOCATION_NAME", "dbaas.physical_databases.registration.labels.json")
+	labelsLocationDir   = common.GetEnv("LABELS_FILE_LOCATION_DIR", "/app/config/")
+	labelsWatchInterval = common.GetIntEnv("LABELS_FILE_WATCH_INTERVAL_MS", 5000)
 	//nolint:errcheck
 	registrationEnabled, _ = strconv.ParseBool(common.GetEnv("REGISTRATION_ENABLED", "false"))
+	//nolint:errcheck
+	backupVerificationEnabled, _  = strconv.ParseBool(common.GetEnv("BACKUP_VERIFICATION_ENABLED", "false"))
+	siblingExpirationInterval     = common.GetIntEnv("SIBLING_EXPIRATION_INTERVAL_MS", 300000)
+	drainTimeout                  = common.GetIntEnv("DRAIN_TIMEOUT_MS", 20000)
+	bootstrapRetryDelay           = common.GetIntEnv("BOOTSTRAP_RETRY_DELAY_MS", 1000)
+	bootstrapMaxRetryDelay        = common.GetIntEnv("BOOTSTRAP_MAX_RETRY_DELAY_MS", 30000)
+	idempotencyKeyTtl             = common.GetIntEnv("IDEMPOTENCY_KEY_TTL_MS", 86400000)
+	idempotencyExpirationInterval = common.GetIntEnv("IDEMPOTENCY_EXPIRATION_INTERVAL_MS", 3600000)
+	credentialsFile               = common.GetEnv("DBAAS_ADAPTER_CREDENTIALS_FILE", "")
+	credentialsWatchInterval      = common.GetIntEnv("DBAAS_ADAPTER_CREDENTIALS_WATCH_INTERVAL_MS", 5000)
+	clientCaFile                  = common.GetEnv("DBAAS_ADAPTER_CLIENT_CA_FILE", "")
+	defaultAuthMethods            = common.GetEnv("DBAAS_ADAPTER_AUTH_METHODS", BasicAuthMethod)
+	routeAuthMethods              = common.GetEnv("DBAAS_ADAPTER_ROUTE_AUTH_METHODS", "")
 )
 
 const certificatesFolder = "/tls"
 
-func Server(ctx context.Context, adapterAddress string, adapterUsername string, adapterPassword string) {
+func Server(ctx context.Context, adapterAddress string, adapterPort int, adapterUsername string, adapterPassword string) {
 	adapter := common.Component{
 		Address: adapterAddress,
 		Credentials: dao.BasicAuth{
@@ -73,18 +91,34 @@ func Server(ctx context.Context, adapterAddress string, adapterUsername string,
 		},
 	}
 
-	hnd := Handlers(ctx, adapter)
-	if hnd == nil {
-		return
+	logger := common.GetLogger()
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		logger.Error("Failed to configure tracing, spans are not exported", slog.Any("error", err))
+		shutdownTracing = func(context.Context) error { return nil }
 	}
 
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: hnd,
+	isTlsEnabled := strings.Contains(adapterAddress, common.Https)
+	methods, err := ParseAuthMethods(defaultAuthMethods, routeAuthMethods)
+	if err != nil {
+		logger.Error("Authentication methods are invalid, only basic authentication is allowed", slog.Any("error", err))
+		methods = AuthMethods{Default: []string{BasicAuthMethod}}
 	}
 
-	isTlsEnabled := strings.Contains(adapterAddress, common.Https)
-	logger := common.GetLogger()
+	drainer := common.NewDrainer()
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", adapterPort),
+		Handler: Handlers(ctx, adapter, drainer, methods),
+	}
+	if isTlsEnabled && clientCaFile != "" {
+		if server.TLSConfig, err = clientTlsConfig(clientCaFile); err != nil {
+			logger.Error("Failed to load client CA certificates, client certificates are not requested", slog.Any("error", err))
+		}
+	}
+	if methods.Uses(MtlsAuthMethod) && server.TLSConfig == nil {
+		logger.Warn("Client certificate authentication requires TLS and DBAAS_ADAPTER_CLIENT_CA_FILE, " +
+			"routes which allow only client certificates are not available")
+	}
 
 	go func() {
 		var err error
@@ -101,140 +135,334 @@ func Server(ctx context.Context, adapterAddress string, adapterUsername string,
 	}()
 
 	<-ctx.Done()
-	deadlineCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	logger.Info(fmt.Sprintf("Draining adapter, waiting up to %d ms for in-flight operations", drainTimeout))
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(drainTimeout)*time.Millisecond)
+	defer cancelDrain()
+	drainer.Drain(drainCtx)
+	if err := drainer.Wait(drainCtx); err != nil {
+		logger.Warn("Shutting down with unfinished operations", slog.Any("error", err))
+	}
+
+	deadlineCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := server.Shutdown(deadlineCtx)
+	err = server.Shutdown(deadlineCtx)
 	if err != nil {
 		logger.Error("failed to shutdown server")
 	}
+	if err = shutdownTracing(deadlineCtx); err != nil {
+		logger.Warn("Failed to export remaining spans", slog.Any("error", err))
+	}
 	logger.Info("server is down gracefully")
 }
 
-func Handlers(ctx context.Context, adapter common.Component) http.Handler {
+// Handlers prepares adapter API, requests creating new resources are rejected when drainer is draining.
+// Initialization in OpenSearch is performed in background, so probes are served while OpenSearch is not available.
+func Handlers(ctx context.Context, adapter common.Component, drainer *common.Drainer, methods AuthMethods) http.Handler {
 	opensearch := cluster.NewOpensearch(opensearchHost, opensearchPort,
 		opensearchProtocol, opensearchUsername, opensearchPassword)
 	baseProvider := basic.NewBaseProvider(opensearch)
-	err := baseProvider.EnsureAggregationIndex(ctx)
-	if err != nil {
-		return nil
+	baseProvider.Drainer = drainer
+	registrationProvider := newRegistrationProvider(adapter.Address, adapter.Credentials.Username,
+		adapter.Credentials.Password, baseProvider, opensearch.Client)
+	if registrationEnabled {
+		drainer.OnDrain(func(ctx context.Context) {
+			if err := registrationProvider.Drain(ctx); err != nil {
+				common.GetLogger().WarnContext(ctx, "Failed to report draining status to DBaaS aggregator", slog.Any("error", err))
+			}
+		})
 	}
-	registrationProvider := startRegistration(adapter.Address, adapter.Credentials.Username,
-		adapter.Credentials.Password, baseProvider)
-	createBasicRoles(baseProvider)
 	curatorBaseClient := cl.ConfigureCuratorClient()
-	backupProvider := backup.NewBackupProvider(opensearch.Client, curatorBaseClient, opensearchRepoRoot)
-	basePath := fmt.Sprintf("/api/%s/dbaas/adapter/opensearch", registrationProvider.ApiVersion)
+	backupProvider := backup.NewBackupProvider(opensearch.Client, curatorBaseClient, opensearchRepoRoot, baseProvider)
+	if backupVerificationEnabled {
+		backupProvider.EnableVerificationAfterBackup(opensearchRepo)
+	}
+	idempotency := common.NewIdempotencyStore(opensearch.Client, time.Duration(idempotencyKeyTtl)*time.Millisecond)
+	idempotency.EnablePasswordReset(func(ctx context.Context, username string) (string, error) {
+		properties, err := baseProvider.ResetPassword(username, ctx)
+		return properties.Password, err
+	})
+	steps := bootstrapSteps(baseProvider, backupProvider, registrationProvider, idempotency)
+	startup := health.NewStartup(stepNames(steps)...)
+	go bootstrap(ctx, startup, steps, time.Duration(bootstrapRetryDelay)*time.Millisecond,
+		time.Duration(bootstrapMaxRetryDelay)*time.Millisecond, func() {
+			if registrationEnabled {
+				registrationProvider.StartRegistration(ctx)
+				go registrationProvider.WatchLabels(ctx, time.Duration(labelsWatchInterval)*time.Millisecond)
+			}
+			go backupProvider.ExpireSiblingsPeriodically(ctx, time.Duration(siblingExpirationInterval)*time.Millisecond)
+			go idempotency.ExpirePeriodically(ctx, time.Duration(idempotencyExpirationInterval)*time.Millisecond)
+		})
 
 	healthService := health.Health{
-		Status:                common.Up,
-		OpensearchHealth:      opensearch.Health,
-		DbaasAggregatorHealth: &registrationProvider.Health,
-		Opensearch:            opensearch,
+		Status:             common.Up,
+		OpensearchHealth:   opensearch.Health,
+		Opensearch:         opensearch,
+		RegistrationHealth: registrationProvider.Health,
+	}
+	healthService.AddCheck("opensearch", health.OpensearchCheck(opensearch))
+	if registrationEnabled {
+		healthService.AddCheck("dbaasAggregator", health.RegistrationCheck(registrationProvider.Health))
+	}
+	healthService.AddCheck("curator", health.CuratorCheck(backupProvider.Curator))
+	healthService.AddCheck("metadataIndex", health.IndexCheck(opensearch.Client, basic.DbaasMetadata))
+	healthService.AddCheck("roles", health.RolesCheck(baseProvider, enhancedSecurityPluginEnabled))
+	healthService.AddCheck("snapshotRepository", health.RepositoryCheck(backupProvider, opensearchRepo))
+	healthService.AddCheck("certificates", health.CertificatesCheck(health.CertificateExpirationWarning,
+		certificatesFolder, cluster.TrustCertsFolder))
+	healthService.AddCheck("bootstrap", startup.Check)
+	healthService.AddReadinessCheck("bootstrap", startup.Check)
+	healthService.AddReadinessCheck("opensearch", health.ConnectionCheck(opensearch))
+	healthService.AddReadinessCheck("metadataIndex", health.IndexCheck(opensearch.Client, basic.DbaasMetadata))
+	healthService.AddReadinessCheck("drain", health.DrainCheck(drainer))
+	healthService.AddStartupCheck("bootstrap", startup.Check)
+
+	if err := metrics.RegisterInventory(baseProvider.CountManagedResources); err != nil {
+		common.GetLogger().Warn("Failed to register managed resources metrics", slog.Any("error", err))
 	}
 
+	credentials := NewCredentials(adapter.Credentials.Username, adapter.Credentials.Password, credentialsFile)
+	if _, err := credentials.Reload(ctx); err != nil {
+		common.GetLogger().WarnContext(ctx, fmt.Sprintf("Credentials file %s is rejected, only DBaaS aggregator is allowed to use API", credentialsFile),
+			slog.Any("error", err))
+	}
+	go credentials.Watch(ctx, time.Duration(credentialsWatchInterval)*time.Millisecond)
+	authorizer := NewAuthorizer(credentials, methods, "This API is for using by DBaaS aggregator and adapter operators only")
+	r := router(ctx, &healthService, baseProvider, backupProvider, registrationProvider, authorizer, drainer, idempotency)
+	if unknown := methods.unknownRoutes(r); len(unknown) > 0 {
+		common.GetLogger().WarnContext(ctx, fmt.Sprintf("Authentication methods are configured for unknown routes: %s",
+			strings.Join(unknown, ", ")))
+	}
+	return responseHandler(r)
+}
+
+// responseHandler sets content type of responses and compresses them if client accepts it, content type is
+// detected by the uncompressed body
+func responseHandler(h http.Handler) http.Handler {
+	return handlers.CompressHandler(JsonContentType(h))
+}
+
+// router registers all routes of adapter, every registered route must be described in OpenAPI specification
+func router(ctx context.Context, healthService *health.Health, baseProvider *basic.BaseProvider, backupProvider *backup.BackupProvider,
+	registrationProvider *physical.RegistrationProvider, authorizer Authorizer,
+	drainer *common.Drainer, idempotency *common.IdempotencyStore) *mux.Router {
 	r := mux.NewRouter()
-	authorizer := BasicAuthorizer(adapter.Credentials.Username, adapter.Credentials.Password,
-		"This API is for using by DBaaS aggregator only")
+	r.Use(tracing.Middleware, metrics.Middleware)
 
 	r.HandleFunc("/health", healthService.HealthHandler()).Methods(http.MethodGet)
+	r.HandleFunc("/health/details", healthService.DetailedHealthHandler()).Methods(http.MethodGet)
+	r.HandleFunc("/livez", healthService.LivenessHandler()).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", healthService.ReadinessHandler()).Methods(http.MethodGet)
+	r.HandleFunc("/startupz", healthService.StartupHandler()).Methods(http.MethodGet)
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	r.HandleFunc(openapi.YamlPath, openapi.YamlHandler()).Methods(http.MethodGet)
+	r.HandleFunc(openapi.JsonPath, openapi.JsonHandler()).Methods(http.MethodGet)
+
+	for _, apiVersion := range []string{common.ApiV1, common.ApiV2} {
+		apiRoutes(ctx, r, apiVersion, baseProvider.WithApiVersion(apiVersion), backupProvider.WithApiVersion(apiVersion), registrationProvider,
+			authorizer, drainer, idempotency)
+	}
+	return r
+}
+
+var (
+	// aggregatorScopes routes change databases and users, so they are available only with aggregator scope
+	aggregatorScopes     []string
+	backupScopes         = []string{BackupOperatorScope}
+	observerScopes       = []string{ReadonlyObserverScope}
+	backupObserverScopes = []string{BackupOperatorScope, ReadonlyObserverScope}
+)
+
+// apiRoutes registers adapter API of the given version. Routes of all versions are served at the same time,
+// so adapter does not depend on the API version negotiated with DBaaS aggregator. Creation of databases and
+// restorations are performed once for the same Idempotency-Key, so their retries do not fail or start new jobs.
+func apiRoutes(ctx context.Context, r *mux.Router, apiVersion string, baseProvider *basic.BaseProvider, backupProvider *backup.BackupProvider,
+	registrationProvider *physical.RegistrationProvider, authorizer Authorizer,
+	drainer *common.Drainer, idempotency *common.IdempotencyStore) {
+	basePath := fmt.Sprintf("/api/%s/dbaas/adapter/opensearch", apiVersion)
 
 	r.HandleFunc(fmt.Sprintf("%s/supports", basePath), baseProvider.SupportsHandler()).Methods(http.MethodGet)
 
 	r.Handle(fmt.Sprintf("%s/databases", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.CreateDatabaseHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(aggregatorScopes, idempotency.Guard(drainer.Guard("database-creation", baseProvider.CreateDatabaseHandler())))),
 	).Methods(http.MethodPost)
 
 	r.Handle(fmt.Sprintf("%s/databases", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.ListDatabasesHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(observerScopes, baseProvider.ListDatabasesHandler())),
 	).Methods(http.MethodGet)
 
 	r.Handle(fmt.Sprintf("%s/resources/bulk-drop", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.BulkDropResourceHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(aggregatorScopes, baseProvider.BulkDropResourceHandler())),
 	).Methods(http.MethodPost)
 
 	r.Handle(fmt.Sprintf("%s/databases/{dbName}/metadata", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.UpdateMetadataHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(aggregatorScopes, baseProvider.UpdateMetadataHandler())),
 	).Methods(http.MethodPut)
 
 	r.Handle(fmt.Sprintf("%s/backups/collect", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(backupProvider.CollectBackupHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, drainer.Guard("backup", backupProvider.CollectBackupHandler()))),
 	).Methods(http.MethodPost)
 
 	r.Handle(fmt.Sprintf("%s/backups/{backupID}/restore", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(backupProvider.RestoreBackupHandler(opensearchRepo, basePath))),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, idempotency.Guard(drainer.Guard("restore", backupProvider.RestoreBackupHandler(opensearchRepo, basePath))))),
 	).Methods(http.MethodPost)
 
 	r.Handle(fmt.Sprintf("%s/backups/{backupID}/restoration", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(backupProvider.RestorationBackupHandler(opensearchRepo, basePath))),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, idempotency.Guard(drainer.Guard("restore", backupProvider.RestorationBackupHandler(opensearchRepo, basePath))))),
+	).Methods(http.MethodPost)
+
+	r.Handle(fmt.Sprintf("%s/backups/{backupID}/sibling", basePath),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, idempotency.Guard(drainer.Guard("restore", backupProvider.RestoreSiblingHandler())))),
 	).Methods(http.MethodPost)
 
 	r.Handle(fmt.Sprintf("%s/backups/track/backup/{backupID}", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(backupProvider.TrackBackupHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupObserverScopes, backupProvider.TrackBackupHandler(opensearchRepo))),
 	).Methods(http.MethodGet)
 
 	r.Handle(fmt.Sprintf("%s/backups/track/restore/{backupID}", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(backupProvider.TrackRestoreFromTrackIdHandler(opensearchRepo))),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupObserverScopes, backupProvider.TrackRestoreFromTrackIdHandler(opensearchRepo))),
 	).Methods(http.MethodGet)
 
 	r.Handle(fmt.Sprintf("%s/backups/track/restoring/backups/{backupID}/indices/{indices}", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(backupProvider.TrackRestoreFromIndicesHandler(opensearchRepo))),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupObserverScopes, backupProvider.TrackRestoreFromIndicesHandler(opensearchRepo))),
+	).Methods(http.MethodGet)
+
+	r.Handle(fmt.Sprintf("%s/backups/{backupID}/verification", basePath),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, drainer.Guard("backup-verification", backupProvider.VerifyBackupHandler(opensearchRepo)))),
+	).Methods(http.MethodPost)
+
+	r.Handle(fmt.Sprintf("%s/backups/{backupID}/verification", basePath),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupObserverScopes, backupProvider.GetVerificationHandler())),
 	).Methods(http.MethodGet)
 
 	r.Handle(fmt.Sprintf("%s/backups/{backupID}", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(backupProvider.DeleteBackupHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, backupProvider.DeleteBackupHandler())),
+	).Methods(http.MethodDelete)
+
+	r.Handle(fmt.Sprintf("%s/repositories", basePath),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupObserverScopes, backupProvider.ListRepositoriesHandler(opensearchRepo))),
+	).Methods(http.MethodGet)
+
+	r.Handle(fmt.Sprintf("%s/repositories/{repository}", basePath),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, backupProvider.RegisterRepositoryHandler())),
+	).Methods(http.MethodPut)
+
+	r.Handle(fmt.Sprintf("%s/repositories/{repository}/verify", basePath),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, backupProvider.VerifyRepositoryHandler())),
+	).Methods(http.MethodPost)
+
+	r.Handle(fmt.Sprintf("%s/repositories/{repository}", basePath),
+		handlers.LoggingHandler(os.Stdout, authorizer(backupScopes, backupProvider.DeleteRepositoryHandler(opensearchRepo))),
 	).Methods(http.MethodDelete)
 
 	r.Handle(fmt.Sprintf("%s/physical_database", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(registrationProvider.GetPhysicalDatabaseHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(observerScopes, registrationProvider.GetPhysicalDatabaseHandler())),
+	).Methods(http.MethodGet)
+
+	r.Handle(fmt.Sprintf("/api/%s/dbaas/adapter/physical_database/force_registration", apiVersion),
+		handlers.LoggingHandler(os.Stdout, authorizer(aggregatorScopes, registrationProvider.ForceRegistrationHandler(ctx))),
+	).Methods(http.MethodGet)
+
+	r.Handle(fmt.Sprintf("/api/%s/dbaas/adapter/physical_database/registration", apiVersion),
+		handlers.LoggingHandler(os.Stdout, authorizer(observerScopes, registrationProvider.RegistrationStatusHandler())),
+	).Methods(http.MethodGet)
+
+	r.Handle(fmt.Sprintf("/api/%s/dbaas/adapter/physical_database/migrations", apiVersion),
+		handlers.LoggingHandler(os.Stdout, authorizer(observerScopes, registrationProvider.MigrationProgressHandler())),
 	).Methods(http.MethodGet)
 
-	r.Handle(fmt.Sprintf("/api/%s/dbaas/adapter/physical_database/force_registration", registrationProvider.ApiVersion),
-		handlers.LoggingHandler(os.Stdout, authorizer(registrationProvider.ForceRegistrationHandler())),
+	r.Handle(fmt.Sprintf("/api/%s/dbaas/adapter/physical_database/migrations/{instructionId}", apiVersion),
+		handlers.LoggingHandler(os.Stdout, authorizer(observerScopes, registrationProvider.MigrationProgressHandler())),
 	).Methods(http.MethodGet)
 
 	r.Handle(fmt.Sprintf("%s/users", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.CreateUserHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(aggregatorScopes, drainer.Guard("user-creation", baseProvider.CreateUserHandler()))),
 	).Methods(http.MethodPut)
 
 	r.Handle(fmt.Sprintf("%s/users/{name}", basePath),
-		handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.CreateUserHandler())),
+		handlers.LoggingHandler(os.Stdout, authorizer(aggregatorScopes, drainer.Guard("user-creation", baseProvider.CreateUserHandler()))),
 	).Methods(http.MethodPut)
 
-	if registrationProvider.ApiVersion == common.ApiV2 {
+	if apiVersion == common.ApiV2 {
 		r.Handle(fmt.Sprintf("%s/users/restore-password", basePath),
-			handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.RecoverUsersHandler())),
+			handlers.LoggingHandler(os.Stdout, authorizer(aggregatorScopes, drainer.Guard("users-recovery", baseProvider.RecoverUsersHandler()))),
 		).Methods(http.MethodPost)
 
 		r.Handle(fmt.Sprintf("%s/users/restore-password/state", basePath),
-			handlers.LoggingHandler(os.Stdout, authorizer(baseProvider.GetRecoveryStateHandler())),
+			handlers.LoggingHandler(os.Stdout, authorizer(observerScopes, baseProvider.GetRecoveryStateHandler())),
 		).Methods(http.MethodGet)
 	}
-
-	return JsonContentType(handlers.CompressHandler(r))
 }
 
+// JsonContentType sets "application/json" content type for responses with JSON body when handler does not set
+// content type itself, other bodies are labelled with detected content type. Panics of handler are turned into
+// INTERNAL_ERROR response if the response is not sent yet.
 func JsonContentType(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() { // error handler, when error occurred it sends request with http status 400 and body with error message
+		cw := &contentTypeWriter{ResponseWriter: w}
+		defer func() {
 			if err := recover(); err != nil {
-				strErr, ok := err.(string)
-				if ok {
-					http.Error(w, strErr, http.StatusBadRequest)
-				} else {
-					http.Error(w, "unrecognized error", http.StatusInternalServerError)
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				ctx := common.PrepareContext(r)
+				common.GetLogger().ErrorContext(ctx, "Unexpected error occurred while processing request",
+					slog.Any("error", err), slog.String("stack", string(debug.Stack())))
+				if !cw.sent {
+					cw.status = 0
+					common.WriteError(ctx, cw, common.NewError(common.ErrInternal, "unexpected error occurred"))
 				}
-
-				return
 			}
+			cw.flushHeader()
 		}()
-		w.Header().Set("Content-Type", "application/json")
-		h.ServeHTTP(w, r)
+		h.ServeHTTP(cw, r)
 	})
 }
 
-func startRegistration(adapterAddress string, adapterUsername string, adapterPassword string,
-	baseProvider *basic.BaseProvider) *physical.RegistrationProvider {
+// contentTypeWriter delays the status until the first write of the body, so content type can be chosen by the body
+type contentTypeWriter struct {
+	http.ResponseWriter
+	status int
+	sent   bool
+}
+
+func (cw *contentTypeWriter) WriteHeader(status int) {
+	if cw.status == 0 && !cw.sent {
+		cw.status = status
+	}
+}
+
+func (cw *contentTypeWriter) Write(body []byte) (int, error) {
+	if !cw.sent {
+		if cw.Header().Get("Content-Type") == "" && len(body) > 0 {
+			if json.Valid(body) {
+				cw.Header().Set("Content-Type", "application/json")
+			} else {
+				cw.Header().Set("Content-Type", http.DetectContentType(body))
+			}
+		}
+		cw.flushHeader()
+	}
+	return cw.ResponseWriter.Write(body)
+}
+
+func (cw *contentTypeWriter) flushHeader() {
+	if cw.sent {
+		return
+	}
+	cw.sent = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *contentTypeWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func newRegistrationProvider(adapterAddress string, adapterUsername string, adapterPassword string,
+	baseProvider *basic.BaseProvider, client common.Client) *physical.RegistrationProvider {
 	dbaasAggregatorCredentials := dao.BasicAuth{
 		Username: dbaasAggregatorRegistrationUsername,
 		Password: dbaasAggregatorRegistrationPassword,
@@ -256,41 +484,43 @@ func startRegistration(adapterAddress string, adapterUsername string, adapterPas
 		adapterCredentials,
 		baseProvider,
 	)
-	if registrationEnabled {
-		registrationService.StartRegistration()
-	}
+	registrationService.Migrations = physical.NewMigrationStore(client)
 	return registrationService
 }
 
-func createBasicRoles(baseProvider *basic.BaseProvider) {
-	// Migration is tracked by the role mapping, because it is created at the end of the initialization
-	mapping, err := baseProvider.GetRoleMapping(fmt.Sprintf(common.RoleNamePattern, basic.AdminRoleType))
-	if err != nil {
-		panic(err)
-	}
-	if err = baseProvider.CreateRoleWithISMPermissions(enhancedSecurityPluginEnabled); err != nil {
-		panic(err)
+func createRoles(baseProvider *basic.BaseProvider) error {
+	if err := baseProvider.CreateRoleWithISMPermissions(enhancedSecurityPluginEnabled); err != nil {
+		return err
 	}
-	if err = baseProvider.CreateRoleWithAdminPermissions(); err != nil {
-		panic(err)
+	if err := baseProvider.CreateRoleWithAdminPermissions(); err != nil {
+		return err
 	}
-	if err = baseProvider.CreateRoleWithDMLPermissions(); err != nil {
-		panic(err)
+	if err := baseProvider.CreateRoleWithDMLPermissions(); err != nil {
+		return err
 	}
-	if err = baseProvider.CreateRoleWithReadOnlyPermissions(); err != nil {
-		panic(err)
+	return baseProvider.CreateRoleWithReadOnlyPermissions()
+}
+
+func migrateUsers(baseProvider *basic.BaseProvider) error {
+	// Migration is tracked by the role mapping, because it is created at the end of the initialization
+	mapping, err := baseProvider.GetRoleMapping(fmt.Sprintf(common.RoleNamePattern, basic.AdminRoleType))
+	if err != nil {
+		return err
 	}
 	// migration is necessary if specific roles mapping does not exist
 	if mapping == nil {
-		if err := performMigration(baseProvider); err != nil {
-			panic(err)
-		}
+		return performMigration(baseProvider)
 	}
+	return nil
+}
+
+func createRoleMappings(baseProvider *basic.BaseProvider) error {
 	for _, roleType := range baseProvider.GetSupportedRoleTypes() {
-		if err = baseProvider.CreateOrUpdateRoleMapping(roleType); err != nil {
-			panic(err)
+		if err := baseProvider.CreateOrUpdateRoleMapping(roleType); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
 func performMigration(baseProvider *basic.BaseProvider) error {
@@ -315,7 +545,7 @@ func relevantForMigration(roleName string, roleMapping basic.RoleMapping) bool {
 }
 
 func updateUserConfiguration(username string, roleName string, baseProvider *basic.BaseProvider) error {
-	user, err := baseProvider.GetUser(username)
+	user, err := baseProvider.GetUser(username, context.Background())
 	if err != nil || user == nil {
 		return err
 	}
@@ -335,20 +565,3 @@ func updateUserConfiguration(username string, roleName string, baseProvider *bas
 	roleType := baseProvider.DefineRoleType(roleName)
 	return baseProvider.PatchUser(username, "", pattern, roleType, context.Background())
 }
-
-func BasicAuthorizer(username string, password string, realm string) func(func(w http.ResponseWriter, r *http.Request)) http.Handler {
-	return func(f func(w http.ResponseWriter, r *http.Request)) http.Handler {
-		h := http.HandlerFunc(f)
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := r.Context()
-			user, pass, ok := r.BasicAuth()
-
-			if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 || subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
-				w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
-				common.ProcessResponseBody(ctx, w, []byte("Not authorized to use this API, only DBaaS aggregator can use it.\n"), http.StatusUnauthorized)
-				return
-			}
-			h.ServeHTTP(w, r)
-		})
-	}
-}
@@ -0,0 +1,161 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/backup"
+	"github.com/Netcracker/dbaas-opensearch-adapter/basic"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/Netcracker/dbaas-opensearch-adapter/health"
+	"github.com/Netcracker/dbaas-opensearch-adapter/openapi"
+	"github.com/Netcracker/dbaas-opensearch-adapter/physical"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonContentTypeRecoversPanic(t *testing.T) {
+	handler := JsonContentType(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("unexpected state")
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var response common.ErrorResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, common.ErrInternal.Code, response.Code)
+	assert.NotEmpty(t, response.RequestId)
+}
+
+func TestJsonContentTypeDetectsBody(t *testing.T) {
+	handler := JsonContentType(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(r.URL.Query().Get("body")))
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, `/?body={"status":"UP"}`, nil))
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?body=accepted", nil))
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+}
+
+func TestCompressedResponseKeepsContentType(t *testing.T) {
+	handler := responseHandler(testRouter())
+	request := httptest.NewRequest(http.MethodGet, openapi.JsonPath, nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	reader, err := gzip.NewReader(recorder.Body)
+	assert.Nil(t, err)
+	body, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.True(t, json.Valid(body))
+}
+
+func TestBasicAuthorizerRejectsInvalidCredentials(t *testing.T) {
+	authorizer := BasicAuthorizer(NewCredentials("dbaas-aggregator", "password", ""), "adapter")
+	handler := authorizer(aggregatorScopes, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.SetBasicAuth("dbaas-aggregator", "wrong")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	var response common.ErrorResponse
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, common.ErrUnauthorized.Code, response.Code)
+}
+
+func testRouter() *mux.Router {
+	return testRouterWithCredentials(NewCredentials("dbaas-aggregator", "password", ""))
+}
+
+func testRouterWithCredentials(credentials *Credentials) *mux.Router {
+	return testRouterWithAuthorizer(BasicAuthorizer(credentials, "adapter"))
+}
+
+func testRouterWithAuthorizer(authorizer Authorizer) *mux.Router {
+	return router(context.Background(), &health.Health{}, basic.NewBaseProvider(nil), &backup.BackupProvider{}, &physical.RegistrationProvider{},
+		authorizer, common.NewDrainer(), common.NewIdempotencyStore(common.NewClient(), time.Hour))
+}
+
+func TestRoutesAreDescribedInOpenApi(t *testing.T) {
+	paths, err := openapi.Paths()
+	assert.Nil(t, err)
+
+	routes := make(map[string][]string)
+	err = testRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		routes[path] = append(routes[path], methods...)
+		for _, method := range methods {
+			assert.Contains(t, paths[path], method, "%s %s is not described in OpenAPI specification", method, path)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	for path, methods := range paths {
+		for _, method := range methods {
+			assert.True(t, slices.Contains(routes[path], method),
+				"%s %s is described in OpenAPI specification, but it is not served", method, path)
+		}
+	}
+}
+
+func TestOpenApiIsServed(t *testing.T) {
+	r := testRouter()
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, openapi.JsonPath, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var spec map[string]interface{}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec["openapi"])
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, openapi.YamlPath, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/yaml", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "openapi: 3.0.3")
+}
@@ -0,0 +1,70 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/opensearch-project/opensearch-go/opensearchtransport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedClient starts span for each request performed by the wrapped OpenSearch client
+type tracedClient struct {
+	common.Client
+}
+
+// InstrumentClient wraps OpenSearch client, so each request sent by opensearchapi and api packages is traced
+// as a child of span from the request context. Trace context is propagated to OpenSearch.
+func InstrumentClient(client common.Client) common.Client {
+	return &tracedClient{Client: client}
+}
+
+func (tc *tracedClient) Perform(req *http.Request) (*http.Response, error) {
+	ctx, span := Start(req.Context(), fmt.Sprintf("opensearch %s", common.OpensearchApiName(req)),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "opensearch"),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.URL.Path),
+		))
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	Inject(ctx, req.Header)
+	response, err := tc.Client.Perform(req.WithContext(ctx))
+	if err != nil {
+		End(span, err)
+		return response, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+	if response.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+	}
+	span.End()
+	return response, nil
+}
+
+func (tc *tracedClient) Metrics() (opensearchtransport.Metrics, error) {
+	return tc.Client.Metrics()
+}
+
+func (tc *tracedClient) DiscoverNodes() error {
+	return tc.Client.DiscoverNodes()
+}
@@ -0,0 +1,86 @@
+// Copyright 2024-2025 NetCracker Technology Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Netcracker/dbaas-opensearch-adapter/api"
+	"github.com/Netcracker/dbaas-opensearch-adapter/common"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type recordingClient struct {
+	*common.ClientStub
+	headers http.Header
+}
+
+func (rc *recordingClient) Perform(req *http.Request) (*http.Response, error) {
+	rc.headers = req.Header.Clone()
+	return rc.ClientStub.Perform(req)
+}
+
+func TestRequestIsTracedDownToOpensearch(t *testing.T) {
+	exporter := SetupInMemory()
+	opensearchClient := &recordingClient{ClientStub: common.NewClient()}
+	client := InstrumentClient(opensearchClient)
+
+	r := mux.NewRouter()
+	r.Use(Middleware)
+	r.HandleFunc("/users/{name}", func(w http.ResponseWriter, r *http.Request) {
+		request := api.GetUserRequest{Username: mux.Vars(r)["name"]}
+		response, err := request.Do(r.Context(), client)
+		assert.Nil(t, err)
+		response.Body.Close()
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods(http.MethodGet)
+
+	request := httptest.NewRequest(http.MethodGet, "/users/dbaas_user", nil)
+	request.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	opensearchSpan, serverSpan := spans[0], spans[1]
+	assert.Equal(t, "GET /users/{name}", serverSpan.Name)
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+	assert.Equal(t, codes.Error, serverSpan.Status.Code)
+
+	assert.Equal(t, "opensearch GET /_plugins/_security/api/internalusers/{name}", opensearchSpan.Name)
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), opensearchSpan.Parent.SpanID())
+	assert.Contains(t, opensearchClient.headers.Get("traceparent"), opensearchSpan.SpanContext.SpanID().String())
+}
+
+func TestEndRecordsError(t *testing.T) {
+	exporter := SetupInMemory()
+	_, span := Start(context.Background(), "curator collect backup")
+	End(span, errors.New("curator is not available"))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "curator is not available", spans[0].Status.Description)
+}